@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/paths"
+	"tools.zach/dev/agentcord/internal/pricing"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Session Aggregation
+// ///////////////////////////////////////////////
+
+// liveSessionIDs returns the IDs of the sessions with a marker in sessDir,
+// sorted. Marker names hold the IDs as sanitized by [paths.SanitizeSessionID].
+// The hooks touch a session's marker on every event and remove it when the
+// session ends. When idle is positive, markers untouched for longer
+// than idle are skipped, so sessions hidden by presence_idle_minutes are not
+// counted.
+func liveSessionIDs(sessDir string, idle time.Duration, now time.Time) []string {
+	entries, err := os.ReadDir(sessDir)
+	if err != nil {
+		return nil // directory may not exist yet
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), paths.SessionExt) {
+			continue
+		}
+		if idle > 0 {
+			info, err := e.Info()
+			if err != nil || now.Sub(info.ModTime()) > idle {
+				continue
+			}
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), paths.SessionExt))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// aggregateSessions sums the displayed session and every other live session
+// for aggregate mode. The displayed session counts with the cost and tokens
+// already resolved for it; the others are read from the transcripts their
+// state files name (see [liveSessionState]) and recorded in the usage ledger, since only the displayed session is recorded
+// otherwise. Sessions in ignored directories are left out.
+func aggregateSessions(
+	cfg *config.Config,
+	actCfg *session.ActivityConfig,
+	state *session.State,
+	cost float64,
+	totalTokens int64,
+	pricingData *pricing.PricingData,
+	dataPaths DataPaths,
+	ls *loopState,
+	now time.Time,
+) *session.Aggregate {
+	agg := &session.Aggregate{}
+	if !actCfg.Ignores(state.CWD) {
+		project := state.Project
+		if actCfg.ProjectName != "" {
+			project = actCfg.ProjectName
+		}
+		agg.Add(project, cost, totalTokens)
+	}
+
+	idle := time.Duration(cfg.Behavior.PresenceIdleMinutes) * time.Minute
+	for _, id := range liveSessionIDs(dataPaths.Sessions(), idle, now) {
+		if id == paths.SanitizeSessionID(state.SessionID) {
+			continue
+		}
+		other := liveSessionState(dataPaths, state.Client, id)
+		c, tokens, _, data := resolveTokenData(pricingData, ls.transcripts, ls.transcriptCaches, other, now)
+		project := ledgerProject(other, data)
+		cwd := other.CWD
+		if cwd == "" && data != nil {
+			cwd = data.CWD
+		}
+		if actCfg.Ignores(cwd) {
+			continue
+		}
+		ls.spend.record(other.SessionID, project, data, pricingData != nil, now)
+		agg.Add(cfg.ProjectName(project, cwd), c, tokens)
+	}
+	return agg
+}
+
+// liveSessionState returns the state of the live session whose marker is
+// named id, read from its per-session state file. The file is looked up under
+// client, the displayed session's client, first and then under any client.
+// Without a state file it returns a state holding only id, whose transcript
+// is then found by searching the transcript directories.
+func liveSessionState(dataPaths DataPaths, client, id string) *session.State {
+	candidates := []string{dataPaths.StateForSession(client, id)}
+	// Sanitized IDs hold no glob metacharacters.
+	if others, err := filepath.Glob(filepath.Join(dataPaths.Root, "state.*."+id+".json")); err == nil {
+		candidates = append(candidates, others...)
+	}
+	for _, path := range candidates {
+		if s, _ := session.ReadState(path); s != nil && s.SessionID != "" {
+			return s
+		}
+	}
+	return &session.State{SessionID: id}
+}
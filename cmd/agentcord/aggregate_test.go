@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/paths"
+	"tools.zach/dev/agentcord/internal/pricing"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// liveSessionIDs Tests
+// ///////////////////////////////////////////////
+
+// writeMarker creates a session marker in sessDir last touched at mtime.
+func writeMarker(t *testing.T, sessDir, id string, mtime time.Time) {
+	t.Helper()
+	p := filepath.Join(sessDir, id+paths.SessionExt)
+	if err := os.WriteFile(p, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	os.Chtimes(p, mtime, mtime)
+}
+
+func TestLiveSessionIDs(t *testing.T) {
+	sessDir := t.TempDir()
+	now := time.Now()
+	writeMarker(t, sessDir, "b", now)
+	writeMarker(t, sessDir, "a", now.Add(-time.Minute))
+	writeMarker(t, sessDir, "stale", now.Add(-time.Hour))
+	os.WriteFile(filepath.Join(sessDir, "notes.txt"), nil, 0o644)
+
+	got := liveSessionIDs(sessDir, 5*time.Minute, now)
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("live = %v, want [a b]", got)
+	}
+	if got := liveSessionIDs(sessDir, 0, now); len(got) != 3 {
+		t.Errorf("without idle limit = %v, want all 3 markers", got)
+	}
+	if got := liveSessionIDs(filepath.Join(sessDir, "missing"), 0, now); got != nil {
+		t.Errorf("missing dir = %v, want nil", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// aggregateSessions Tests
+// ///////////////////////////////////////////////
+
+func TestAggregateSessions(t *testing.T) {
+	dir := t.TempDir()
+	dataPaths := DataPaths{Root: dir}
+	sessDir := dataPaths.Sessions()
+	os.MkdirAll(sessDir, 0o755)
+	convDir := filepath.Join(dir, "conversations")
+	os.MkdirAll(convDir, 0o755)
+
+	now := time.Now()
+	for id, cwd := range map[string]string{"s2": "/home/u/api", "s3": "/home/u/secret/app"} {
+		writeMarker(t, sessDir, id, now)
+		os.WriteFile(filepath.Join(convDir, id+".jsonl"), []byte(
+			`{"type":"assistant","model":"claude-opus-4-6","cwd":"`+cwd+`","usage":{"input_tokens":1000,"output_tokens":1000},"message":{"content":[{"type":"text"}]}}
+`), 0o644)
+	}
+	writeMarker(t, sessDir, "s1", now)
+
+	cfg := config.DefaultConfig()
+	actCfg := session.ActivityConfig{IgnoredPatterns: []string{"/home/u/secret/*"}}
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+	}}
+	ls := &loopState{
+		transcripts:      session.TranscriptResolver{Fallback: convDir},
+		transcriptCaches: session.NewJSONLCaches(),
+		spend:            newSpendTracker(cfg, dataPaths),
+	}
+	state := &session.State{SessionID: "s1", Project: "web", CWD: "/home/u/web"}
+
+	agg := aggregateSessions(cfg, &actCfg, state, 1, 500, pd, dataPaths, ls, now)
+	if agg.Sessions != 2 || agg.Tokens != 2500 || math.Abs(agg.Cost-1.09) > 1e-9 {
+		t.Errorf("aggregate = %+v, want 2 sessions, 2500 tokens, $1.09", agg)
+	}
+	if len(agg.Projects) != 2 || agg.Projects[0] != "web" || agg.Projects[1] != "api" {
+		t.Errorf("Projects = %v, want [web api]", agg.Projects)
+	}
+
+	// The other session is recorded in the ledger; the displayed one is
+	// recorded by the caller.
+	records := ls.spend.ledger.Records()
+	if len(records) != 1 || records[0].Session != "s2" || records[0].Project != "api" {
+		t.Errorf("records = %+v, want one record for s2 in api", records)
+	}
+}
+
+func TestAggregateSessions_StateFile(t *testing.T) {
+	dir := t.TempDir()
+	dataPaths := DataPaths{Root: dir}
+	sessDir := dataPaths.Sessions()
+	os.MkdirAll(sessDir, 0o755)
+
+	// The transcript is outside the transcript directories and records no
+	// working directory: both come from the session's state file, written by
+	// another client under the sanitized session ID.
+	now := time.Now()
+	transcript := filepath.Join(dir, "elsewhere", "t.jsonl")
+	os.MkdirAll(filepath.Dir(transcript), 0o755)
+	os.WriteFile(transcript, []byte(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":1000}}
+`), 0o644)
+	writeMarker(t, sessDir, "team_s2", now)
+	data, _ := json.Marshal(session.State{SessionID: "team/s2", Client: "codex", CWD: "/home/u/cli", TranscriptPath: transcript})
+	os.WriteFile(dataPaths.StateForSession("codex", "team/s2"), data, 0o644)
+
+	cfg := config.DefaultConfig()
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015},
+	}}
+	ls := &loopState{
+		transcripts:      session.TranscriptResolver{Fallback: filepath.Join(dir, "conversations")},
+		transcriptCaches: session.NewJSONLCaches(),
+		spend:            newSpendTracker(cfg, dataPaths),
+	}
+	state := &session.State{SessionID: "s1", Client: "claude-code", Project: "web", CWD: "/home/u/web"}
+
+	agg := aggregateSessions(cfg, &session.ActivityConfig{}, state, 0, 0, pd, dataPaths, ls, now)
+	if agg.Sessions != 2 || math.Abs(agg.Cost-0.015) > 1e-9 {
+		t.Errorf("aggregate = %+v, want 2 sessions, $0.015", agg)
+	}
+	if len(agg.Projects) != 2 || agg.Projects[1] != "cli" {
+		t.Errorf("Projects = %v, want [web cli]", agg.Projects)
+	}
+	records := ls.spend.ledger.Records()
+	if len(records) != 1 || records[0].Session != "team/s2" || records[0].Project != "cli" {
+		t.Errorf("records = %+v, want one record for team/s2 in cli", records)
+	}
+}
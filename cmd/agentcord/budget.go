@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/budget"
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/ledger"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Spend Tracking
+// ///////////////////////////////////////////////
+
+// spendTracker records session usage in the usage ledger and evaluates the
+// configured budgets against it. A nil tracker records nothing and reports no
+// budgets, so the daemon keeps running when the ledger cannot be opened.
+type spendTracker struct {
+	// ledger persists session usage across daemon restarts.
+	ledger *ledger.Ledger
+	// limits holds the configured budgets.
+	limits budget.Limits
+	// alerter runs the alert command when a budget crosses a threshold.
+	alerter *budget.Alerter
+}
+
+// newSpendTracker opens the usage ledger and sets up budget alerts. Returns
+// nil when the ledger cannot be opened.
+func newSpendTracker(cfg *config.Config, dataPaths DataPaths) *spendTracker {
+	l, err := ledger.Open(dataPaths.UsageLedger())
+	if err != nil {
+		slog.Warn("usage ledger unavailable, budgets disabled", "error", err)
+		return nil
+	}
+	b := cfg.Budget
+	return &spendTracker{
+		ledger:  l,
+		limits:  budget.Limits{Daily: b.Daily, Weekly: b.Weekly, Monthly: b.Monthly},
+		alerter: budget.NewAlerter(dataPaths.BudgetAlerts(), b.AlertCommand, b.WarnPercent),
+	}
+}
+
+// update records the current usage of a session when it is priced, then
+// returns the status of each configured budget and fires any due alerts.
+// Unpriced usage (no pricing data yet) is not recorded, since its zero cost
+// would erase the session's earlier cost from the ledger.
+func (t *spendTracker) update(sessionID, project string, data *session.JSONLData, priced bool, now time.Time) []budget.Status {
+	if t == nil {
+		return nil
+	}
+	t.record(sessionID, project, data, priced, now)
+	statuses := budget.Evaluate(t.limits, t.ledger.Spent, now)
+	t.alerter.Check(statuses, now)
+	return statuses
+}
+
+// record records the current usage of a session when it is priced, without
+// evaluating budgets. It is used for sessions that are not displayed.
+func (t *spendTracker) record(sessionID, project string, data *session.JSONLData, priced bool, now time.Time) {
+	if t == nil || !priced || data == nil {
+		return
+	}
+	if err := t.ledger.Record(sessionID, project, ledgerUsage(data), now); err != nil {
+		slog.Warn("failed to record session usage", "error", err)
+	}
+}
+
+// ledgerProject returns the project a session's usage is recorded under: the
+// project the session reported, else the base name of its working directory
+// as the hooks derive it, else that of the directory its conversation log
+// records. state and data may be nil.
+func ledgerProject(state *session.State, data *session.JSONLData) string {
+	if state != nil {
+		if state.Project != "" {
+			return state.Project
+		}
+		if state.CWD != "" {
+			return filepath.Base(state.CWD)
+		}
+	}
+	if data != nil && data.CWD != "" {
+		return filepath.Base(data.CWD)
+	}
+	return ""
+}
+
+// ledgerUsage converts the per-model totals of a priced conversation log into
+// ledger usage. data.ModelCosts must already be populated.
+func ledgerUsage(data *session.JSONLData) map[string]ledger.Usage {
+	out := make(map[string]ledger.Usage, len(data.ModelUsage))
+	for model, u := range data.ModelUsage {
+		out[model] = ledger.Usage{
+			InputTokens:      u.InputTokens,
+			OutputTokens:     u.OutputTokens,
+			CacheWriteTokens: u.CacheCreationTokens,
+			CacheReadTokens:  u.CacheReadTokens,
+			Turns:            u.TurnCount,
+			ToolUses:         u.ToolUseCount,
+			Cost:             data.ModelCosts[model],
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/discord"
+)
+
+// ///////////////////////////////////////////////
+// Waiting for Discord
+// ///////////////////////////////////////////////
+
+// maxReconnectInterval caps the backoff between connection attempts while
+// waiting for Discord. The socket watcher usually ends the wait sooner.
+const maxReconnectInterval = 5 * time.Minute
+
+// discordWait tracks the wait for Discord while it is not running: the
+// exponential backoff between connection attempts, and a watcher on the IPC
+// socket directories that triggers an attempt as soon as a socket appears.
+// The daemon keeps tracking sessions meanwhile.
+type discordWait struct {
+	// interval is the delay before the first retry, doubled after each
+	// failed attempt up to [maxReconnectInterval].
+	interval time.Duration
+	// next is the delay before the next retry.
+	next time.Duration
+	// timer fires when the next attempt is due. Nil when not waiting.
+	timer *time.Timer
+	// sockets reports new IPC sockets. Nil when not waiting or when no
+	// socket directory can be watched (e.g. on Windows).
+	sockets *discord.SocketWatcher
+	// socketDirs are the directories watched for IPC sockets.
+	socketDirs []string
+}
+
+// newDiscordWait returns a wait whose first retry comes after interval.
+func newDiscordWait(interval time.Duration) *discordWait {
+	return &discordWait{interval: interval, next: interval, socketDirs: discord.SocketDirs()}
+}
+
+// waiting reports whether a connection attempt is scheduled.
+func (w *discordWait) waiting() bool {
+	return w.timer != nil
+}
+
+// failed records a failed connection attempt: it starts waiting if not
+// already, and schedules the next attempt after the current delay, doubling
+// the delay for the one after. Returns the delay until the next attempt.
+func (w *discordWait) failed() time.Duration {
+	if w.timer == nil {
+		sockets, err := discord.WatchSockets(w.socketDirs)
+		if err != nil {
+			slog.Debug("not watching for Discord sockets", "error", err)
+		}
+		w.sockets = sockets
+	} else {
+		w.timer.Stop()
+	}
+	delay := w.next
+	w.timer = time.NewTimer(delay)
+	w.next = min(2*w.next, maxReconnectInterval)
+	return delay
+}
+
+// socketAppeared restarts the backoff when a socket appeared, so a failed
+// attempt on a Discord that is still starting is retried soon.
+func (w *discordWait) socketAppeared() {
+	w.next = w.interval
+}
+
+// connected ends the wait and resets the backoff.
+func (w *discordWait) connected() {
+	w.stop()
+	w.next = w.interval
+}
+
+// stop cancels the scheduled attempt and stops watching for sockets.
+func (w *discordWait) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.sockets != nil {
+		w.sockets.Close()
+		w.sockets = nil
+	}
+}
+
+// retryC returns the channel that fires when the next attempt is due, or
+// nil when not waiting. A nil channel never fires in a select.
+func (w *discordWait) retryC() <-chan time.Time {
+	if w.timer == nil {
+		return nil
+	}
+	return w.timer.C
+}
+
+// socketC returns the channel signalled when an IPC socket appears, or nil.
+func (w *discordWait) socketC() <-chan struct{} {
+	if w.sockets == nil {
+		return nil
+	}
+	return w.sockets.Events()
+}
+
+// connectDiscord makes one connection attempt. On success the wait ends and
+// the activity hash is reset so the next [processState] publishes presence
+// on the new connection; on failure the next attempt is scheduled. Reports
+// whether the client connected.
+func connectDiscord(client *discord.Client, ls *loopState) bool {
+	if err := client.Connect(); err != nil {
+		first := !ls.wait.waiting()
+		delay := ls.wait.failed()
+		if first {
+			slog.Warn("Discord not available, waiting for it", "error", err, "retry_in", delay)
+		} else {
+			slog.Debug("Discord still not available", "error", err, "retry_in", delay)
+		}
+		return false
+	}
+	ls.wait.connected()
+	ls.lastHash = ""
+	slog.Info("connected to Discord")
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// discordWait Tests
+// ///////////////////////////////////////////////
+
+func TestDiscordWaitBackoff(t *testing.T) {
+	w := &discordWait{interval: time.Minute, next: time.Minute}
+	defer w.stop()
+
+	if w.waiting() || w.retryC() != nil || w.socketC() != nil {
+		t.Fatal("a new wait should not be waiting")
+	}
+
+	var delays []time.Duration
+	for range 5 {
+		delays = append(delays, w.failed())
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, maxReconnectInterval, maxReconnectInterval}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("delay %d = %v, want %v", i, delays[i], want[i])
+		}
+	}
+	if !w.waiting() || w.retryC() == nil {
+		t.Error("a failed attempt should schedule a retry")
+	}
+
+	w.socketAppeared()
+	if d := w.failed(); d != time.Minute {
+		t.Errorf("delay after a socket appeared = %v, want the initial interval", d)
+	}
+
+	w.connected()
+	if w.waiting() || w.retryC() != nil {
+		t.Error("connected should end the wait")
+	}
+	if d := w.failed(); d != time.Minute {
+		t.Errorf("delay after reconnecting = %v, want the initial interval", d)
+	}
+}
+
+func TestDiscordWaitSockets(t *testing.T) {
+	dir := t.TempDir()
+	w := &discordWait{interval: time.Hour, next: time.Hour, socketDirs: []string{dir}}
+	defer w.stop()
+
+	w.failed()
+	if w.socketC() == nil {
+		t.Fatal("waiting should watch the socket directories")
+	}
+	os.WriteFile(filepath.Join(dir, "discord-ipc-0"), nil, 0o600)
+	select {
+	case <-w.socketC():
+	case <-time.After(2 * time.Second):
+		t.Fatal("no signal when a socket appeared")
+	}
+
+	w.connected()
+	if w.socketC() != nil {
+		t.Error("connected should stop watching the socket directories")
+	}
+}
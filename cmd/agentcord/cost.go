@@ -0,0 +1,365 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/budget"
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/ledger"
+	"tools.zach/dev/agentcord/internal/netclient"
+	"tools.zach/dev/agentcord/internal/pricing"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Cost Report Command
+// ///////////////////////////////////////////////
+
+// costReport holds the tables printed by the cost command.
+type costReport struct {
+	// Daily holds one row per local calendar day.
+	Daily []ledger.Row `json:"daily"`
+	// Weekly holds one row per week, keyed by the Monday it starts on.
+	Weekly []ledger.Row `json:"weekly"`
+	// Projects holds one row per project, most expensive first.
+	Projects []ledger.Row `json:"projects"`
+	// Models holds one row per model, most expensive first.
+	Models []ledger.Row `json:"models"`
+}
+
+// runCost implements `agentcord cost`: it prints daily, weekly, per-project
+// and per-model usage tables from the usage ledger, optionally backfilling
+// the ledger from existing conversation logs first. Returns the exit code.
+func runCost(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cost", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dataDir := fs.String("data-dir", defaultDataDir(), "Data directory for config, state, and logs")
+	days := fs.Int("days", 30, "Only include usage from the last N days (0 for all time)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	asCSV := fs.Bool("csv", false, "Print the report as CSV")
+	backfill := fs.Bool("backfill", false, "Record conversation logs missing from the ledger before reporting")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *asJSON && *asCSV {
+		fmt.Fprintln(stderr, "cost: --json and --csv are mutually exclusive")
+		return 2
+	}
+
+	dataPaths := DataPaths{Root: *dataDir}
+	l, err := ledger.Open(dataPaths.UsageLedger())
+	if err != nil {
+		fmt.Fprintf(stderr, "cost: %v\n", err)
+		return 1
+	}
+
+	if *backfill {
+		n, err := backfillFromConfig(l, dataPaths)
+		if err != nil {
+			fmt.Fprintf(stderr, "cost: backfill: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stderr, "backfilled %d session(s)\n", n)
+	}
+
+	records := l.Records()
+	if *days > 0 {
+		records = ledger.Since(records, budget.PeriodStart(budget.Daily, time.Now()).AddDate(0, 0, 1-*days))
+	}
+	report := buildCostReport(records)
+
+	switch {
+	case *asJSON:
+		err = writeCostJSON(stdout, report)
+	case *asCSV:
+		err = writeCostCSV(stdout, report)
+	default:
+		err = writeCostTables(stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "cost: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildCostReport groups ledger records into the report tables. Days and
+// weeks follow the local calendar.
+func buildCostReport(records []ledger.Record) costReport {
+	day := func(r ledger.Record) string {
+		return time.Unix(r.Time, 0).Format(time.DateOnly)
+	}
+	week := func(r ledger.Record) string {
+		return budget.PeriodStart(budget.Weekly, time.Unix(r.Time, 0)).Format(time.DateOnly)
+	}
+	report := costReport{
+		Daily:    ledger.Group(records, day),
+		Weekly:   ledger.Group(records, week),
+		Projects: ledger.Group(records, func(r ledger.Record) string { return r.Project }),
+		Models:   ledger.Group(records, func(r ledger.Record) string { return r.Model }),
+	}
+	ledger.SortByCost(report.Projects)
+	ledger.SortByCost(report.Models)
+	return report
+}
+
+// ///////////////////////////////////////////////
+// Backfill
+// ///////////////////////////////////////////////
+
+// backfillFromConfig loads the config and local pricing data, then backfills
+// the ledger from the transcripts under the configured transcript roots and
+// the conversation logs in the data directory. Pricing is fetched from the
+// network only when no local copy exists.
+func backfillFromConfig(l *ledger.Ledger, dataPaths DataPaths) (int, error) {
+	cfg, err := config.Load(dataPaths.Root)
+	if err != nil {
+		return 0, fmt.Errorf("loading config: %w", err)
+	}
+	if err := netclient.Configure(buildNetworkSettings(cfg, resolveVersion())); err != nil {
+		return 0, fmt.Errorf("configuring network: %w", err)
+	}
+	src := buildPricingSource(cfg)
+	pd := seedDataStore(refreshConfig{pricing: src, dataDir: dataPaths.Root}).pricing.Load()
+	if pd == nil {
+		if pd, err = pricing.Fetch(src, dataPaths.Root); err != nil {
+			return 0, fmt.Errorf("loading pricing: %w", err)
+		}
+	}
+
+	transcripts := buildTranscriptResolver(cfg, dataPaths)
+	dirs := []string{transcripts.Fallback}
+	for _, root := range transcripts.Roots {
+		entries, _ := os.ReadDir(root)
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, filepath.Join(root, e.Name()))
+			}
+		}
+	}
+	return backfillLedger(l, dirs, pd)
+}
+
+// transcriptFile is a conversation log found during backfill.
+type transcriptFile struct {
+	path    string
+	session string
+	modTime time.Time
+}
+
+// backfillLedger records every conversation log in dirs whose session is not
+// yet in the ledger. The session ID is the log's file name without its
+// extension and the project is resolved as the daemon resolves it (see
+// [ledgerProject]). The usage is recorded per day by [recordByDay].
+// Subagent transcripts are merged into their session, as the daemon does, and
+// entries a resumed session copied from an earlier log count only toward the
+// earlier one. Sessions are recorded oldest first.
+// Returns the number of sessions recorded.
+func backfillLedger(l *ledger.Ledger, dirs []string, pd *pricing.PricingData) (int, error) {
+	var files []transcriptFile
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading %s: %w", dir, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, transcriptFile{
+				path:    filepath.Join(dir, e.Name()),
+				session: strings.TrimSuffix(e.Name(), ".jsonl"),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+	slices.SortStableFunc(files, func(a, b transcriptFile) int { return a.modTime.Compare(b.modTime) })
+
+	caches := session.NewJSONLCaches()
+	count := 0
+	for _, f := range files {
+		if l.Has(f.session) {
+			continue
+		}
+		data, err := caches.Parse(f.session, f.path, f.modTime)
+		if err != nil {
+			continue
+		}
+		for _, subPath := range session.SubagentTranscripts(f.path) {
+			if sub, err := caches.Parse(f.session, subPath, f.modTime); err == nil {
+				data.MergeSubagent(sub)
+			}
+		}
+		if len(data.ModelUsage) == 0 {
+			continue
+		}
+		priceModelUsage(pd, data)
+
+		if err := recordByDay(l, f.session, ledgerProject(nil, data), data, pd, f.modTime); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// recordByDay records a backfilled session's usage as one ledger delta per
+// local calendar day, timestamped with the day's latest request, so a session
+// that ran over several days counts toward each of them. Requests without a
+// timestamp belong to the day of the request before them, or to fallback if
+// none has one. The last day brings the session to data's full totals, which
+// data.ModelCosts must already hold.
+func recordByDay(l *ledger.Ledger, sessionID, project string, data *session.JSONLData, pd *pricing.PricingData, fallback time.Time) error {
+	reqs := slices.Clone(data.Requests)
+	last := fallback.Unix()
+	if i := slices.IndexFunc(reqs, func(r session.RequestUsage) bool { return r.Time != 0 }); i >= 0 {
+		last = reqs[i].Time
+	}
+	for i := range reqs {
+		if reqs[i].Time == 0 {
+			reqs[i].Time = last
+		}
+		last = reqs[i].Time
+	}
+	// Subagent requests follow the main transcript's; interleave them by time.
+	slices.SortStableFunc(reqs, func(a, b session.RequestUsage) int { return cmp.Compare(a.Time, b.Time) })
+
+	totals := make(map[string]ledger.Usage)
+	for i, r := range reqs {
+		totals[r.Model] = totals[r.Model].Add(ledger.Usage{
+			InputTokens:      r.Usage.InputTokens,
+			OutputTokens:     r.Usage.OutputTokens,
+			CacheWriteTokens: r.Usage.CacheCreationTokens,
+			CacheReadTokens:  r.Usage.CacheReadTokens,
+			Turns:            r.Usage.TurnCount,
+			ToolUses:         r.Usage.ToolUseCount,
+			Cost:             priceRequest(pd, r),
+		})
+		day := time.Unix(r.Time, 0)
+		if i+1 < len(reqs) && sameDay(day, time.Unix(reqs[i+1].Time, 0)) {
+			continue
+		}
+		if i+1 == len(reqs) {
+			totals = ledgerUsage(data)
+		}
+		if err := l.Record(sessionID, project, totals, day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameDay reports whether a and b fall on the same local calendar day.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// ///////////////////////////////////////////////
+// Output
+// ///////////////////////////////////////////////
+
+// costTable is one report table with its heading and key column name.
+type costTable struct {
+	title, key string
+	rows       []ledger.Row
+}
+
+// costTables returns the report tables in print order.
+func costTables(report costReport) []costTable {
+	return []costTable{
+		{"Daily", "DATE", report.Daily},
+		{"Weekly", "WEEK OF", report.Weekly},
+		{"Projects", "PROJECT", report.Projects},
+		{"Models", "MODEL", report.Models},
+	}
+}
+
+// writeCostTables prints the report as aligned text tables.
+func writeCostTables(w io.Writer, report costReport) error {
+	if len(report.Daily) == 0 {
+		_, err := fmt.Fprintln(w, "No usage recorded.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, t := range costTables(report) {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintln(tw, t.title)
+		fmt.Fprintf(tw, "%s\tSESSIONS\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ\tTURNS\tTOOLS\tCOST\n", t.key)
+		var total ledger.Usage
+		for _, r := range t.rows {
+			writeCostRow(tw, keyOrUnknown(r.Key), strconv.Itoa(r.Sessions), r.Usage)
+			total = total.Add(r.Usage)
+		}
+		writeCostRow(tw, "TOTAL", "", total)
+	}
+	return tw.Flush()
+}
+
+// writeCostRow prints one text table row.
+func writeCostRow(w io.Writer, key, sessions string, u ledger.Usage) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t$%.2f\n",
+		key, sessions,
+		config.FormatShort(u.InputTokens), config.FormatShort(u.OutputTokens),
+		config.FormatShort(u.CacheWriteTokens), config.FormatShort(u.CacheReadTokens),
+		u.Turns, u.ToolUses, u.Cost)
+}
+
+// keyOrUnknown labels rows whose project or model was not recorded.
+func keyOrUnknown(key string) string {
+	if key == "" {
+		return "(unknown)"
+	}
+	return key
+}
+
+// writeCostJSON prints the report as indented JSON.
+func writeCostJSON(w io.Writer, report costReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// writeCostCSV prints the report as a single CSV table whose first column
+// names the report table each row belongs to.
+func writeCostCSV(w io.Writer, report costReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"table", "key", "sessions", "input_tokens", "output_tokens",
+		"cache_write_tokens", "cache_read_tokens", "turns", "tool_uses", "cost_usd"})
+	for _, t := range costTables(report) {
+		table := strings.ToLower(t.title)
+		for _, r := range t.rows {
+			cw.Write([]string{
+				table, r.Key, strconv.Itoa(r.Sessions),
+				strconv.FormatInt(r.InputTokens, 10), strconv.FormatInt(r.OutputTokens, 10),
+				strconv.FormatInt(r.CacheWriteTokens, 10), strconv.FormatInt(r.CacheReadTokens, 10),
+				strconv.FormatInt(r.Turns, 10), strconv.FormatInt(r.ToolUses, 10),
+				strconv.FormatFloat(r.Cost, 'f', 6, 64),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
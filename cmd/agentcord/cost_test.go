@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/ledger"
+	"tools.zach/dev/agentcord/internal/pricing"
+)
+
+// ///////////////////////////////////////////////
+// backfillLedger Tests
+// ///////////////////////////////////////////////
+
+func TestBackfillLedger(t *testing.T) {
+	dir := t.TempDir()
+	convDir := filepath.Join(dir, "conversations")
+	os.MkdirAll(convDir, 0o755)
+	os.WriteFile(filepath.Join(convDir, "abc.jsonl"), []byte(
+		`{"type":"assistant","model":"claude-opus-4-6","cwd":"/home/u/web","usage":{"input_tokens":1000,"output_tokens":1000},"message":{"content":[{"type":"tool_use"},{"type":"text"}]}}
+`), 0o644)
+	os.WriteFile(filepath.Join(convDir, "empty.jsonl"), []byte(`{"type":"user"}
+`), 0o644)
+
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+	}}
+	l, _ := ledger.Open(filepath.Join(dir, "ledger.jsonl"))
+
+	n, err := backfillLedger(l, []string{convDir}, pd)
+	if err != nil || n != 1 {
+		t.Fatalf("backfillLedger = %d, %v; want 1 session", n, err)
+	}
+	records := l.Records()
+	if len(records) != 1 {
+		t.Fatalf("records = %+v, want 1", records)
+	}
+	r := records[0]
+	if r.Session != "abc" || r.Project != "web" || r.Model != "claude-opus-4-6" {
+		t.Errorf("record = %+v, want session abc, project web, opus", r)
+	}
+	if r.Turns != 1 || r.ToolUses != 1 || math.Abs(r.Cost-0.09) > 1e-12 {
+		t.Errorf("record usage = %+v, want 1 turn, 1 tool use, $0.09", r.Usage)
+	}
+
+	// Sessions already in the ledger are skipped.
+	if n, _ := backfillLedger(l, []string{convDir}, pd); n != 0 {
+		t.Errorf("second backfill recorded %d sessions, want 0", n)
+	}
+}
+
+func TestBackfillLedger_Subagents(t *testing.T) {
+	dir := t.TempDir()
+	convDir := filepath.Join(dir, "conversations")
+	subDir := filepath.Join(convDir, "abc", "subagents")
+	os.MkdirAll(subDir, 0o755)
+	os.WriteFile(filepath.Join(convDir, "abc.jsonl"), []byte(
+		`{"type":"assistant","model":"claude-opus-4-6","cwd":"/home/u/web","usage":{"input_tokens":1000,"output_tokens":1000}}
+`), 0o644)
+	os.WriteFile(filepath.Join(subDir, "agent-1.jsonl"), []byte(
+		`{"type":"assistant","model":"claude-haiku-4-5","isSidechain":true,"usage":{"input_tokens":1000,"output_tokens":0}}
+`), 0o644)
+
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6":  {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+		"claude-haiku-4-5": {InputPerToken: 0.000001},
+	}}
+	l, _ := ledger.Open(filepath.Join(dir, "ledger.jsonl"))
+
+	if n, err := backfillLedger(l, []string{convDir}, pd); err != nil || n != 1 {
+		t.Fatalf("backfillLedger = %d, %v; want 1 session", n, err)
+	}
+	var total float64
+	models := map[string]bool{}
+	for _, r := range l.Records() {
+		if r.Session != "abc" {
+			t.Errorf("record for session %q, want abc", r.Session)
+		}
+		total += r.Cost
+		models[r.Model] = true
+	}
+	if !models["claude-haiku-4-5"] || math.Abs(total-0.091) > 1e-12 {
+		t.Errorf("session cost = %v over %v, want $0.091 including the haiku subagent", total, models)
+	}
+}
+
+func TestBackfillLedger_Days(t *testing.T) {
+	dir := t.TempDir()
+	convDir := filepath.Join(dir, "conversations")
+	os.MkdirAll(convDir, 0o755)
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local)
+	day2 := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	line := `{"type":"assistant","model":"claude-opus-4-6","cwd":"/home/u/web","timestamp":%q,"usage":{"input_tokens":1000}}` + "\n"
+	os.WriteFile(filepath.Join(convDir, "abc.jsonl"), []byte(
+		fmt.Sprintf(line, day1.Format(time.RFC3339))+
+			// No timestamp: belongs to the day before it.
+			fmt.Sprintf(line, "")+
+			fmt.Sprintf(line, day2.Format(time.RFC3339))), 0o644)
+
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015},
+	}}
+	l, _ := ledger.Open(filepath.Join(dir, "ledger.jsonl"))
+	if n, err := backfillLedger(l, []string{convDir}, pd); err != nil || n != 1 {
+		t.Fatalf("backfillLedger = %d, %v; want 1 session", n, err)
+	}
+
+	records := l.Records()
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want one per day", records)
+	}
+	for i, want := range []struct {
+		at     time.Time
+		tokens int64
+	}{{day1, 2000}, {day2, 1000}} {
+		r := records[i]
+		if r.Time != want.at.Unix() || r.InputTokens != want.tokens || math.Abs(r.Cost-float64(want.tokens)*0.000015) > 1e-12 {
+			t.Errorf("record %d = %+v, want %d tokens at %v", i, r, want.tokens, want.at)
+		}
+	}
+}
+
+// ///////////////////////////////////////////////
+// runCost Tests
+// ///////////////////////////////////////////////
+
+// writeTestLedger seeds a usage ledger in dataDir with two sessions today.
+func writeTestLedger(t *testing.T, dataDir string) {
+	t.Helper()
+	l, err := ledger.Open(DataPaths{Root: dataDir}.UsageLedger())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	now := time.Now()
+	l.Record("s1", "web", map[string]ledger.Usage{"opus": {InputTokens: 100, Turns: 1, Cost: 2}}, now)
+	l.Record("s2", "api", map[string]ledger.Usage{"haiku": {InputTokens: 50, Turns: 2, Cost: 0.5}}, now)
+}
+
+func TestRunCost_JSON(t *testing.T) {
+	dir := t.TempDir()
+	writeTestLedger(t, dir)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCost([]string{"--data-dir", dir, "--json"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runCost exit = %d, stderr = %s", code, stderr.String())
+	}
+	var report costReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(report.Daily) != 1 || report.Daily[0].Sessions != 2 || report.Daily[0].Cost != 2.5 {
+		t.Errorf("daily = %+v, want one day with 2 sessions and $2.50", report.Daily)
+	}
+	if len(report.Projects) != 2 || report.Projects[0].Key != "web" {
+		t.Errorf("projects = %+v, want web first by cost", report.Projects)
+	}
+}
+
+func TestRunCost_CSV(t *testing.T) {
+	dir := t.TempDir()
+	writeTestLedger(t, dir)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCost([]string{"--data-dir", dir, "--csv"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runCost exit = %d, stderr = %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	// Header, one daily row, one weekly row, two projects, two models.
+	if len(lines) != 7 {
+		t.Fatalf("CSV has %d lines, want 7:\n%s", len(lines), stdout.String())
+	}
+	if !strings.HasPrefix(lines[0], "table,key,sessions,") || !strings.HasPrefix(lines[3], "projects,web,1,100,") {
+		t.Errorf("unexpected CSV:\n%s", stdout.String())
+	}
+}
+
+func TestRunCost_Tables(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := runCost([]string{"--data-dir", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runCost exit = %d", code)
+	}
+	if got := stdout.String(); got != "No usage recorded.\n" {
+		t.Errorf("empty ledger output = %q", got)
+	}
+
+	writeTestLedger(t, dir)
+	stdout.Reset()
+	runCost([]string{"--data-dir", dir}, &stdout, &stderr)
+	for _, want := range []string{"Daily", "WEEK OF", "PROJECT", "MODEL", "TOTAL", "$2.50"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("table output missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestRunCost_ConflictingFormats(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCost([]string{"--data-dir", t.TempDir(), "--json", "--csv"}, &stdout, &stderr); code != 2 {
+		t.Errorf("exit = %d, want 2", code)
+	}
+}
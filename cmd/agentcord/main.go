@@ -5,9 +5,11 @@ package main
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -17,8 +19,10 @@ import (
 
 	rootpkg "tools.zach/dev/agentcord"
 	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/currency"
 	"tools.zach/dev/agentcord/internal/discord"
 	"tools.zach/dev/agentcord/internal/logger"
+	"tools.zach/dev/agentcord/internal/netclient"
 	"tools.zach/dev/agentcord/internal/paths"
 	"tools.zach/dev/agentcord/internal/pricing"
 	"tools.zach/dev/agentcord/internal/session"
@@ -161,26 +165,44 @@ func checkStalePID(paths DataPaths) (alive bool, pid int) {
 // ///////////////////////////////////////////////
 
 // toDiscordActivity converts a [session.Activity] into the [discord.Activity]
-// wire type, copying fields and omitting empty optional sections.
+// wire type, copying fields and omitting empty optional sections and image
+// links whose image is not set.
 func toDiscordActivity(a *session.Activity) *discord.Activity {
 	if a == nil {
 		return nil
 	}
 	da := &discord.Activity{
-		Details: a.Details,
-		State:   a.State,
-	}
-	if a.Timestamps.Start != 0 {
+		Type:              activityTypes[a.Type],
+		StatusDisplayType: statusDisplayTypes[a.StatusDisplay],
+		Details:           a.Details,
+		DetailsURL:        a.DetailsURL,
+		State:             a.State,
+		StateURL:          a.StateURL,
+		Instance:          a.Instance,
+	}
+	if a.Timestamps != (session.Timestamps{}) {
 		da.Timestamps = &discord.Timestamps{
 			Start: a.Timestamps.Start,
+			End:   a.Timestamps.End,
 		}
 	}
-	if a.Assets.LargeImage != "" || a.Assets.LargeText != "" || a.Assets.SmallImage != "" || a.Assets.SmallText != "" {
+	// An image link is only sent with its image: there is nothing to click
+	// without one.
+	assets := a.Assets
+	if assets.LargeImage == "" {
+		assets.LargeURL = ""
+	}
+	if assets.SmallImage == "" {
+		assets.SmallURL = ""
+	}
+	if assets != (session.Assets{}) {
 		da.Assets = &discord.Assets{
-			LargeImage: a.Assets.LargeImage,
-			LargeText:  a.Assets.LargeText,
-			SmallImage: a.Assets.SmallImage,
-			SmallText:  a.Assets.SmallText,
+			LargeImage: assets.LargeImage,
+			LargeText:  assets.LargeText,
+			LargeURL:   assets.LargeURL,
+			SmallImage: assets.SmallImage,
+			SmallText:  assets.SmallText,
+			SmallURL:   assets.SmallURL,
 		}
 	}
 	for _, b := range a.Buttons {
@@ -189,9 +211,31 @@ func toDiscordActivity(a *session.Activity) *discord.Activity {
 			URL:   b.URL,
 		})
 	}
+	if a.Party.Size > 0 {
+		da.Party = &discord.Party{
+			ID:   a.Party.ID,
+			Size: []int{a.Party.Size, a.Party.Max},
+		}
+	}
 	return da
 }
 
+// activityTypes maps the activity_type settings to Discord activity types.
+var activityTypes = map[string]discord.ActivityType{
+	"playing":   discord.ActivityPlaying,
+	"listening": discord.ActivityListening,
+	"watching":  discord.ActivityWatching,
+	"competing": discord.ActivityCompeting,
+}
+
+// statusDisplayTypes maps the status_display settings to Discord status
+// display types.
+var statusDisplayTypes = map[string]discord.StatusDisplayType{
+	"name":    discord.StatusDisplayName,
+	"state":   discord.StatusDisplayState,
+	"details": discord.StatusDisplayDetails,
+}
+
 // ///////////////////////////////////////////////
 // Config Builders
 // ///////////////////////////////////////////////
@@ -208,11 +252,20 @@ func buildActivityConfig(cfg *config.Config, tierData *tiers.TierData, client st
 		DetailsNoBranchFormat: cfg.Display.DetailsNoBranch,
 		StateNoCostFormat:     cfg.Display.StateNoCost,
 		CostFormat:            cfg.Display.Format.CostFormat,
+		CurrencySymbol:        currencySymbol(cfg.Display.Currency),
+		CurrencySymbolAfter:   cfg.Display.Currency.SymbolPosition == "after",
 		TokenFormat:           cfg.Display.Format.TokenFormat,
 		ModelFormat:           cfg.Display.Format.ModelName,
+		ActivityType:          cfg.Display.ActivityType,
+		StatusDisplay:         cfg.Display.StatusDisplay,
+		DetailsURLFormat:      cfg.Display.DetailsURL,
+		StateURLFormat:        cfg.Display.StateURL,
+		Instance:              cfg.Display.Instance,
 		LargeImage:            cfg.Display.Assets.LargeImage,
 		LargeText:             cfg.Display.Assets.LargeText,
+		LargeURLFormat:        cfg.Display.Assets.LargeURL,
 		ShowModelIcon:         cfg.Display.Assets.ShowModelIcon,
+		SmallURLFormat:        cfg.Display.Assets.SmallURL,
 		ShowRepoButton:        cfg.Display.Buttons.ShowRepoButton,
 		RepoButtonLabel:       cfg.Display.Buttons.RepoButtonLabel,
 		CustomButtonLabel:     cfg.Display.Buttons.CustomButtonLabel,
@@ -221,6 +274,7 @@ func buildActivityConfig(cfg *config.Config, tierData *tiers.TierData, client st
 		ShowTokens:            cfg.Behavior.ShowTokens,
 		ShowBranch:            cfg.Behavior.ShowBranch,
 		TimestampMode:         cfg.Display.Timestamps.Mode,
+		EndMinutes:            cfg.Display.Timestamps.EndMinutes,
 		IdleMinutes:           cfg.Behavior.PresenceIdleMinutes,
 		IgnoredPatterns:       cfg.Privacy.Ignore,
 		ModelTiers:            tierData.TierNamesForClient(client),
@@ -230,6 +284,56 @@ func buildActivityConfig(cfg *config.Config, tierData *tiers.TierData, client st
 		IdleMode:              cfg.Behavior.IdleMode,
 		IdleDetails:           cfg.Behavior.IdleDetails,
 		IdleState:             cfg.Behavior.IdleState,
+		BudgetWarnPercent:     cfg.Budget.WarnPercent,
+		BudgetWarnDetails:     cfg.Budget.WarnDetails,
+		BudgetWarnState:       cfg.Budget.WarnState,
+		BudgetWarnSmallImage:  cfg.Budget.WarnSmallImage,
+		BudgetWarnSmallText:   cfg.Budget.WarnSmallText,
+		PartyMax:              cfg.Behavior.AggregatePartyMax,
+		Party: session.Party{
+			ID:   cfg.Display.Party.ID,
+			Size: cfg.Display.Party.Size,
+			Max:  cfg.Display.Party.Max,
+		},
+	}
+}
+
+// currencySymbol returns the configured currency symbol, deriving it from the
+// currency code when unset.
+func currencySymbol(c config.CurrencyConfig) string {
+	if c.Symbol != "" {
+		return c.Symbol
+	}
+	return currency.Symbol(c.Code)
+}
+
+// buildCurrencySource creates a [currency.SourceConfig] from the
+// [display.currency] config section.
+func buildCurrencySource(cfg *config.Config) currency.SourceConfig {
+	c := cfg.Display.Currency
+	return currency.SourceConfig{
+		Code:   c.Code,
+		Source: c.Source,
+		Rate:   c.Rate,
+		File:   c.File,
+		URL:    c.URL,
+	}
+}
+
+// buildNetworkSettings maps the [network] config section to
+// [netclient.Settings]. An unset User-Agent identifies the daemon version.
+func buildNetworkSettings(cfg *config.Config, ver string) netclient.Settings {
+	ua := cfg.Network.UserAgent
+	if ua == "" {
+		ua = netclient.DefaultUserAgent + "/" + ver
+	}
+	return netclient.Settings{
+		Offline:        cfg.Network.Offline,
+		ProxyURL:       cfg.Network.Proxy,
+		CABundle:       cfg.Network.CABundle,
+		UserAgent:      ua,
+		Timeout:        time.Duration(cfg.Network.TimeoutSeconds) * time.Second,
+		ConnectTimeout: time.Duration(cfg.Network.ConnectTimeoutSeconds) * time.Second,
 	}
 }
 
@@ -242,18 +346,46 @@ func buildPricingSource(cfg *config.Config) pricing.SourceConfig {
 		URL:    cfg.Pricing.URL,
 		File:   cfg.Pricing.File,
 	}
+	if len(cfg.Pricing.Aliases) > 0 {
+		src.Aliases = maps.Clone(cfg.Pricing.Aliases)
+	}
 	if len(cfg.Pricing.Models) > 0 {
 		src.Models = make(map[string]pricing.ModelPricing, len(cfg.Pricing.Models))
 		for k, v := range cfg.Pricing.Models {
 			src.Models[k] = pricing.ModelPricing{
-				InputPerToken:  v.InputPerToken,
-				OutputPerToken: v.OutputPerToken,
+				InputPerToken:        v.InputPerToken,
+				OutputPerToken:       v.OutputPerToken,
+				CacheWrite5mPerToken: v.CacheWrite5mPerToken,
+				CacheWrite1hPerToken: v.CacheWrite1hPerToken,
+				CacheReadPerToken:    v.CacheReadPerToken,
+				Bands:                buildPriceBands(v.Bands),
+				ContextWindow:        v.ContextWindow,
 			}
 		}
 	}
 	return src
 }
 
+// buildPriceBands converts config long-context bands into [pricing.PriceBand]
+// values. Returns nil when there are no bands.
+func buildPriceBands(bands []config.PricingBandConfig) []pricing.PriceBand {
+	if len(bands) == 0 {
+		return nil
+	}
+	out := make([]pricing.PriceBand, len(bands))
+	for i, b := range bands {
+		out[i] = pricing.PriceBand{
+			AboveInputTokens:     b.AboveInputTokens,
+			InputPerToken:        b.InputPerToken,
+			OutputPerToken:       b.OutputPerToken,
+			CacheWrite5mPerToken: b.CacheWrite5mPerToken,
+			CacheWrite1hPerToken: b.CacheWrite1hPerToken,
+			CacheReadPerToken:    b.CacheReadPerToken,
+		}
+	}
+	return out
+}
+
 // ///////////////////////////////////////////////
 // Default Data Directory
 // ///////////////////////////////////////////////
@@ -269,11 +401,44 @@ func defaultDataDir() string {
 	return filepath.Join(home, paths.DataDirRel)
 }
 
+// buildTranscriptResolver creates the resolver for session transcripts from
+// the configured roots, with the data directory's conversations directory as
+// the fallback.
+func buildTranscriptResolver(cfg *config.Config, paths DataPaths) session.TranscriptResolver {
+	roots := make([]string, 0, len(cfg.Behavior.TranscriptRoots))
+	for _, root := range cfg.Behavior.TranscriptRoots {
+		roots = append(roots, expandHome(root))
+	}
+	return session.TranscriptResolver{Roots: roots, Fallback: paths.Conversations()}
+}
+
+// expandHome replaces a leading ~ in path with the user's home directory.
+// The path is returned unchanged when the home directory is unknown.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
 // ///////////////////////////////////////////////
 // Main
 // ///////////////////////////////////////////////
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cost":
+			os.Exit(runCost(os.Args[2:], os.Stdout, os.Stderr))
+		case "statusline":
+			os.Exit(runStatusline(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		}
+	}
+
 	dataDir := flag.String("data-dir", defaultDataDir(), "Data directory for config, state, and logs")
 	flag.Parse()
 
@@ -313,6 +478,14 @@ func main() {
 	ver := resolveVersion()
 	slog.Info("agentcord starting", "version", ver, "data_dir", paths.Root)
 
+	if err := netclient.Configure(buildNetworkSettings(cfg, ver)); err != nil {
+		slog.Error("invalid network settings", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Network.Offline {
+		slog.Info("offline mode: using cached and built-in data only")
+	}
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -330,34 +503,34 @@ func main() {
 	}
 	defer removePID(paths, token, pidFile)
 
-	pricingSrc := buildPricingSource(cfg)
-	pricingData, pricingErr := pricing.Fetch(pricingSrc, paths.Root)
-	if pricingErr != nil {
-		slog.Warn("pricing fetch used fallback", "error", pricingErr)
-	}
-	if pricingData == nil {
-		slog.Error("no pricing data available")
-		os.Exit(1)
-	}
-
-	tierData, tierErr := tiers.Fetch(paths.Root)
-	if tierErr != nil {
-		slog.Warn("no tier data available, model icons disabled", "error", tierErr)
-		tierData = &tiers.TierData{DefaultIcon: "default"}
-	} else {
-		slog.Info("loaded model tiers", "clients", len(tierData.Clients))
-	}
-
-	client := discord.NewClient(cfg.Discord.AppID)
+	// Seed pricing, tiers and the exchange rate from local data only, then
+	// fetch from the network in the background so a slow or offline source
+	// never delays presence.
+	rc := refreshConfig{
+		pricing:       buildPricingSource(cfg),
+		currency:      buildCurrencySource(cfg),
+		dataDir:       paths.Root,
+		pricingEvery:  time.Duration(cfg.Behavior.PricingRefreshMinutes) * time.Minute,
+		tiersEvery:    time.Duration(cfg.Behavior.TiersRefreshMinutes) * time.Minute,
+		currencyEvery: time.Duration(cfg.Behavior.CurrencyRefreshMinutes) * time.Minute,
+	}
+	store := seedDataStore(rc)
+
+	done := make(chan struct{})
+	defer close(done)
+	go runRefresher(done, store, rc)
+
+	// The event loop connects, and waits for Discord if it is not running.
+	pub := newPublisher(discord.NewClient(cfg.Discord.AppID))
 	reconnectInterval := time.Duration(cfg.Behavior.ReconnectIntervalSeconds) * time.Second
-	if err := connectWithRetry(client, reconnectInterval); err != nil {
-		slog.Error("failed to connect to Discord", "error", err)
-		os.Exit(1)
-	}
-	defer func() { client.Close() }()
-	slog.Info("connected to Discord")
+	pub.start()
+	defer pub.stop()
 
-	watcher, err := session.NewDirWatcher(paths.Root)
+	var watched []string
+	if cfg.Behavior.UseStatusline {
+		watched = append(watched, filepath.Base(paths.Statusline()))
+	}
+	watcher, err := session.NewDirWatcher(paths.Root, watched...)
 	if err != nil {
 		slog.Error("failed to create watcher", "error", err)
 		os.Exit(1)
@@ -368,30 +541,9 @@ func main() {
 		slog.Info("using polling mode for file watching")
 	}
 
-	run(&client, watcher, cfg, pricingData, tierData, paths, reconnectInterval)
-}
-
-// ///////////////////////////////////////////////
-// Connect with Retry
-// ///////////////////////////////////////////////
-
-// connectWithRetry attempts to connect the [discord.Client] up to 10 times,
-// sleeping the given interval between failures. Returns nil on success or an
-// error if all attempts are exhausted.
-func connectWithRetry(client *discord.Client, interval time.Duration) error {
-	const maxAttempts = 10
+	spend := newSpendTracker(cfg, paths)
 
-	for i := range maxAttempts {
-		err := client.Connect()
-		if err == nil {
-			return nil
-		}
-		slog.Warn("Discord connect attempt failed", "attempt", i+1, "error", err)
-		if i < maxAttempts-1 {
-			time.Sleep(interval)
-		}
-	}
-	return fmt.Errorf("failed to connect after %d attempts", maxAttempts)
+	run(pub, watcher, cfg, store, spend, paths, reconnectInterval)
 }
 
 // ///////////////////////////////////////////////
@@ -432,23 +584,54 @@ type loopState struct {
 	// activeAppID is the Discord application ID currently in use, tracked to
 	// detect when a client switch requires reconnecting with a different AppID.
 	activeAppID string
+
+	// tierData is the tier snapshot the activity config was last built from,
+	// used to detect when the background refresher swapped in new tiers.
+	tierData *tiers.TierData
+
+	// pricingDiag is the pricing diagnostics last written, so the file is only
+	// rewritten when the model count or the matched or unmatched IDs change.
+	pricingDiag pricing.Diagnostics
+	// pricingDiagData is the pricing data pricingDiag was taken from, used to
+	// detect when a refresh swapped in new data with no matching results yet.
+	pricingDiagData *pricing.PricingData
+
+	// spend records session costs in the usage ledger and evaluates budgets.
+	// Nil when the ledger is unavailable.
+	spend *spendTracker
+
+	// transcripts locates the conversation log of the displayed session.
+	transcripts session.TranscriptResolver
+
+	// transcriptCaches holds the incremental parse state of each transcript,
+	// so polls only read newly appended lines.
+	transcriptCaches *session.JSONLCaches
+
+	// rotationStep counts the rotation ticks, selecting the rotation card and
+	// session to show. See [rotationIndexes].
+	rotationStep int
+
+	// wait schedules connection attempts while Discord is not available.
+	wait *discordWait
 }
 
 // run is the main event loop. It listens for file-system change events from
-// the [session.Watcher], a periodic poll ticker, and OS signals, dispatching
-// each to [processState] to rebuild and publish Discord presence. The loop runs
-// until an OS interrupt/terminate signal is received or the daemon idle timeout
-// fires.
+// the [session.Watcher], a periodic poll ticker, the presence rotation ticker
+// when rotation is configured, Discord connection events, and OS signals,
+// dispatching each to [processState] to rebuild and publish Discord presence.
+// A lost Discord connection is re-established immediately. The loop runs
+// until an OS interrupt/terminate signal is received or the daemon idle
+// timeout fires.
 func run(
-	client **discord.Client,
+	pub *publisher,
 	watcher *session.Watcher,
 	cfg *config.Config,
-	pricingData *pricing.PricingData,
-	tierData *tiers.TierData,
+	store *dataStore,
+	spend *spendTracker,
 	dataPaths DataPaths,
 	reconnectInterval time.Duration,
 ) {
-	actCfg := buildActivityConfig(cfg, tierData, "")
+	actCfg := buildActivityConfig(cfg, store.tiers.Load(), "")
 	pollInterval := time.Duration(cfg.Behavior.PollIntervalSeconds) * time.Second
 	daemonIdleMinutes := int64(cfg.Behavior.DaemonIdleMinutes)
 	cleanupMaxAge := time.Duration(cfg.Behavior.SessionCleanupHours) * time.Hour
@@ -456,14 +639,33 @@ func run(
 	pollTicker := time.NewTicker(pollInterval)
 	defer pollTicker.Stop()
 
+	// A nil channel never fires, leaving the rotation case idle when off.
+	var rotationC <-chan time.Time
+	if interval := rotationInterval(cfg.Display); interval > 0 {
+		rotationTicker := time.NewTicker(interval)
+		defer rotationTicker.Stop()
+		rotationC = rotationTicker.C
+	}
+
 	sigCh := signalChannel()
 
 	ls := loopState{
 		daemonStart: time.Now(),
 		activeAppID: cfg.Discord.AppID,
+		spend:       spend,
+		transcripts: buildTranscriptResolver(cfg, dataPaths),
+
+		transcriptCaches: session.NewJSONLCaches(),
+		wait:             newDiscordWait(reconnectInterval),
 	}
+	defer ls.wait.stop()
+	defer func() {
+		published, dropped := pub.stats()
+		slog.Info("presence updates", "published", published, "dropped", dropped)
+	}()
 
-	processState(client, &actCfg, cfg, pricingData, tierData, dataPaths, &ls, reconnectInterval)
+	connectDiscord(pub.client(), &ls)
+	processState(pub, &actCfg, cfg, store, dataPaths, &ls)
 
 	for {
 		select {
@@ -472,16 +674,42 @@ func run(
 			return
 
 		case <-watcher.Events():
-			processState(client, &actCfg, cfg, pricingData, tierData, dataPaths, &ls, reconnectInterval)
+			processState(pub, &actCfg, cfg, store, dataPaths, &ls)
 
 		case <-pollTicker.C:
-			processState(client, &actCfg, cfg, pricingData, tierData, dataPaths, &ls, reconnectInterval)
-			cleanupOrphanedSessions(dataPaths.Sessions(), cleanupMaxAge, &ls)
+			processState(pub, &actCfg, cfg, store, dataPaths, &ls)
+			cleanupOrphanedSessions(dataPaths, cleanupMaxAge, &ls)
 			if checkDaemonIdle(&ls, daemonIdleMinutes) {
 				return
 			}
-			if err := handleReconnect(*client, &ls, reconnectInterval); err != nil {
-				return
+			if handleReconnect(pub.client(), &ls) {
+				processState(pub, &actCfg, cfg, store, dataPaths, &ls)
+			}
+
+		case <-rotationC:
+			ls.rotationStep++
+			processState(pub, &actCfg, cfg, store, dataPaths, &ls)
+
+		case ev := <-pub.client().Events():
+			if ev.State != discord.StateDisconnected {
+				continue
+			}
+			// Reconnect right away rather than on the next poll tick, and
+			// republish since the new connection starts without presence.
+			slog.Warn("Discord connection lost", "error", ev.Err)
+			if connectDiscord(pub.client(), &ls) {
+				processState(pub, &actCfg, cfg, store, dataPaths, &ls)
+			}
+
+		case <-ls.wait.retryC():
+			if connectDiscord(pub.client(), &ls) {
+				processState(pub, &actCfg, cfg, store, dataPaths, &ls)
+			}
+
+		case <-ls.wait.socketC():
+			ls.wait.socketAppeared()
+			if connectDiscord(pub.client(), &ls) {
+				processState(pub, &actCfg, cfg, store, dataPaths, &ls)
 			}
 		}
 	}
@@ -502,33 +730,28 @@ func checkDaemonIdle(ls *loopState, daemonIdleMinutes int64) bool {
 }
 
 // handleReconnect checks whether the [discord.Client] is still connected and,
-// if not, attempts to re-establish the connection via [connectWithRetry]. On
-// success it resets the activity hash so the next [processState] call
-// re-publishes presence. Returns an error if reconnection fails permanently.
-func handleReconnect(client *discord.Client, ls *loopState, interval time.Duration) error {
-	if client.Connected() {
-		return nil
+// if not and no attempt is already scheduled, attempts to re-establish the
+// connection via [connectDiscord]. This catches a lost connection whose
+// event was missed. Reports whether the client reconnected, in which case
+// presence should be republished.
+func handleReconnect(client *discord.Client, ls *loopState) bool {
+	if client.Connected() || ls.wait.waiting() {
+		return false
 	}
 	slog.Warn("Discord disconnected, attempting reconnect")
-	if err := connectWithRetry(client, interval); err != nil {
-		slog.Error("reconnect failed", "error", err)
-		return err
-	}
-	slog.Info("reconnected to Discord")
-	ls.lastHash = ""
-	return nil
+	return connectDiscord(client, ls)
 }
 
 // ///////////////////////////////////////////////
 // Multi-Client State Resolution
 // ///////////////////////////////////////////////
 
-// findLatestState scans the data directory for per-client state files
-// (state.*.json), parses each, and returns the one with the most recent
-// lastActivity timestamp. Falls back to the legacy state.json if no
-// per-client files exist.
+// findLatestState scans the data directory for state files (state.*.json):
+// the per-session files hooks write and the legacy per-client files. It
+// parses each and returns the one with the most recent lastActivity
+// timestamp. Falls back to the legacy state.json if no such files exist.
 func findLatestState(dataDir string) (*session.State, error) {
-	pattern := filepath.Join(dataDir, "state.*.json")
+	pattern := filepath.Join(dataDir, paths.StateFilePattern)
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("glob state files: %w", err)
@@ -557,6 +780,28 @@ func findLatestState(dataDir string) (*session.State, error) {
 	return session.ReadState(legacyPath)
 }
 
+// applyStatusline combines the hook state with the Claude Code statusline
+// payload at path. A payload for the same session is merged into state; a
+// payload for another session replaces state when it is more recent, so
+// sessions without hooks are still shown. state may be nil.
+func applyStatusline(state *session.State, path string) *session.State {
+	sl, err := session.ReadStatusline(path)
+	if err != nil {
+		slog.Debug("statusline not readable", "error", err)
+		return state
+	}
+	if state == nil {
+		return sl.State()
+	}
+	if state.MergeStatusline(sl) {
+		return state
+	}
+	if sl.UpdatedAt.Unix() > state.LastActivity {
+		return sl.State()
+	}
+	return state
+}
+
 // resolveDiscordAppID returns the Discord application ID for the given client.
 // It checks for a per-client override in the config, falling back to the
 // global discord.app_id setting.
@@ -573,22 +818,28 @@ func resolveDiscordAppID(cfg *config.Config, client string) string {
 // State Processing
 // ///////////////////////////////////////////////
 
+// transcriptCacheIdle is how long a transcript cache is kept without being
+// parsed before it is evicted, covering sessions that ended without a stop
+// hook.
+const transcriptCacheIdle = time.Hour
+
 // processState reads the most recently active client's state file, computes
-// token costs, builds a [session.Activity], and pushes it to Discord when
-// the activity hash has changed. If the active client changed and requires a
+// token costs, builds a [session.Activity], and queues it on the [publisher]
+// when the activity hash has changed. If the active client changed and requires a
 // different Discord AppID, it triggers a reconnect. Called on every watcher
 // event and poll tick.
 func processState(
-	client **discord.Client,
+	pub *publisher,
 	actCfg *session.ActivityConfig,
 	cfg *config.Config,
-	pricingData *pricing.PricingData,
-	tierData *tiers.TierData,
+	store *dataStore,
 	dataPaths DataPaths,
 	ls *loopState,
-	reconnectInterval time.Duration,
 ) {
 	state, err := findLatestState(dataPaths.Root)
+	if cfg.Behavior.UseStatusline {
+		state = applyStatusline(state, dataPaths.Statusline())
+	}
 	if err != nil {
 		if state == nil {
 			slog.Debug("state file not readable", "error", err)
@@ -596,6 +847,9 @@ func processState(
 		}
 		slog.Debug("state file recovered with warning", "error", err)
 	}
+	if cfg.Display.RotateSessions {
+		state = rotateSession(cfg, dataPaths, state, ls.rotationStep, time.Now())
+	}
 
 	// Check if the active client changed and requires a different AppID.
 	newAppID := resolveDiscordAppID(cfg, state.Client)
@@ -605,34 +859,66 @@ func processState(
 			"new_client", state.Client,
 			"new_app_id", newAppID,
 		)
-		(*client).Close()
-		*client = discord.NewClient(newAppID)
-		if connErr := connectWithRetry(*client, reconnectInterval); connErr != nil {
-			slog.Error("reconnect with new AppID failed", "error", connErr)
-			return
-		}
-		ls.lastHash = ""
+		pub.replaceClient(discord.NewClient(newAppID))
+		// On failure the wait for Discord takes over; the state is still
+		// tracked so it is published once connected.
+		connectDiscord(pub.client(), ls)
 	}
-	// Update per-client settings when the active client changes.
-	if ls.activeClient != state.Client {
-		actCfg.ModelTiers = tierData.TierNamesForClient(state.Client)
-		actCfg.DefaultTierIcon = tierData.DefaultIconForClient(state.Client)
-		actCfg.LargeImage = config.ClientIcon(state.Client)
+	// Rebuild the per-client settings when the active client or tier data changes.
+	tierData := store.tiers.Load()
+	if ls.activeClient != state.Client || ls.tierData != tierData {
+		*actCfg = buildClientActivityConfig(cfg, tierData, state.Client)
 	}
 	ls.activeClient = state.Client
 	ls.activeAppID = newAppID
+	ls.tierData = tierData
 
 	applyPrivacyOverrides(actCfg, cfg, state)
 
-	if cfg.Clients != nil {
-		if clientCfg, ok := cfg.Clients[state.Client]; ok {
-			applyClientOverrides(actCfg, clientCfg)
-		}
+	actCfg.CurrencyRate = 0
+	if rate := store.currency.Load(); rate != nil {
+		actCfg.CurrencyRate = rate.PerUSD
 	}
 
-	cost, totalTokens, model, jsonlData := resolveTokenData(cfg, pricingData, dataPaths)
+	now := time.Now()
+	pricingData := store.pricing.Load()
+	var cost float64
+	var totalTokens int64
+	var model string
+	var jsonlData *session.JSONLData
+	if state.Stopped {
+		ls.transcriptCaches.Evict(state.SessionID)
+	} else {
+		cost, totalTokens, model, jsonlData = resolveTokenData(pricingData, ls.transcripts, ls.transcriptCaches, state, now)
+	}
+	ls.transcriptCaches.EvictIdle(transcriptCacheIdle, now)
+	writePricingDiagnostics(pricingData, dataPaths, ls)
+
+	if sl := state.Statusline; sl != nil {
+		// Claude Code's own figures cover subagents and models missing from
+		// the pricing data, so they take precedence on the card.
+		if sl.Cost.TotalCostUSD > 0 {
+			cost = sl.Cost.TotalCostUSD
+		}
+		if model == "" {
+			model = sl.Model.ID
+		}
+	}
+	actCfg.Aggregate = nil
+	if cfg.Behavior.AggregateSessions && !state.Stopped {
+		// Recorded before the budgets are evaluated so they include every session.
+		actCfg.Aggregate = aggregateSessions(cfg, actCfg, state, cost, totalTokens, pricingData, dataPaths, ls, now)
+	}
+	actCfg.Budgets = ls.spend.update(state.SessionID, ledgerProject(state, jsonlData), jsonlData, pricingData != nil, now)
+	if !cfg.Behavior.ShowCost {
+		cost = 0
+	}
 
-	activity := session.BuildActivityWithData(state, *actCfg, cost, totalTokens, model, jsonlData)
+	cardCfg := *actCfg
+	if card, _ := rotationIndexes(ls.rotationStep, len(cfg.Display.Rotation), 0); card >= 0 {
+		cardCfg = applyRotationCard(cardCfg, cfg.Display.Rotation[card])
+	}
+	activity := session.BuildActivityWithData(state, cardCfg, cost, totalTokens, model, jsonlData)
 
 	if cfg.Clients != nil {
 		if clientCfg, ok := cfg.Clients[state.Client]; ok {
@@ -641,10 +927,14 @@ func processState(
 	}
 
 	if activity != nil && cfg.Display.Timestamps.Mode == "daemon" {
-		activity.Timestamps.Start = ls.daemonStart.Unix()
+		ts := &activity.Timestamps
+		if ts.End != 0 {
+			ts.End += ls.daemonStart.Unix() - ts.Start
+		}
+		ts.Start = ls.daemonStart.Unix()
 	}
 
-	activity = handleIdleState(*client, actCfg, ls, activity)
+	activity = handleIdleState(pub, actCfg, ls, activity)
 	if activity == nil {
 		return
 	}
@@ -653,21 +943,46 @@ func processState(
 	ls.lastActivityTime = time.Now()
 	ls.lastActivity = activity
 
+	if !pub.client().Connected() {
+		// Published once Discord is available.
+		ls.lastHash = ""
+		return
+	}
+	if pub.takeRetry() {
+		ls.lastHash = ""
+	}
 	hash := activity.Hash()
 	if hash == ls.lastHash {
 		return
 	}
 	ls.lastHash = hash
 
-	da := toDiscordActivity(activity)
-	if err := (*client).SetActivity(da); err != nil {
-		slog.Warn("failed to set activity", "error", err)
+	pub.publish(toDiscordActivity(activity))
+}
+
+// logActivityError logs a failed presence update. When Discord rejected the
+// payload, the rejected field is logged so the template or button causing it
+// can be found.
+func logActivityError(msg string, err error) {
+	var cmdErr *discord.CommandError
+	if errors.As(err, &cmdErr) {
+		slog.Warn(msg, "field", cmdErr.Field(), "code", cmdErr.Code, "error", cmdErr.Message)
 		return
 	}
-	slog.Debug("presence updated",
-		"details", activity.Details,
-		"state", activity.State,
-	)
+	slog.Warn(msg, "error", err)
+}
+
+// buildClientActivityConfig builds the activity config for client from
+// scratch: the base config with the client's tier set and icon, then its
+// [clients] overrides. Building it anew on every client switch keeps one
+// client's overrides from carrying over to the next.
+func buildClientActivityConfig(cfg *config.Config, tierData *tiers.TierData, client string) session.ActivityConfig {
+	actCfg := buildActivityConfig(cfg, tierData, client)
+	actCfg.LargeImage = config.ClientIcon(client)
+	if clientCfg, ok := cfg.Clients[client]; ok {
+		applyClientOverrides(&actCfg, clientCfg)
+	}
+	return actCfg
 }
 
 // applyClientOverrides applies per-client display overrides (e.g. different
@@ -685,6 +1000,39 @@ func applyClientOverrides(actCfg *session.ActivityConfig, clientCfg config.Clien
 	if clientCfg.State != "" {
 		actCfg.StateFormat = clientCfg.State
 	}
+	if clientCfg.ActivityType != "" {
+		actCfg.ActivityType = clientCfg.ActivityType
+	}
+	if clientCfg.StatusDisplay != "" {
+		actCfg.StatusDisplay = clientCfg.StatusDisplay
+	}
+	if clientCfg.DetailsURL != "" {
+		actCfg.DetailsURLFormat = clientCfg.DetailsURL
+	}
+	if clientCfg.StateURL != "" {
+		actCfg.StateURLFormat = clientCfg.StateURL
+	}
+	if clientCfg.LargeURL != "" {
+		actCfg.LargeURLFormat = clientCfg.LargeURL
+	}
+	if clientCfg.SmallURL != "" {
+		actCfg.SmallURLFormat = clientCfg.SmallURL
+	}
+	if clientCfg.EndMinutes != nil {
+		actCfg.EndMinutes = *clientCfg.EndMinutes
+	}
+	if clientCfg.Instance != nil {
+		actCfg.Instance = *clientCfg.Instance
+	}
+	if clientCfg.PartyID != "" {
+		actCfg.Party.ID = clientCfg.PartyID
+	}
+	if clientCfg.PartySize != 0 {
+		actCfg.Party.Size = clientCfg.PartySize
+	}
+	if clientCfg.PartyMax != 0 {
+		actCfg.Party.Max = clientCfg.PartyMax
+	}
 }
 
 // applyClientActivityOverrides applies per-client overrides that must be set
@@ -715,46 +1063,172 @@ func applyPrivacyOverrides(actCfg *session.ActivityConfig, cfg *config.Config, s
 	state.Branch = cfg.FormatBranch(state.Branch)
 }
 
-// resolveTokenData finds the latest JSONL conversation log, parses it, and
-// returns the computed dollar cost, total token count, and model identifier.
-// Returns zero values if the file cannot be found or parsed.
-func resolveTokenData(cfg *config.Config, pricingData *pricing.PricingData, paths DataPaths) (cost float64, totalTokens int64, model string, jsonlData *session.JSONLData) {
-	latest, findErr := session.FindLatestJSONL(paths.Conversations())
+// resolveTokenData locates the transcript of the displayed session and its
+// subagent transcripts, parses the lines appended since the last call through
+// their caches, and returns the computed dollar cost, total token count, and model identifier.
+// Cost is summed per model so sessions that switch models are priced correctly,
+// and covers every token class, including cache writes and reads. The per-model
+// costs are stored in the returned data's ModelCosts. Cost is computed even
+// when hidden from the card, so the usage ledger and budgets stay complete.
+// The token count remains input plus output. Returns zero values if the
+// transcript cannot be found or parsed.
+func resolveTokenData(
+	pricingData *pricing.PricingData,
+	transcripts session.TranscriptResolver,
+	caches *session.JSONLCaches,
+	state *session.State,
+	now time.Time,
+) (cost float64, totalTokens int64, model string, jsonlData *session.JSONLData) {
+	path, findErr := transcripts.Resolve(state)
 	if findErr != nil {
-		slog.Debug("no conversation log found", "error", findErr)
+		slog.Debug("no conversation log found", "session", state.SessionID, "error", findErr)
 		return 0, 0, "", nil
 	}
-	data, parseErr := session.ParseJSONL(latest)
+	data, parseErr := caches.Parse(state.SessionID, path, now)
 	if parseErr != nil {
 		slog.Debug("failed to parse conversation log", "error", parseErr)
 		return 0, 0, "", nil
 	}
+	for _, subPath := range session.SubagentTranscripts(path) {
+		sub, err := caches.Parse(state.SessionID, subPath, now)
+		if err != nil {
+			slog.Debug("failed to parse subagent log", "path", subPath, "error", err)
+			continue
+		}
+		data.MergeSubagent(sub)
+	}
 	model = data.Model
 	totalTokens = data.InputTokens + data.OutputTokens
-	if cfg.Behavior.ShowCost && model != "" {
-		cost = pricingData.Calculate(model, data.InputTokens, data.OutputTokens)
-	}
+	cost = priceModelUsage(pricingData, data)
+	data.ContextWindow = pricingData.ContextWindow(data.ContextModel)
 	return cost, totalTokens, model, data
 }
 
-// cleanupOrphanedSessions removes session marker files whose mtime is older
-// than maxAge. It is rate-limited internally so calling it on every poll tick
-// is cheap — the actual scan only runs if at least 10 minutes have passed
-// since the last run.
-func cleanupOrphanedSessions(sessDir string, maxAge time.Duration, ls *loopState) {
+// priceModelUsage prices each request of a parsed conversation log at its
+// model's rates, so long-context bands apply only to requests over their
+// threshold. Per-model totals are stored in data.ModelCosts, the subagent
+// share in data.SubagentCost, and the overall total is returned.
+func priceModelUsage(pricingData *pricing.PricingData, data *session.JSONLData) float64 {
+	var total float64
+	data.ModelCosts = make(map[string]float64, len(data.ModelUsage))
+	data.SubagentCost = 0
+	for _, req := range data.Requests {
+		c := priceRequest(pricingData, req)
+		data.ModelCosts[req.Model] += c
+		if req.Sidechain {
+			data.SubagentCost += c
+		}
+		total += c
+	}
+	return total
+}
+
+// priceRequest returns the USD cost of a single request in a parsed
+// conversation log.
+func priceRequest(pricingData *pricing.PricingData, req session.RequestUsage) float64 {
+	u := jsonlUsage(req.Usage)
+	u.PromptTokens = req.Prompt
+	return pricingData.CalculateUsage(req.Model, u)
+}
+
+// jsonlUsage maps the token counts of a request in a parsed conversation log
+// onto a [pricing.Usage]. Cache creation tokens not attributed to the 1-hour
+// cache are billed as 5-minute cache writes.
+func jsonlUsage(u session.ModelUsage) pricing.Usage {
+	return pricing.Usage{
+		InputTokens:        u.InputTokens,
+		OutputTokens:       u.OutputTokens,
+		CacheWrite5mTokens: u.CacheCreationTokens - u.CacheCreation1hTokens,
+		CacheWrite1hTokens: u.CacheCreation1hTokens,
+		CacheReadTokens:    u.CacheReadTokens,
+	}
+}
+
+// writePricingDiagnostics persists the pricing model-matching diagnostics to
+// the data directory whenever they change. After a pricing refresh the model
+// IDs listed so far are resolved against the new data, so an unmatched ID
+// stays listed until a refresh prices it, even if no session uses it anymore.
+func writePricingDiagnostics(pricingData *pricing.PricingData, paths DataPaths, ls *loopState) {
+	if pricingData == nil {
+		return
+	}
+	if pricingData != ls.pricingDiagData {
+		for _, model := range ls.pricingDiag.Unmatched {
+			pricingData.Resolve(model)
+		}
+		for model := range ls.pricingDiag.Matched {
+			pricingData.Resolve(model)
+		}
+		ls.pricingDiagData = pricingData
+	}
+	diag := pricingData.Diagnostics()
+	if diag.Equal(ls.pricingDiag) {
+		return
+	}
+	ls.pricingDiag = diag
+	if err := pricing.WriteDiagnostics(paths.Root, diag); err != nil {
+		slog.Debug("failed to write pricing diagnostics", "error", err)
+	}
+}
+
+// cleanupOrphanedSessions removes session marker files and per-session state
+// files whose mtime is older than maxAge, and migrates legacy per-client state
+// files. It is rate-limited internally so calling it on every poll tick is
+// cheap — the actual scan only runs if at least 10 minutes have passed since
+// the last run.
+func cleanupOrphanedSessions(dataPaths DataPaths, maxAge time.Duration, ls *loopState) {
 	const cleanupInterval = 10 * time.Minute
 	if time.Since(ls.lastCleanup) < cleanupInterval {
 		return
 	}
 	ls.lastCleanup = time.Now()
 
-	entries, err := os.ReadDir(sessDir)
+	cutoff := time.Now().Add(-maxAge)
+	removeOlderThan(dataPaths.Sessions(), cutoff, func(name string) bool {
+		return strings.HasSuffix(name, paths.SessionExt)
+	})
+	migrateLegacyStateFiles(dataPaths.Root)
+	removeOlderThan(dataPaths.Root, cutoff, paths.IsSessionStateFile)
+}
+
+// migrateLegacyStateFiles moves each legacy per-client state file in dataDir
+// to the per-session file of the session it describes, so it is cleaned up
+// like any other session. A file whose session already has a per-session
+// file, or that names no session, is deleted.
+func migrateLegacyStateFiles(dataDir string) {
+	matches, err := filepath.Glob(filepath.Join(dataDir, paths.StateFilePattern))
+	if err != nil {
+		return
+	}
+	for _, path := range matches {
+		if !paths.IsLegacyClientStateFile(path) {
+			continue
+		}
+		if s, _ := session.ReadState(path); s != nil && s.SessionID != "" {
+			client := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "state."), ".json")
+			target := filepath.Join(dataDir, paths.StateFileForSession(client, s.SessionID))
+			if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+				if err := os.Rename(path, target); err == nil {
+					slog.Info("migrated legacy state file", "from", filepath.Base(path), "to", filepath.Base(target))
+					continue
+				}
+			}
+		}
+		if err := os.Remove(path); err == nil {
+			slog.Debug("removed legacy state file", "file", filepath.Base(path))
+		}
+	}
+}
+
+// removeOlderThan removes the files in dir accepted by match whose mtime is
+// before cutoff.
+func removeOlderThan(dir string, cutoff time.Time, match func(name string) bool) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return // directory may not exist yet
 	}
-	cutoff := time.Now().Add(-maxAge)
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), paths.SessionExt) {
+		if e.IsDir() || !match(e.Name()) {
 			continue
 		}
 		info, err := e.Info()
@@ -762,9 +1236,9 @@ func cleanupOrphanedSessions(sessDir string, maxAge time.Duration, ls *loopState
 			continue
 		}
 		if info.ModTime().Before(cutoff) {
-			fp := filepath.Join(sessDir, e.Name())
+			fp := filepath.Join(dir, e.Name())
 			if rmErr := os.Remove(fp); rmErr == nil {
-				slog.Debug("removed orphaned session marker", "file", e.Name())
+				slog.Debug("removed orphaned session file", "file", e.Name())
 			}
 		}
 	}
@@ -774,7 +1248,7 @@ func cleanupOrphanedSessions(sessDir string, maxAge time.Duration, ls *loopState
 // non-nil it is returned directly. When nil, behavior depends on the configured
 // idle mode: "last_activity" returns the most recent activity from [loopState],
 // while the default mode clears Discord presence once and returns nil.
-func handleIdleState(client *discord.Client, actCfg *session.ActivityConfig, ls *loopState, activity *session.Activity) *session.Activity {
+func handleIdleState(pub *publisher, actCfg *session.ActivityConfig, ls *loopState, activity *session.Activity) *session.Activity {
 	if activity != nil {
 		return activity
 	}
@@ -785,8 +1259,10 @@ func handleIdleState(client *discord.Client, actCfg *session.ActivityConfig, ls
 
 	if !ls.idleCleared {
 		slog.Debug("clearing presence (idle/stopped)")
-		if clearErr := client.ClearActivity(); clearErr != nil {
-			slog.Warn("failed to clear activity", "error", clearErr)
+		// Without a connection there is nothing to clear: a new connection
+		// starts without presence.
+		if pub.client().Connected() {
+			pub.publish(nil)
 		}
 		ls.idleCleared = true
 		ls.lastHash = ""
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -192,6 +196,26 @@ func TestToDiscordActivityNoAssets(t *testing.T) {
 	}
 }
 
+func TestToDiscordActivity_ImageURLs(t *testing.T) {
+	input := &session.Activity{
+		Assets: session.Assets{
+			LargeImage: "app_icon",
+			LargeURL:   "https://example.com/large",
+			SmallURL:   "https://example.com/small",
+		},
+	}
+	got := toDiscordActivity(input)
+	if got.Assets == nil || got.Assets.LargeURL != "https://example.com/large" || got.Assets.SmallURL != "" {
+		t.Errorf("Assets = %+v, want only the large image's URL", got.Assets)
+	}
+
+	// URLs alone make no assets section.
+	input.Assets.LargeImage = ""
+	if got := toDiscordActivity(input); got.Assets != nil {
+		t.Errorf("Assets = %+v, want nil without images", got.Assets)
+	}
+}
+
 func TestToDiscordActivityNoButtons(t *testing.T) {
 	input := &session.Activity{
 		Details: "Working",
@@ -208,6 +232,60 @@ func TestToDiscordActivityNoButtons(t *testing.T) {
 	}
 }
 
+func TestToDiscordActivityParty(t *testing.T) {
+	got := toDiscordActivity(&session.Activity{Details: "Working"})
+	if got.Party != nil {
+		t.Errorf("Party = %+v, want nil", got.Party)
+	}
+
+	got = toDiscordActivity(&session.Activity{
+		Details: "Working",
+		Party:   session.Party{ID: "p", Size: 3, Max: 5},
+	})
+	if got.Party == nil || got.Party.ID != "p" || len(got.Party.Size) != 2 || got.Party.Size[0] != 3 || got.Party.Size[1] != 5 {
+		t.Errorf("Party = %+v, want id p, size [3 5]", got.Party)
+	}
+}
+
+func TestToDiscordActivityPayloadFields(t *testing.T) {
+	got := toDiscordActivity(&session.Activity{
+		Type:          "listening",
+		StatusDisplay: "state",
+		Details:       "Working",
+		DetailsURL:    "https://example.com/details",
+		StateURL:      "https://example.com/state",
+		Timestamps:    session.Timestamps{Start: 1000, End: 2500},
+		Assets: session.Assets{
+			LargeImage: "app_icon",
+			LargeURL:   "https://example.com/large",
+			SmallImage: "opus",
+			SmallURL:   "https://example.com/small",
+		},
+		Instance: true,
+	})
+	if got.Type != discord.ActivityListening || got.StatusDisplayType != discord.StatusDisplayState {
+		t.Errorf("Type, StatusDisplayType = %d, %d, want listening, state", got.Type, got.StatusDisplayType)
+	}
+	if got.DetailsURL != "https://example.com/details" || got.StateURL != "https://example.com/state" {
+		t.Errorf("DetailsURL, StateURL = %q, %q", got.DetailsURL, got.StateURL)
+	}
+	if got.Timestamps == nil || *got.Timestamps != (discord.Timestamps{Start: 1000, End: 2500}) {
+		t.Errorf("Timestamps = %+v, want start 1000, end 2500", got.Timestamps)
+	}
+	if got.Assets == nil || got.Assets.LargeURL != "https://example.com/large" || got.Assets.SmallURL != "https://example.com/small" {
+		t.Errorf("Assets = %+v, want the asset URLs", got.Assets)
+	}
+	if !got.Instance {
+		t.Error("Instance = false, want true")
+	}
+
+	// Unset type and status display map to Discord's defaults.
+	got = toDiscordActivity(&session.Activity{Details: "Working"})
+	if got.Type != discord.ActivityPlaying || got.StatusDisplayType != discord.StatusDisplayName {
+		t.Errorf("defaults = %d, %d, want playing, name", got.Type, got.StatusDisplayType)
+	}
+}
+
 func TestToDiscordActivityPartialAssets(t *testing.T) {
 	input := &session.Activity{
 		Details: "Working",
@@ -300,6 +378,31 @@ func TestBuildActivityConfig(t *testing.T) {
 	}
 }
 
+func TestBuildActivityConfigPayload(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Display.ActivityType = "watching"
+	cfg.Display.StatusDisplay = "details"
+	cfg.Display.DetailsURL = "https://example.com/{project}"
+	cfg.Display.Assets.SmallURL = "https://example.com/small"
+	cfg.Display.Timestamps.EndMinutes = 25
+	cfg.Display.Instance = true
+	cfg.Display.Party = config.PartyConfig{ID: "team", Size: 1, Max: 4}
+
+	actCfg := buildActivityConfig(cfg, &tiers.TierData{}, "")
+	if actCfg.ActivityType != "watching" || actCfg.StatusDisplay != "details" {
+		t.Errorf("ActivityType, StatusDisplay = %q, %q", actCfg.ActivityType, actCfg.StatusDisplay)
+	}
+	if actCfg.DetailsURLFormat != "https://example.com/{project}" || actCfg.SmallURLFormat != "https://example.com/small" {
+		t.Errorf("URL formats = %q, %q", actCfg.DetailsURLFormat, actCfg.SmallURLFormat)
+	}
+	if actCfg.EndMinutes != 25 || !actCfg.Instance {
+		t.Errorf("EndMinutes, Instance = %d, %v", actCfg.EndMinutes, actCfg.Instance)
+	}
+	if actCfg.Party != (session.Party{ID: "team", Size: 1, Max: 4}) {
+		t.Errorf("Party = %+v", actCfg.Party)
+	}
+}
+
 // ///////////////////////////////////////////////
 // buildPricingSource Tests
 // ///////////////////////////////////////////////
@@ -349,6 +452,342 @@ func TestBuildPricingSourceWithModels(t *testing.T) {
 	}
 }
 
+func TestBuildPricingSourceWithCachePrices(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Pricing.Source = "static"
+	cfg.Pricing.Models = map[string]config.PricingModelConfig{
+		"claude-opus-4-6": {
+			InputPerToken:        0.000015,
+			OutputPerToken:       0.000075,
+			CacheWrite5mPerToken: 0.00001875,
+			CacheWrite1hPerToken: 0.00003,
+			CacheReadPerToken:    0.0000015,
+		},
+	}
+
+	model := buildPricingSource(cfg).Models["claude-opus-4-6"]
+	if model.CacheWrite5mPerToken != 0.00001875 {
+		t.Errorf("CacheWrite5mPerToken = %v, want %v", model.CacheWrite5mPerToken, 0.00001875)
+	}
+	if model.CacheWrite1hPerToken != 0.00003 {
+		t.Errorf("CacheWrite1hPerToken = %v, want %v", model.CacheWrite1hPerToken, 0.00003)
+	}
+	if model.CacheReadPerToken != 0.0000015 {
+		t.Errorf("CacheReadPerToken = %v, want %v", model.CacheReadPerToken, 0.0000015)
+	}
+}
+
+func TestBuildPricingSourceWithBands(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Pricing.Source = "static"
+	cfg.Pricing.Models = map[string]config.PricingModelConfig{
+		"claude-opus-4-6": {
+			InputPerToken:  0.000015,
+			OutputPerToken: 0.000075,
+			Bands: []config.PricingBandConfig{
+				{AboveInputTokens: 200_000, InputPerToken: 0.00003, OutputPerToken: 0.0001125},
+			},
+		},
+	}
+
+	bands := buildPricingSource(cfg).Models["claude-opus-4-6"].Bands
+	if len(bands) != 1 {
+		t.Fatalf("Bands count = %d, want 1", len(bands))
+	}
+	want := pricing.PriceBand{AboveInputTokens: 200_000, InputPerToken: 0.00003, OutputPerToken: 0.0001125}
+	if bands[0] != want {
+		t.Errorf("Bands[0] = %+v, want %+v", bands[0], want)
+	}
+}
+
+func TestBuildPricingSourceWithAliases(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Pricing.Aliases = map[string]string{"my-proxy-opus": "claude-opus-4-6"}
+
+	src := buildPricingSource(cfg)
+	if got := src.Aliases["my-proxy-opus"]; got != "claude-opus-4-6" {
+		t.Errorf("Aliases[my-proxy-opus] = %q, want %q", got, "claude-opus-4-6")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Currency Builder Tests
+// ///////////////////////////////////////////////
+
+func TestBuildActivityConfigCurrency(t *testing.T) {
+	td := &tiers.TierData{DefaultIcon: "default"}
+	cfg := config.DefaultConfig()
+	if actCfg := buildActivityConfig(cfg, td, ""); actCfg.CurrencySymbol != "$" || actCfg.CurrencySymbolAfter {
+		t.Errorf("default currency = %q (after=%v), want $ before", actCfg.CurrencySymbol, actCfg.CurrencySymbolAfter)
+	}
+
+	cfg.Display.Currency.Code = "EUR"
+	cfg.Display.Currency.SymbolPosition = "after"
+	if actCfg := buildActivityConfig(cfg, td, ""); actCfg.CurrencySymbol != "€" || !actCfg.CurrencySymbolAfter {
+		t.Errorf("EUR currency = %q (after=%v), want € after", actCfg.CurrencySymbol, actCfg.CurrencySymbolAfter)
+	}
+
+	cfg.Display.Currency.Symbol = "EUR"
+	if actCfg := buildActivityConfig(cfg, td, ""); actCfg.CurrencySymbol != "EUR" {
+		t.Errorf("CurrencySymbol = %q, want configured override %q", actCfg.CurrencySymbol, "EUR")
+	}
+}
+
+// ///////////////////////////////////////////////
+// spendTracker Tests
+// ///////////////////////////////////////////////
+
+func TestSpendTracker_RecordsPricedCostOnly(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Budget.Daily = 10
+	tracker := newSpendTracker(cfg, DataPaths{Root: t.TempDir()})
+	if tracker == nil {
+		t.Fatal("newSpendTracker returned nil")
+	}
+
+	now := time.Now()
+	data := &session.JSONLData{
+		ModelUsage: map[string]session.ModelUsage{"claude-opus-4-6": {InputTokens: 100, TurnCount: 2, ToolUseCount: 3}},
+		ModelCosts: map[string]float64{"claude-opus-4-6": 4},
+	}
+	statuses := tracker.update("s1", "web", data, true, now)
+	if len(statuses) != 1 || statuses[0].Spent != 4 {
+		t.Fatalf("statuses = %+v, want daily budget with 4 spent", statuses)
+	}
+	records := tracker.ledger.Records()
+	if len(records) != 1 || records[0].Project != "web" || records[0].ToolUses != 3 || records[0].Turns != 2 {
+		t.Errorf("records = %+v, want one web record with 2 turns and 3 tool uses", records)
+	}
+
+	// Unpriced usage must not erase the session's recorded cost.
+	statuses = tracker.update("s1", "web", &session.JSONLData{}, false, now)
+	if statuses[0].Spent != 4 {
+		t.Errorf("Spent after unpriced update = %v, want 4", statuses[0].Spent)
+	}
+}
+
+func TestSpendTracker_Nil(t *testing.T) {
+	var tracker *spendTracker
+	if got := tracker.update("s1", "", nil, true, time.Now()); got != nil {
+		t.Errorf("nil tracker update = %v, want nil", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// buildNetworkSettings Tests
+// ///////////////////////////////////////////////
+
+func TestBuildNetworkSettings(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s := buildNetworkSettings(cfg, "1.2.3")
+	if s.UserAgent != "agentcord/1.2.3" {
+		t.Errorf("UserAgent = %q, want %q", s.UserAgent, "agentcord/1.2.3")
+	}
+	if s.Timeout != 10*time.Second || s.ConnectTimeout != 5*time.Second {
+		t.Errorf("timeouts = %v/%v, want 10s/5s", s.Timeout, s.ConnectTimeout)
+	}
+
+	cfg.Network.Offline = true
+	cfg.Network.Proxy = "http://proxy.internal:3128"
+	cfg.Network.UserAgent = "custom"
+	s = buildNetworkSettings(cfg, "1.2.3")
+	if !s.Offline || s.ProxyURL != "http://proxy.internal:3128" || s.UserAgent != "custom" {
+		t.Errorf("settings = %+v, want offline, proxy, and custom user agent", s)
+	}
+}
+
+// ///////////////////////////////////////////////
+// priceModelUsage Tests
+// ///////////////////////////////////////////////
+
+func TestPriceModelUsage_SumsPerModel(t *testing.T) {
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6":  {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+		"claude-haiku-4-5": {InputPerToken: 0.000001, OutputPerToken: 0.000005},
+	}}
+	data := &session.JSONLData{
+		Model: "claude-haiku-4-5",
+		Requests: []session.RequestUsage{
+			{Model: "claude-opus-4-6", Usage: session.ModelUsage{InputTokens: 600, OutputTokens: 400}},
+			{Model: "claude-haiku-4-5", Usage: session.ModelUsage{InputTokens: 1000, OutputTokens: 1000}},
+			{Model: "claude-opus-4-6", Usage: session.ModelUsage{InputTokens: 400, OutputTokens: 600}},
+		},
+	}
+
+	total := priceModelUsage(pd, data)
+	if want := 0.09 + 0.006; math.Abs(total-want) > 1e-12 {
+		t.Errorf("total = %v, want %v", total, want)
+	}
+	if got := data.ModelCosts["claude-opus-4-6"]; math.Abs(got-0.09) > 1e-12 {
+		t.Errorf("opus cost = %v, want 0.09", got)
+	}
+	if got := data.ModelCosts["claude-haiku-4-5"]; math.Abs(got-0.006) > 1e-12 {
+		t.Errorf("haiku cost = %v, want 0.006", got)
+	}
+}
+
+func TestPriceModelUsage_BandPerRequest(t *testing.T) {
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-sonnet-4-5": {
+			InputPerToken:  0.000003,
+			OutputPerToken: 0.000015,
+			Bands: []pricing.PriceBand{
+				{AboveInputTokens: 200_000, InputPerToken: 0.000006, OutputPerToken: 0.0000225},
+			},
+		},
+	}}
+	data := &session.JSONLData{
+		Requests: []session.RequestUsage{
+			{Model: "claude-sonnet-4-5", Usage: session.ModelUsage{InputTokens: 150_000, OutputTokens: 1000}},
+			{Model: "claude-sonnet-4-5", Usage: session.ModelUsage{InputTokens: 10_000, CacheReadTokens: 240_000, OutputTokens: 1000}},
+		},
+	}
+
+	// Only the second request crosses the threshold; summed usage would not
+	// tell them apart.
+	got := priceModelUsage(pd, data)
+	want := (150_000*0.000003 + 1000*0.000015) + (10_000*0.000006 + 1000*0.0000225)
+	if math.Abs(got-want) > 1e-12 {
+		t.Errorf("total = %v, want %v", got, want)
+	}
+}
+
+func TestPriceModelUsage_SubagentCost(t *testing.T) {
+	pd := &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+	}}
+	data := &session.JSONLData{
+		Requests: []session.RequestUsage{
+			{Model: "claude-opus-4-6", Usage: session.ModelUsage{InputTokens: 1000}},
+			{Model: "claude-opus-4-6", Sidechain: true, Usage: session.ModelUsage{OutputTokens: 1000}},
+		},
+	}
+	total := priceModelUsage(pd, data)
+	if math.Abs(total-0.09) > 1e-12 || math.Abs(data.SubagentCost-0.075) > 1e-12 {
+		t.Errorf("total = %v, subagent = %v; want 0.09 and 0.075", total, data.SubagentCost)
+	}
+}
+
+func TestPriceModelUsage_NilPricing(t *testing.T) {
+	data := &session.JSONLData{
+		Requests: []session.RequestUsage{
+			{Model: "claude-opus-4-6", Usage: session.ModelUsage{InputTokens: 1000}},
+		},
+	}
+	if got := priceModelUsage(nil, data); got != 0 {
+		t.Errorf("total = %v, want 0 without pricing data", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// applyStatusline Tests
+// ///////////////////////////////////////////////
+
+func TestFindLatestState_PerSessionAndLegacy(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, paths.StateFileForClient("claude-code")),
+		[]byte(`{"$version":1,"sessionId":"legacy","lastActivity":300}`), 0o644)
+	os.WriteFile(filepath.Join(dir, paths.StateFileForSession("claude-code", "a")),
+		[]byte(`{"$version":2,"sessionId":"a","client":"claude-code","lastActivity":200}`), 0o644)
+	os.WriteFile(filepath.Join(dir, paths.StateFileForSession("claude-code", "b")),
+		[]byte(`{"$version":2,"sessionId":"b","client":"claude-code","lastActivity":100}`), 0o644)
+
+	got, err := findLatestState(dir)
+	if err != nil || got.SessionID != "legacy" {
+		t.Fatalf("findLatestState = %+v, %v; want the legacy per-client state", got, err)
+	}
+
+	os.Remove(filepath.Join(dir, paths.StateFileForClient("claude-code")))
+	got, err = findLatestState(dir)
+	if err != nil || got.SessionID != "a" {
+		t.Errorf("findLatestState = %+v, %v; want session a", got, err)
+	}
+}
+
+func TestApplyStatusline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statusline.json")
+	os.WriteFile(path, []byte(`{"session_id":"s1","cwd":"/home/me/web","cost":{"total_cost_usd":2}}`), 0o644)
+	mod := time.Unix(1_700_000_000, 0)
+	os.Chtimes(path, mod, mod)
+
+	if got := applyStatusline(nil, path); got == nil || got.SessionID != "s1" || got.Project != "web" {
+		t.Errorf("without hook state = %+v, want a state built from the statusline", got)
+	}
+
+	same := &session.State{SessionID: "s1", Project: "hooked"}
+	if got := applyStatusline(same, path); got != same || got.Statusline == nil {
+		t.Errorf("same session = %+v, want the hook state with the statusline merged", got)
+	}
+
+	newer := &session.State{SessionID: "s2", LastActivity: mod.Unix() + 60}
+	if got := applyStatusline(newer, path); got != newer || got.Statusline != nil {
+		t.Errorf("newer hook state for another session = %+v, want it unchanged", got)
+	}
+
+	older := &session.State{SessionID: "s2", LastActivity: mod.Unix() - 60}
+	if got := applyStatusline(older, path); got.SessionID != "s1" {
+		t.Errorf("older hook state for another session = %+v, want the statusline session", got)
+	}
+
+	missing := &session.State{SessionID: "s1"}
+	if got := applyStatusline(missing, filepath.Join(t.TempDir(), "none.json")); got != missing {
+		t.Error("a missing statusline file should leave the state unchanged")
+	}
+}
+
+// ///////////////////////////////////////////////
+// buildTranscriptResolver Tests
+// ///////////////////////////////////////////////
+
+func TestBuildTranscriptResolver(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cfg := config.DefaultConfig()
+	cfg.Behavior.TranscriptRoots = []string{"~/.claude/projects", "/srv/transcripts"}
+	r := buildTranscriptResolver(cfg, DataPaths{Root: "/data"})
+
+	want := []string{filepath.Join(home, ".claude", "projects"), "/srv/transcripts"}
+	if len(r.Roots) != 2 || r.Roots[0] != want[0] || r.Roots[1] != want[1] {
+		t.Errorf("Roots = %v, want %v", r.Roots, want)
+	}
+	if r.Fallback != filepath.Join("/data", "conversations") {
+		t.Errorf("Fallback = %q, want the conversations directory", r.Fallback)
+	}
+	if got := expandHome("~other/x"); got != "~other/x" {
+		t.Errorf("expandHome(~other/x) = %q, want unchanged", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// jsonlUsage Tests
+// ///////////////////////////////////////////////
+
+func TestJSONLUsage_SplitsCacheWrites(t *testing.T) {
+	data := session.ModelUsage{
+		InputTokens:           100,
+		OutputTokens:          50,
+		CacheCreationTokens:   300,
+		CacheCreation1hTokens: 200,
+		CacheReadTokens:       5000,
+	}
+
+	u := jsonlUsage(data)
+	if u.InputTokens != 100 || u.OutputTokens != 50 {
+		t.Errorf("input/output = %d/%d, want 100/50", u.InputTokens, u.OutputTokens)
+	}
+	if u.CacheWrite5mTokens != 100 {
+		t.Errorf("CacheWrite5mTokens = %d, want 100", u.CacheWrite5mTokens)
+	}
+	if u.CacheWrite1hTokens != 200 {
+		t.Errorf("CacheWrite1hTokens = %d, want 200", u.CacheWrite1hTokens)
+	}
+	if u.CacheReadTokens != 5000 {
+		t.Errorf("CacheReadTokens = %d, want 5000", u.CacheReadTokens)
+	}
+}
+
 // ///////////////////////////////////////////////
 // defaultDataDir Tests
 // ///////////////////////////////////////////////
@@ -556,6 +995,47 @@ func TestCheckDaemonIdle_IdleTimeout(t *testing.T) {
 	}
 }
 
+// ///////////////////////////////////////////////
+// writePricingDiagnostics Tests
+// ///////////////////////////////////////////////
+
+func TestWritePricingDiagnostics(t *testing.T) {
+	dataPaths := DataPaths{Root: t.TempDir()}
+	ls := &loopState{}
+	read := func() pricing.Diagnostics {
+		t.Helper()
+		var d pricing.Diagnostics
+		b, err := os.ReadFile(dataPaths.PricingDiagnostics())
+		if err != nil {
+			t.Fatalf("reading diagnostics: %v", err)
+		}
+		json.Unmarshal(b, &d)
+		return d
+	}
+	models := map[string]pricing.ModelPricing{"claude-opus-4-6": {InputPerToken: 1}}
+
+	pd := &pricing.PricingData{Models: models}
+	pd.Resolve("gpt-4")
+	writePricingDiagnostics(pd, dataPaths, ls)
+	if d := read(); !slices.Equal(d.Unmatched, []string{"gpt-4"}) {
+		t.Errorf("Unmatched = %v, want [gpt-4]", d.Unmatched)
+	}
+
+	// A refresh keeps the unmatched ID until the new data prices it.
+	pd = &pricing.PricingData{Models: models}
+	writePricingDiagnostics(pd, dataPaths, ls)
+	if d := read(); !slices.Equal(d.Unmatched, []string{"gpt-4"}) {
+		t.Errorf("Unmatched after refresh = %v, want [gpt-4]", d.Unmatched)
+	}
+
+	// Moving from unmatched to matched keeps the count but rewrites the file.
+	pd = &pricing.PricingData{Models: models, Aliases: map[string]string{"gpt-4": "claude-opus-4-6"}}
+	writePricingDiagnostics(pd, dataPaths, ls)
+	if d := read(); len(d.Unmatched) != 0 || d.Matched["gpt-4"] != "claude-opus-4-6" {
+		t.Errorf("diagnostics = %+v, want gpt-4 matched to claude-opus-4-6", d)
+	}
+}
+
 // ///////////////////////////////////////////////
 // cleanupOrphanedSessions Tests
 // ///////////////////////////////////////////////
@@ -575,7 +1055,7 @@ func TestCleanupOrphanedSessions_RemovesOldFiles(t *testing.T) {
 	os.Chtimes(oldFile, past, past)
 
 	ls := &loopState{}
-	cleanupOrphanedSessions(sessDir, 24*time.Hour, ls)
+	cleanupOrphanedSessions(DataPaths{Root: filepath.Dir(sessDir)}, 24*time.Hour, ls)
 
 	if _, err := os.Stat(oldFile); !os.IsNotExist(err) {
 		t.Error("old session file should have been removed")
@@ -594,13 +1074,69 @@ func TestCleanupOrphanedSessions_KeepsRecentFiles(t *testing.T) {
 	}
 
 	ls := &loopState{}
-	cleanupOrphanedSessions(sessDir, 24*time.Hour, ls)
+	cleanupOrphanedSessions(DataPaths{Root: filepath.Dir(sessDir)}, 24*time.Hour, ls)
 
 	if _, err := os.Stat(recentFile); os.IsNotExist(err) {
 		t.Error("recent session file should NOT have been removed")
 	}
 }
 
+func TestCleanupOrphanedSessions_RemovesOldSessionStates(t *testing.T) {
+	dir := t.TempDir()
+	past := time.Now().Add(-25 * time.Hour)
+	oldState := filepath.Join(dir, paths.StateFileForSession("claude-code", "old"))
+	recentState := filepath.Join(dir, paths.StateFileForSession("claude-code", "recent"))
+	legacyState := filepath.Join(dir, paths.StateFileForClient("claude-code"))
+	for _, p := range []string{oldState, recentState, legacyState} {
+		os.WriteFile(p, []byte("{}"), 0o644)
+	}
+	os.Chtimes(oldState, past, past)
+	os.Chtimes(legacyState, past, past)
+
+	cleanupOrphanedSessions(DataPaths{Root: dir}, 24*time.Hour, &loopState{})
+
+	// The legacy file names no session, so it is removed rather than migrated.
+	for _, p := range []string{oldState, legacyState} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", filepath.Base(p))
+		}
+	}
+	if _, err := os.Stat(recentState); err != nil {
+		t.Errorf("recent per-session state should have been kept: %v", err)
+	}
+}
+
+func TestMigrateLegacyStateFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	// cursor's legacy file is moved to its session's file; claude-code's is
+	// superseded by the per-session file the hooks already wrote.
+	write(paths.StateFileForClient("cursor"), `{"$version":2,"sessionId":"a.b","client":"cursor","project":"legacy"}`)
+	write(paths.StateFileForClient("claude-code"), `{"$version":1,"sessionId":"c","project":"legacy"}`)
+	write(paths.StateFileForSession("claude-code", "c"), `{"$version":2,"sessionId":"c","client":"claude-code","project":"current"}`)
+
+	migrateLegacyStateFiles(dir)
+
+	for _, client := range []string{"cursor", "claude-code"} {
+		if _, err := os.Stat(filepath.Join(dir, paths.StateFileForClient(client))); !os.IsNotExist(err) {
+			t.Errorf("legacy %s state file should be gone", client)
+		}
+	}
+	moved, err := session.ReadState(filepath.Join(dir, "state.cursor.a_b.json"))
+	if err != nil || moved.SessionID != "a.b" || moved.Project != "legacy" {
+		t.Errorf("migrated state = %+v, %v; want session a.b from the legacy file", moved, err)
+	}
+	kept, err := session.ReadState(filepath.Join(dir, paths.StateFileForSession("claude-code", "c")))
+	if err != nil || kept.Project != "current" {
+		t.Errorf("per-session state = %+v, %v; want it left as written", kept, err)
+	}
+}
+
 func TestCleanupOrphanedSessions_RateLimited(t *testing.T) {
 	sessDir := filepath.Join(t.TempDir(), "sessions")
 	if err := os.MkdirAll(sessDir, 0o755); err != nil {
@@ -610,7 +1146,7 @@ func TestCleanupOrphanedSessions_RateLimited(t *testing.T) {
 	ls := &loopState{}
 
 	// First call runs the cleanup and sets lastCleanup.
-	cleanupOrphanedSessions(sessDir, 24*time.Hour, ls)
+	cleanupOrphanedSessions(DataPaths{Root: filepath.Dir(sessDir)}, 24*time.Hour, ls)
 	firstCleanup := ls.lastCleanup
 
 	// Create an old file after the first cleanup.
@@ -622,7 +1158,7 @@ func TestCleanupOrphanedSessions_RateLimited(t *testing.T) {
 	os.Chtimes(oldFile, past, past)
 
 	// Second call should be rate-limited (no-op) because < 10 minutes elapsed.
-	cleanupOrphanedSessions(sessDir, 24*time.Hour, ls)
+	cleanupOrphanedSessions(DataPaths{Root: filepath.Dir(sessDir)}, 24*time.Hour, ls)
 
 	if ls.lastCleanup != firstCleanup {
 		t.Error("lastCleanup should not have been updated on rate-limited call")
@@ -638,6 +1174,62 @@ func TestCleanupOrphanedSessions_RateLimited(t *testing.T) {
 // applyClientOverrides Tests
 // ///////////////////////////////////////////////
 
+func TestProcessState_ClientSwitchRebuildsConfig(t *testing.T) {
+	dir := t.TempDir()
+	dataPaths := DataPaths{Root: dir}
+	cfg := config.DefaultConfig()
+	cfg.Display.Timestamps.EndMinutes = 25
+	off := 0
+	cfg.Clients = map[string]config.ClientConfig{
+		"cursor": {
+			ActivityType:  "competing",
+			StatusDisplay: "details",
+			DetailsURL:    "https://cursor.com/{project}",
+			EndMinutes:    &off,
+			PartyID:       "cursor-team",
+		},
+	}
+	store := seedDataStore(refreshConfig{dataDir: dir})
+	pub := newPublisher(discord.NewClient(cfg.Discord.AppID))
+	ls := &loopState{
+		activeAppID:      cfg.Discord.AppID,
+		transcripts:      buildTranscriptResolver(cfg, dataPaths),
+		transcriptCaches: session.NewJSONLCaches(),
+	}
+	actCfg := buildActivityConfig(cfg, store.tiers.Load(), "")
+
+	// show makes client's session the most recently active and processes it.
+	show := func(client string, lastActivity int64) session.ActivityConfig {
+		t.Helper()
+		body := fmt.Sprintf(`{"$version":2,"sessionId":%q,"client":%q,"lastActivity":%d}`, client, client, lastActivity)
+		if err := os.WriteFile(dataPaths.StateForSession(client, client), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		processState(pub, &actCfg, cfg, store, dataPaths, ls)
+		got := actCfg
+		// Tier names come from a map, so their order varies between builds.
+		got.ModelTiers = slices.Sorted(slices.Values(actCfg.ModelTiers))
+		return got
+	}
+
+	first := show("claude-code", 100)
+	if first.EndMinutes != 25 || first.ActivityType != cfg.Display.ActivityType || first.Party.ID != "" {
+		t.Fatalf("claude-code config = end %d, type %q, party %q; want the base config",
+			first.EndMinutes, first.ActivityType, first.Party.ID)
+	}
+
+	cursor := show("cursor", 200)
+	if cursor.EndMinutes != 0 || cursor.ActivityType != "competing" || cursor.StatusDisplay != "details" ||
+		cursor.DetailsURLFormat != "https://cursor.com/{project}" || cursor.Party.ID != "cursor-team" {
+		t.Errorf("cursor config = end %d, type %q, status %q, url %q, party %q; want the cursor overrides",
+			cursor.EndMinutes, cursor.ActivityType, cursor.StatusDisplay, cursor.DetailsURLFormat, cursor.Party.ID)
+	}
+
+	if again := show("claude-code", 300); !reflect.DeepEqual(again, first) {
+		t.Errorf("claude-code config after cursor = %+v, want %+v", again, first)
+	}
+}
+
 func TestApplyClientOverrides_Details(t *testing.T) {
 	actCfg := session.ActivityConfig{
 		DetailsFormat: "Working on: {project} ({branch})",
@@ -655,7 +1247,7 @@ func TestApplyClientOverrides_Details(t *testing.T) {
 
 func TestApplyClientOverrides_State(t *testing.T) {
 	actCfg := session.ActivityConfig{
-		StateFormat: "{model} · ~${cost} API value",
+		StateFormat: "{model} · ~{cost} API value",
 	}
 	clientCfg := config.ClientConfig{
 		State: "Using {model}",
@@ -671,7 +1263,7 @@ func TestApplyClientOverrides_State(t *testing.T) {
 func TestApplyClientOverrides_AllFields(t *testing.T) {
 	actCfg := session.ActivityConfig{
 		DetailsFormat: "Working on: {project}",
-		StateFormat:   "{model} · ~${cost}",
+		StateFormat:   "{model} · ~{cost}",
 		LargeImage:    "app_icon",
 		LargeText:     "Claude Code",
 	}
@@ -698,6 +1290,49 @@ func TestApplyClientOverrides_AllFields(t *testing.T) {
 	}
 }
 
+func TestApplyClientOverrides_PayloadFields(t *testing.T) {
+	actCfg := session.ActivityConfig{
+		ActivityType:  "playing",
+		StatusDisplay: "name",
+		Instance:      true,
+		Party:         session.Party{ID: "team", Size: 1, Max: 4},
+	}
+	instance := false
+	endMinutes := 50
+	clientCfg := config.ClientConfig{
+		ActivityType:  "competing",
+		StatusDisplay: "details",
+		DetailsURL:    "https://cursor.com/{project}",
+		StateURL:      "https://cursor.com/state",
+		LargeURL:      "https://cursor.com",
+		SmallURL:      "https://cursor.com/small",
+		EndMinutes:    &endMinutes,
+		Instance:      &instance,
+		PartySize:     2,
+	}
+
+	applyClientOverrides(&actCfg, clientCfg)
+
+	if actCfg.ActivityType != "competing" || actCfg.StatusDisplay != "details" {
+		t.Errorf("ActivityType, StatusDisplay = %q, %q", actCfg.ActivityType, actCfg.StatusDisplay)
+	}
+	if actCfg.DetailsURLFormat != "https://cursor.com/{project}" || actCfg.StateURLFormat != "https://cursor.com/state" ||
+		actCfg.LargeURLFormat != "https://cursor.com" || actCfg.SmallURLFormat != "https://cursor.com/small" {
+		t.Errorf("URL formats = %q, %q, %q, %q",
+			actCfg.DetailsURLFormat, actCfg.StateURLFormat, actCfg.LargeURLFormat, actCfg.SmallURLFormat)
+	}
+	if actCfg.EndMinutes != 50 {
+		t.Errorf("EndMinutes = %d, want 50", actCfg.EndMinutes)
+	}
+	if actCfg.Instance {
+		t.Error("Instance = true, want the client's false")
+	}
+	// Unset party fields keep the [display.party] values.
+	if actCfg.Party != (session.Party{ID: "team", Size: 2, Max: 4}) {
+		t.Errorf("Party = %+v, want team 2 of 4", actCfg.Party)
+	}
+}
+
 func TestApplyClientOverrides_EmptyFieldsNoOp(t *testing.T) {
 	actCfg := session.ActivityConfig{
 		DetailsFormat: "original details",
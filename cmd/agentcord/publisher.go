@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/discord"
+)
+
+// ///////////////////////////////////////////////
+// Rate Limiting
+// ///////////////////////////////////////////////
+
+const (
+	// publishBurst is how many activity updates Discord accepts in a row.
+	publishBurst = 5
+	// publishWindow is the window Discord's SET_ACTIVITY limit applies to:
+	// publishBurst updates per publishWindow. Extra updates are silently
+	// dropped by Discord.
+	publishWindow = 20 * time.Second
+)
+
+// tokenBucket is a token bucket rate limiter. It holds up to capacity
+// tokens and regains one every refill; each update spends one.
+type tokenBucket struct {
+	// capacity is the maximum number of tokens, i.e. the burst size.
+	capacity float64
+	// tokens is the number of tokens left at last.
+	tokens float64
+	// refill is the time it takes to regain one token.
+	refill time.Duration
+	// last is when tokens was last brought up to date.
+	last time.Time
+}
+
+// newTokenBucket returns a full bucket allowing burst updates per window.
+func newTokenBucket(burst int, window time.Duration, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		refill:   window / time.Duration(burst),
+		last:     now,
+	}
+}
+
+// take spends a token and returns 0 when one is available at now.
+// Otherwise it spends nothing and returns how long until one is.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.capacity, b.tokens+float64(elapsed)/float64(b.refill))
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(b.refill))
+}
+
+// ///////////////////////////////////////////////
+// Presence Publisher
+// ///////////////////////////////////////////////
+
+// publisher owns the [discord.Client] and sends presence updates from its
+// own goroutine, so a slow socket never blocks state processing. Updates
+// are rate limited to Discord's SET_ACTIVITY limit; while waiting for the
+// limit, newer updates replace older ones so the latest is always sent.
+// Only the publisher replaces or closes the client, never while an update
+// is being sent.
+type publisher struct {
+	// mu protects dc, pending and hasPending.
+	mu sync.Mutex
+	// sendMu is held while an update is sent, so the client is not replaced
+	// or closed under it.
+	sendMu sync.Mutex
+	// dc is the Discord client updates are sent with. Replaced when the
+	// active client needs a different application ID.
+	dc *discord.Client
+	// pending is the latest update not yet sent; nil clears the presence.
+	pending *discord.Activity
+	// hasPending reports whether pending holds an update.
+	hasPending bool
+
+	// wake signals the publishing goroutine that an update is pending.
+	// Buffered to 1 so signals coalesce.
+	wake chan struct{}
+	// quit is closed to stop the publishing goroutine.
+	quit chan struct{}
+	// stopped is closed when the publishing goroutine has returned.
+	stopped chan struct{}
+	// bucket limits the update rate. Only used by the publishing goroutine.
+	bucket *tokenBucket
+	// send delivers an update to Discord. Replaced in tests.
+	send func(a *discord.Activity) error
+
+	// published counts the updates Discord accepted.
+	published atomic.Uint64
+	// dropped counts the updates replaced by a newer one before being sent.
+	dropped atomic.Uint64
+	// retry is set when an update failed for a reason other than Discord
+	// rejecting it, so the next state update resends it.
+	retry atomic.Bool
+}
+
+// newPublisher returns a publisher sending with dc. Start it with start.
+func newPublisher(dc *discord.Client) *publisher {
+	p := &publisher{
+		dc:      dc,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		bucket:  newTokenBucket(publishBurst, publishWindow, time.Now()),
+	}
+	p.send = p.sendToDiscord
+	return p
+}
+
+// client returns the Discord client updates are sent with.
+func (p *publisher) client() *discord.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dc
+}
+
+// replaceClient makes dc the Discord client updates are sent with and closes
+// the old one. It waits for an update being sent with the old client.
+func (p *publisher) replaceClient(dc *discord.Client) {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	p.mu.Lock()
+	old := p.dc
+	p.dc = dc
+	p.mu.Unlock()
+	old.Close()
+}
+
+// publish queues a for sending; nil clears the presence. An update still
+// waiting to be sent is replaced and counted as dropped. Never blocks.
+func (p *publisher) publish(a *discord.Activity) {
+	p.mu.Lock()
+	if p.hasPending {
+		p.dropped.Add(1)
+	}
+	p.pending = a
+	p.hasPending = true
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the pending update, reporting whether there was one.
+func (p *publisher) next() (*discord.Activity, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	a, ok := p.pending, p.hasPending
+	p.pending = nil
+	p.hasPending = false
+	return a, ok
+}
+
+// waiting reports whether an update is pending.
+func (p *publisher) waiting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hasPending
+}
+
+// stats returns the number of updates published and dropped so far.
+func (p *publisher) stats() (published, dropped uint64) {
+	return p.published.Load(), p.dropped.Load()
+}
+
+// takeRetry reports whether the last update failed and should be resent,
+// clearing the flag.
+func (p *publisher) takeRetry() bool {
+	return p.retry.Swap(false)
+}
+
+// start runs the publishing goroutine. Stop it with stop.
+func (p *publisher) start() {
+	go p.run()
+}
+
+// stop stops the publishing goroutine, waits for it to return, and then
+// closes the Discord client, so no update is sent on a closed client.
+// Updates still pending are not sent.
+func (p *publisher) stop() {
+	close(p.quit)
+	<-p.stopped
+	p.client().Close()
+}
+
+// run sends pending updates as the rate limit allows until quit is closed.
+func (p *publisher) run() {
+	defer close(p.stopped)
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-p.wake:
+		}
+		if !p.waiting() {
+			continue // already sent after an earlier signal
+		}
+		for wait := p.bucket.take(time.Now()); wait > 0; wait = p.bucket.take(time.Now()) {
+			timer := time.NewTimer(wait)
+			select {
+			case <-p.quit:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		if a, ok := p.next(); ok {
+			p.deliver(a)
+		}
+	}
+}
+
+// deliver sends a and records the outcome.
+func (p *publisher) deliver(a *discord.Activity) {
+	p.sendMu.Lock()
+	err := p.send(a)
+	p.sendMu.Unlock()
+	if err != nil {
+		msg := "failed to set activity"
+		if a == nil {
+			msg = "failed to clear activity"
+		}
+		logActivityError(msg, err)
+		var cmdErr *discord.CommandError
+		if !errors.As(err, &cmdErr) {
+			// A rejected payload is not resent until it changes.
+			p.retry.Store(true)
+		}
+		return
+	}
+	p.published.Add(1)
+	if a == nil {
+		slog.Debug("presence cleared")
+		return
+	}
+	slog.Debug("presence updated", "details", a.Details, "state", a.State)
+}
+
+// sendToDiscord sends a with the current Discord client.
+func (p *publisher) sendToDiscord(a *discord.Activity) error {
+	if a == nil {
+		return p.client().ClearActivity()
+	}
+	return p.client().SetActivity(a)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/discord"
+)
+
+// ///////////////////////////////////////////////
+// tokenBucket Tests
+// ///////////////////////////////////////////////
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := newTokenBucket(5, 20*time.Second, start)
+
+	for i := range 5 {
+		if wait := b.take(start); wait != 0 {
+			t.Fatalf("update %d of the burst waits %v, want none", i+1, wait)
+		}
+	}
+	if wait := b.take(start); wait != 4*time.Second {
+		t.Errorf("sixth update waits %v, want 4s", wait)
+	}
+	if wait := b.take(start.Add(time.Second)); wait != 3*time.Second {
+		t.Errorf("after 1s the update waits %v, want 3s", wait)
+	}
+	if wait := b.take(start.Add(4 * time.Second)); wait != 0 {
+		t.Errorf("after 4s the update waits %v, want none", wait)
+	}
+
+	// An idle bucket refills to its capacity, not beyond.
+	later := start.Add(time.Hour)
+	for i := range 5 {
+		if wait := b.take(later); wait != 0 {
+			t.Fatalf("update %d after an idle hour waits %v, want none", i+1, wait)
+		}
+	}
+	if wait := b.take(later); wait == 0 {
+		t.Error("the refilled bucket allowed more than its capacity")
+	}
+}
+
+// ///////////////////////////////////////////////
+// publisher Tests
+// ///////////////////////////////////////////////
+
+// recordingPublisher returns a publisher whose updates are sent on the
+// returned channel, failing with err.
+func recordingPublisher(err error) (*publisher, chan *discord.Activity) {
+	sent := make(chan *discord.Activity, 16)
+	p := newPublisher(discord.NewClient("test"))
+	p.send = func(a *discord.Activity) error {
+		sent <- a
+		return err
+	}
+	return p, sent
+}
+
+// nextSent waits for the next update p sent.
+func nextSent(t *testing.T, sent <-chan *discord.Activity) *discord.Activity {
+	t.Helper()
+	select {
+	case a := <-sent:
+		return a
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update sent")
+		return nil
+	}
+}
+
+func TestPublisherCoalesces(t *testing.T) {
+	p, sent := recordingPublisher(nil)
+	// An empty bucket holds updates back so they can coalesce.
+	p.bucket = newTokenBucket(1, 100*time.Millisecond, time.Now())
+	p.bucket.take(time.Now())
+	p.start()
+	defer p.stop()
+
+	for _, details := range []string{"one", "two", "three"} {
+		p.publish(&discord.Activity{Details: details})
+	}
+	if a := nextSent(t, sent); a.Details != "three" {
+		t.Errorf("sent %q, want only the latest update", a.Details)
+	}
+	select {
+	case a := <-sent:
+		t.Errorf("replaced update %q was sent", a.Details)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	published, dropped := p.stats()
+	if published != 1 || dropped != 2 {
+		t.Errorf("stats = %d published, %d dropped; want 1, 2", published, dropped)
+	}
+}
+
+func TestPublisherClear(t *testing.T) {
+	p, sent := recordingPublisher(nil)
+	p.start()
+	defer p.stop()
+
+	p.publish(nil)
+	if a := nextSent(t, sent); a != nil {
+		t.Errorf("sent %+v, want a clear", a)
+	}
+}
+
+func TestPublisherRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantRetry bool
+	}{
+		{"sent", nil, false},
+		{"connection error", discord.ErrNoResponse, true},
+		{"rejected by Discord", &discord.CommandError{Cmd: "SET_ACTIVITY", Code: 4000}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, sent := recordingPublisher(tt.err)
+			p.start()
+			defer p.stop()
+
+			p.publish(&discord.Activity{Details: "test"})
+			nextSent(t, sent)
+			// The outcome is recorded right after sending.
+			deadline := time.Now().Add(time.Second)
+			for tt.wantRetry && !p.retry.Load() && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if got := p.takeRetry(); got != tt.wantRetry {
+				t.Errorf("takeRetry() = %v, want %v", got, tt.wantRetry)
+			}
+			if p.takeRetry() {
+				t.Error("takeRetry() should clear the flag")
+			}
+			if published, _ := p.stats(); tt.err != nil && published != 0 {
+				t.Errorf("published = %d after a failure, want 0", published)
+			}
+		})
+	}
+}
+
+// blockingPublisher returns a started publisher whose sends report on
+// sending and then block until release is closed.
+func blockingPublisher() (p *publisher, sending chan struct{}, release chan struct{}) {
+	sending = make(chan struct{}, 1)
+	release = make(chan struct{})
+	p = newPublisher(discord.NewClient("test"))
+	p.send = func(*discord.Activity) error {
+		sending <- struct{}{}
+		<-release
+		return nil
+	}
+	p.start()
+	return p, sending, release
+}
+
+// waitReturn runs f and reports whether it returned within d.
+func waitReturn(f func(), d time.Duration) <-chan bool {
+	returned := make(chan bool, 1)
+	finished := make(chan struct{})
+	go func() {
+		f()
+		close(finished)
+	}()
+	go func() {
+		select {
+		case <-finished:
+			returned <- true
+		case <-time.After(d):
+			returned <- false
+		}
+	}()
+	return returned
+}
+
+func TestPublisherStopWaitsForSend(t *testing.T) {
+	p, sending, release := blockingPublisher()
+	p.publish(&discord.Activity{Details: "test"})
+	<-sending
+
+	stopped := waitReturn(p.stop, 100*time.Millisecond)
+	if <-stopped {
+		t.Fatal("stop returned while an update was being sent")
+	}
+	close(release)
+	select {
+	case <-p.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publishing goroutine did not return")
+	}
+
+	p.publish(&discord.Activity{Details: "late"})
+	select {
+	case <-sending:
+		t.Error("an update was sent after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestPublisherReplaceClientWaitsForSend(t *testing.T) {
+	p, sending, release := blockingPublisher()
+	defer p.stop()
+	p.publish(&discord.Activity{Details: "test"})
+	<-sending
+
+	next := discord.NewClient("other")
+	replaced := waitReturn(func() { p.replaceClient(next) }, 100*time.Millisecond)
+	if <-replaced {
+		t.Fatal("replaceClient returned while an update was being sent")
+	}
+	if p.client() == next {
+		t.Fatal("client replaced while an update was being sent")
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.client() != next && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if p.client() != next {
+		t.Error("client was not replaced after the send finished")
+	}
+}
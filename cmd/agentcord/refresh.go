@@ -0,0 +1,252 @@
+package main
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/currency"
+	"tools.zach/dev/agentcord/internal/pricing"
+	"tools.zach/dev/agentcord/internal/tiers"
+)
+
+// ///////////////////////////////////////////////
+// Shared Data Store
+// ///////////////////////////////////////////////
+
+// dataStore holds the pricing, tier and exchange rate data shared between the
+// event loop and the background refresher. Each value is an immutable snapshot swapped
+// atomically, so the loop never observes a partially updated dataset.
+type dataStore struct {
+	// pricing is the current pricing snapshot. May be nil when no pricing is available.
+	pricing atomic.Pointer[pricing.PricingData]
+	// tiers is the current tier snapshot. Never nil once the daemon is running.
+	tiers atomic.Pointer[tiers.TierData]
+	// currency is the current display currency rate. Nil until a rate is
+	// available, in which case costs are shown in USD.
+	currency atomic.Pointer[currency.Rate]
+}
+
+// refreshConfig describes the data sources and refresh intervals for the
+// background refresher.
+type refreshConfig struct {
+	// pricing is the pricing data source.
+	pricing pricing.SourceConfig
+	// currency is the display currency and its exchange rate source.
+	currency currency.SourceConfig
+	// dataDir holds the on-disk caches.
+	dataDir string
+	// pricingEvery is the pricing refresh interval. Zero disables refresh.
+	pricingEvery time.Duration
+	// tiersEvery is the tier refresh interval. Zero disables refresh.
+	tiersEvery time.Duration
+	// currencyEvery is the exchange rate refresh interval. Zero disables refresh.
+	currencyEvery time.Duration
+}
+
+// seedDataStore returns a store populated from local data only: static
+// pricing or the on-disk pricing cache, the on-disk tier cache, and a static
+// or cached exchange rate. It never touches the network, so presence can be
+// published immediately. Pricing is left nil when no local data exists, which
+// renders as the no-cost state until the background fetch succeeds; tiers fall
+// back to the embedded copy, or an empty set if that cannot be parsed; costs
+// show in USD until a rate is available.
+func seedDataStore(rc refreshConfig) *dataStore {
+	store := seedTiersAndCurrency(rc)
+	if pd := seedPricing(rc); pd != nil {
+		store.pricing.Store(pd)
+	}
+	return store
+}
+
+// seedPricing returns static pricing or the on-disk pricing cache, or nil
+// when neither is available.
+func seedPricing(rc refreshConfig) *pricing.PricingData {
+	src, dataDir := rc.pricing, rc.dataDir
+	if src.Source == "static" {
+		pd, err := pricing.Fetch(src, dataDir)
+		if err != nil {
+			return nil
+		}
+		return pd
+	}
+	pd, err := pricing.ReadPricingCache(dataDir)
+	if err != nil {
+		return nil
+	}
+	pd.Aliases = src.Aliases
+	slog.Debug("seeded pricing from cache", "models", len(pd.Models))
+	return pd
+}
+
+// seedTiersAndCurrency returns a store without pricing, holding the on-disk
+// or embedded tiers and a static or cached exchange rate.
+func seedTiersAndCurrency(rc refreshConfig) *dataStore {
+	store := &dataStore{}
+	td, err := tiers.ReadCache(rc.dataDir)
+	if err != nil {
+		if td, err = tiers.Embedded(); err != nil {
+			td = &tiers.TierData{DefaultIcon: "default"}
+		}
+	}
+	store.tiers.Store(td)
+
+	if rate, err := currency.ReadLocal(rc.currency, rc.dataDir); err == nil {
+		store.currency.Store(rate)
+	}
+	return store
+}
+
+// ///////////////////////////////////////////////
+// Background Refresh
+// ///////////////////////////////////////////////
+
+// missingPricingRetry is how often the refresher retries the initial pricing
+// fetch while no pricing data is available at all.
+const missingPricingRetry = time.Minute
+
+// runRefresher performs the initial network fetch of pricing, tier and
+// exchange rate data, then re-fetches each on its configured interval,
+// swapping fresh snapshots into store. A zero interval disables refresh for
+// that dataset. While no pricing is available the initial fetch is retried
+// every [missingPricingRetry]. Returns when done is closed.
+func runRefresher(done <-chan struct{}, store *dataStore, rc refreshConfig) {
+	src, dataDir := rc.pricing, rc.dataDir
+	fetchPricing(store, src, dataDir, true)
+	fetchTiers(store, dataDir)
+	fetchCurrency(store, rc.currency, dataDir)
+
+	retry := time.NewTicker(missingPricingRetry)
+	defer retry.Stop()
+
+	pricingC, stopPricing := startTicker(rc.pricingEvery)
+	defer stopPricing()
+	tiersC, stopTiers := startTicker(rc.tiersEvery)
+	defer stopTiers()
+	currencyC, stopCurrency := startTicker(rc.currencyEvery)
+	defer stopCurrency()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-retry.C:
+			if store.pricing.Load() == nil {
+				fetchPricing(store, src, dataDir, false)
+			}
+		case <-pricingC:
+			refreshPricing(store, src, dataDir)
+		case <-tiersC:
+			refreshTiers(store, dataDir)
+		case <-currencyC:
+			refreshCurrency(store, rc.currency, dataDir)
+		}
+	}
+}
+
+// startTicker starts a ticker for interval d and returns its channel and stop
+// function. A zero interval returns a nil channel, which never fires.
+func startTicker(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// fetchPricing runs a full pricing fetch (primary source, then cache) and
+// stores the result. When nothing is available the daemon keeps running in a
+// degraded state without cost; warn controls whether that is logged as a
+// warning or, for repeated retries, only at debug level.
+func fetchPricing(store *dataStore, src pricing.SourceConfig, dataDir string, warn bool) {
+	pd, err := pricing.Fetch(src, dataDir)
+	if pd == nil {
+		if warn {
+			slog.Warn("no pricing data available, cost hidden until pricing loads", "error", err)
+		} else {
+			slog.Debug("pricing still unavailable", "error", err)
+		}
+		return
+	}
+	if err != nil {
+		slog.Warn("pricing fetch used fallback", "error", err)
+	}
+	store.pricing.Store(pd)
+	slog.Info("loaded pricing data", "models", len(pd.Models))
+}
+
+// fetchTiers runs a full tier fetch (remote, then cache) and stores the
+// result, keeping the seeded data when both fail.
+func fetchTiers(store *dataStore, dataDir string) {
+	td, err := tiers.Fetch(dataDir)
+	if err != nil {
+		slog.Warn("no tier data available, model icons use the default", "error", err)
+		return
+	}
+	store.tiers.Store(td)
+	slog.Info("loaded model tiers", "clients", len(td.Clients))
+}
+
+// refreshPricing refreshes pricing data once, keeping the current snapshot
+// when the source is unchanged or the refresh fails.
+func refreshPricing(store *dataStore, src pricing.SourceConfig, dataDir string) {
+	pd, err := pricing.Refresh(src, dataDir)
+	if err != nil {
+		slog.Warn("pricing refresh failed, keeping current data", "error", err)
+		return
+	}
+	if pd == nil {
+		slog.Debug("pricing data unchanged")
+		return
+	}
+	store.pricing.Store(pd)
+	slog.Info("refreshed pricing data", "models", len(pd.Models))
+}
+
+// refreshTiers refreshes tier data once, keeping the current snapshot when
+// the remote is unchanged or the refresh fails.
+func refreshTiers(store *dataStore, dataDir string) {
+	td, err := tiers.Refresh(dataDir)
+	if err != nil {
+		slog.Warn("tier refresh failed, keeping current data", "error", err)
+		return
+	}
+	if td == nil {
+		slog.Debug("tier data unchanged")
+		return
+	}
+	store.tiers.Store(td)
+	slog.Info("refreshed model tiers", "clients", len(td.Clients))
+}
+
+// fetchCurrency resolves the display currency rate (primary source, then
+// cache) and stores it. On failure costs keep showing in USD, or at the
+// seeded rate.
+func fetchCurrency(store *dataStore, src currency.SourceConfig, dataDir string) {
+	rate, err := currency.Fetch(src, dataDir)
+	if rate == nil {
+		slog.Warn("no exchange rate available, costs shown in USD", "currency", src.Code, "error", err)
+		return
+	}
+	if err != nil {
+		slog.Warn("exchange rate fetch used fallback", "error", err)
+	}
+	store.currency.Store(rate)
+	slog.Info("loaded exchange rate", "currency", rate.Code, "per_usd", rate.PerUSD)
+}
+
+// refreshCurrency refreshes the exchange rate once, keeping the current rate
+// when the source is unchanged or the refresh fails.
+func refreshCurrency(store *dataStore, src currency.SourceConfig, dataDir string) {
+	rate, err := currency.Refresh(src, dataDir)
+	if err != nil {
+		slog.Warn("exchange rate refresh failed, keeping current rate", "error", err)
+		return
+	}
+	if rate == nil {
+		slog.Debug("exchange rate unchanged")
+		return
+	}
+	store.currency.Store(rate)
+	slog.Info("refreshed exchange rate", "currency", rate.Code, "per_usd", rate.PerUSD)
+}
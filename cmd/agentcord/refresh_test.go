@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/currency"
+	"tools.zach/dev/agentcord/internal/pricing"
+)
+
+// ///////////////////////////////////////////////
+// refreshPricing Tests
+// ///////////////////////////////////////////////
+
+func TestRefreshPricing_SwapsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prices.json")
+	os.WriteFile(file, []byte(`{"models":{"claude-opus-4-6":{"input_per_token":0.000015,"output_per_token":0.000075}}}`), 0o644)
+	src := pricing.SourceConfig{Source: "file", Format: "agentcord", File: file}
+
+	initial, err := pricing.Fetch(src, dir)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	store := &dataStore{}
+	store.pricing.Store(initial)
+
+	refreshPricing(store, src, dir)
+	if store.pricing.Load() != initial {
+		t.Error("pricing swapped although the source was unchanged")
+	}
+
+	os.WriteFile(file, []byte(`{"models":{"claude-haiku-4-5":{"input_per_token":0.000001,"output_per_token":0.000005}}}`), 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(file, future, future)
+
+	refreshPricing(store, src, dir)
+	got := store.pricing.Load()
+	if got == initial {
+		t.Fatal("pricing not swapped after the source changed")
+	}
+	if _, ok := got.Models["claude-haiku-4-5"]; !ok {
+		t.Error("swapped pricing missing claude-haiku-4-5")
+	}
+}
+
+func TestRefreshPricing_KeepsDataOnError(t *testing.T) {
+	dir := t.TempDir()
+	initial := &pricing.PricingData{Models: map[string]pricing.ModelPricing{"claude-opus-4-6": {InputPerToken: 1}}}
+	store := &dataStore{}
+	store.pricing.Store(initial)
+
+	src := pricing.SourceConfig{Source: "file", Format: "agentcord", File: filepath.Join(dir, "missing.json")}
+	refreshPricing(store, src, dir)
+	if store.pricing.Load() != initial {
+		t.Error("pricing replaced after a failed refresh")
+	}
+}
+
+// ///////////////////////////////////////////////
+// runRefresher Tests
+// ///////////////////////////////////////////////
+
+func TestRunRefresher_StopsOnDone(t *testing.T) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		runRefresher(done, &dataStore{}, refreshConfig{
+			pricing:      pricing.SourceConfig{Source: "static"},
+			dataDir:      t.TempDir(),
+			pricingEvery: time.Millisecond,
+		})
+		close(finished)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(done)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("runRefresher did not return after done was closed")
+	}
+}
+
+// ///////////////////////////////////////////////
+// seedDataStore Tests
+// ///////////////////////////////////////////////
+
+func TestSeedDataStore_Static(t *testing.T) {
+	src := pricing.SourceConfig{
+		Source: "static",
+		Models: map[string]pricing.ModelPricing{"claude-opus-4-6": {InputPerToken: 1}},
+	}
+	store := seedDataStore(refreshConfig{pricing: src, dataDir: t.TempDir()})
+	if pd := store.pricing.Load(); pd == nil || len(pd.Models) != 1 {
+		t.Errorf("pricing = %+v, want static models", pd)
+	}
+	if td := store.tiers.Load(); td == nil || len(td.Clients) == 0 {
+		t.Errorf("tiers = %+v, want embedded tier data", td)
+	}
+}
+
+func TestSeedDataStore_FromCacheWithoutNetwork(t *testing.T) {
+	dir := t.TempDir()
+	cached := &pricing.PricingData{Models: map[string]pricing.ModelPricing{"claude-opus-4-6": {InputPerToken: 1}}}
+	if err := pricing.WritePricingCache(dir, cached); err != nil {
+		t.Fatalf("WritePricingCache: %v", err)
+	}
+
+	// The URL is unroutable; seeding must not touch it.
+	src := pricing.SourceConfig{Source: "url", Format: "openrouter", URL: "http://127.0.0.1:0/", Aliases: map[string]string{"x": "claude-opus-4-6"}}
+	store := seedDataStore(refreshConfig{pricing: src, dataDir: dir})
+	pd := store.pricing.Load()
+	if pd == nil {
+		t.Fatal("pricing = nil, want cached data")
+	}
+	if _, ok := pd.Resolve("x"); !ok {
+		t.Error("aliases not attached to seeded pricing")
+	}
+}
+
+func TestSeedDataStore_NoLocalPricing(t *testing.T) {
+	src := pricing.SourceConfig{Source: "url", Format: "openrouter", URL: "http://127.0.0.1:0/"}
+	store := seedDataStore(refreshConfig{pricing: src, dataDir: t.TempDir()})
+	if pd := store.pricing.Load(); pd != nil {
+		t.Errorf("pricing = %+v, want nil (degraded, no cost)", pd)
+	}
+}
+
+// ///////////////////////////////////////////////
+// fetchPricing Tests
+// ///////////////////////////////////////////////
+
+func TestFetchPricing_UnavailableIsNotFatal(t *testing.T) {
+	dir := t.TempDir()
+	store := &dataStore{}
+	src := pricing.SourceConfig{Source: "file", Format: "agentcord", File: filepath.Join(dir, "missing.json")}
+
+	fetchPricing(store, src, dir, true)
+	if store.pricing.Load() != nil {
+		t.Error("pricing stored although every source failed")
+	}
+
+	os.WriteFile(src.File, []byte(`{"models":{"claude-opus-4-6":{"input_per_token":0.000015,"output_per_token":0.000075}}}`), 0o644)
+	fetchPricing(store, src, dir, false)
+	if store.pricing.Load() == nil {
+		t.Error("pricing not stored once the source became available")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Currency Tests
+// ///////////////////////////////////////////////
+
+func TestSeedDataStore_Currency(t *testing.T) {
+	dir := t.TempDir()
+	static := refreshConfig{currency: currency.SourceConfig{Code: "EUR", Source: "static", Rate: 0.9}, dataDir: dir}
+	if rate := seedDataStore(static).currency.Load(); rate == nil || rate.PerUSD != 0.9 {
+		t.Errorf("static rate = %+v, want 0.9", rate)
+	}
+
+	// A URL source without a cache stays unset until the background fetch.
+	remote := refreshConfig{currency: currency.SourceConfig{Code: "EUR", Source: "url", URL: "http://127.0.0.1:0/"}, dataDir: dir}
+	if rate := seedDataStore(remote).currency.Load(); rate != nil {
+		t.Errorf("uncached url rate = %+v, want nil", rate)
+	}
+}
+
+func TestRefreshCurrency_SwapsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rates.json")
+	os.WriteFile(file, []byte(`{"rates":{"GBP":0.79}}`), 0o644)
+	src := currency.SourceConfig{Code: "GBP", Source: "file", File: file}
+
+	store := &dataStore{}
+	fetchCurrency(store, src, dir)
+	initial := store.currency.Load()
+	if initial == nil || initial.PerUSD != 0.79 {
+		t.Fatalf("fetched rate = %+v, want 0.79", initial)
+	}
+
+	refreshCurrency(store, src, dir)
+	if store.currency.Load() != initial {
+		t.Error("rate swapped although the source was unchanged")
+	}
+
+	os.WriteFile(file, []byte(`{"rates":{"GBP":0.8}}`), 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(file, future, future)
+
+	refreshCurrency(store, src, dir)
+	if got := store.currency.Load(); got == nil || got.PerUSD != 0.8 {
+		t.Errorf("refreshed rate = %+v, want 0.8", got)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/paths"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Presence Rotation
+// ///////////////////////////////////////////////
+
+// rotationInterval returns how often the presence carousel advances, or 0
+// when rotation is off: no interval is set, or there are neither cards nor
+// sessions to rotate through.
+func rotationInterval(d config.DisplayConfig) time.Duration {
+	if d.RotationSeconds <= 0 || (len(d.Rotation) == 0 && !d.RotateSessions) {
+		return 0
+	}
+	return time.Duration(d.RotationSeconds) * time.Second
+}
+
+// rotationIndexes splits the rotation step into the card to show and the
+// session to show it for. Every card is shown for a session before moving
+// to the next session. card is -1 when there are no cards; sess is 0
+// when there are fewer than two sessions.
+func rotationIndexes(step, cards, sessions int) (card, sess int) {
+	card = -1
+	perSession := step
+	if cards > 0 {
+		card = step % cards
+		perSession = step / cards
+	}
+	if sessions > 1 {
+		sess = perSession % sessions
+	}
+	return card, sess
+}
+
+// applyRotationCard returns actCfg with the templates of card. Empty card
+// templates keep the ones in actCfg; the no-branch and no-cost templates
+// default to the card's details and state.
+func applyRotationCard(actCfg session.ActivityConfig, card config.RotationCard) session.ActivityConfig {
+	if card.Details != "" {
+		actCfg.DetailsFormat = card.Details
+		actCfg.DetailsNoBranchFormat = card.Details
+	}
+	if card.DetailsNoBranch != "" {
+		actCfg.DetailsNoBranchFormat = card.DetailsNoBranch
+	}
+	if card.State != "" {
+		actCfg.StateFormat = card.State
+		actCfg.StateNoCostFormat = card.State
+	}
+	if card.StateNoCost != "" {
+		actCfg.StateNoCostFormat = card.StateNoCost
+	}
+	return actCfg
+}
+
+// findLiveStates returns the states of every session that has not stopped,
+// ordered by session start so the rotation order is stable. When idle is
+// positive, sessions without activity for longer than idle are left out.
+// A session with both a per-session and a legacy per-client file is listed
+// once, with its most recent state.
+func findLiveStates(dataDir string, idle time.Duration, now time.Time) []*session.State {
+	matches, err := filepath.Glob(filepath.Join(dataDir, paths.StateFilePattern))
+	if err != nil {
+		return nil
+	}
+
+	bySession := make(map[string]*session.State)
+	for _, path := range matches {
+		s, readErr := session.ReadState(path)
+		if s == nil {
+			slog.Debug("skipping unreadable state file", "path", path, "error", readErr)
+			continue
+		}
+		if s.Stopped || s.SessionID == "" {
+			continue
+		}
+		if idle > 0 && now.Sub(time.Unix(s.LastActivity, 0)) > idle {
+			continue
+		}
+		if prev, ok := bySession[s.SessionID]; !ok || s.LastActivity > prev.LastActivity {
+			bySession[s.SessionID] = s
+		}
+	}
+
+	live := make([]*session.State, 0, len(bySession))
+	for _, s := range bySession {
+		live = append(live, s)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].SessionStart != live[j].SessionStart {
+			return live[i].SessionStart < live[j].SessionStart
+		}
+		return live[i].SessionID < live[j].SessionID
+	})
+	return live
+}
+
+// rotateSession returns the live session the rotation step shows, or state
+// when fewer than two sessions are live. The statusline payload is merged
+// into the chosen session when it describes it.
+func rotateSession(cfg *config.Config, dataPaths DataPaths, state *session.State, step int, now time.Time) *session.State {
+	idle := time.Duration(cfg.Behavior.PresenceIdleMinutes) * time.Minute
+	live := findLiveStates(dataPaths.Root, idle, now)
+	if len(live) < 2 {
+		return state
+	}
+	_, i := rotationIndexes(step, len(cfg.Display.Rotation), len(live))
+	picked := live[i]
+	if picked.SessionID == state.SessionID {
+		return state
+	}
+	if cfg.Behavior.UseStatusline {
+		if sl, err := session.ReadStatusline(dataPaths.Statusline()); err == nil {
+			picked.MergeStatusline(sl)
+		}
+	}
+	return picked
+}
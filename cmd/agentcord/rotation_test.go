@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/paths"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Rotation Tests
+// ///////////////////////////////////////////////
+
+func TestRotationInterval(t *testing.T) {
+	cards := []config.RotationCard{{State: "{tokens}"}}
+	tests := []struct {
+		name string
+		d    config.DisplayConfig
+		want time.Duration
+	}{
+		{"off by default", config.DisplayConfig{}, 0},
+		{"interval without cards or sessions", config.DisplayConfig{RotationSeconds: 20}, 0},
+		{"cards without interval", config.DisplayConfig{Rotation: cards}, 0},
+		{"cards", config.DisplayConfig{RotationSeconds: 20, Rotation: cards}, 20 * time.Second},
+		{"sessions", config.DisplayConfig{RotationSeconds: 30, RotateSessions: true}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rotationInterval(tt.d); got != tt.want {
+				t.Errorf("rotationInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotationIndexes(t *testing.T) {
+	tests := []struct {
+		step, cards, sessions int
+		wantCard, wantSess    int
+	}{
+		{0, 0, 0, -1, 0},
+		{5, 0, 1, -1, 0},
+		{5, 0, 3, -1, 2},
+		{0, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 0, 1},
+		{6, 3, 2, 0, 0},
+		{4, 3, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("step%d_cards%d_sessions%d", tt.step, tt.cards, tt.sessions), func(t *testing.T) {
+			card, sess := rotationIndexes(tt.step, tt.cards, tt.sessions)
+			if card != tt.wantCard || sess != tt.wantSess {
+				t.Errorf("rotationIndexes() = (%d, %d), want (%d, %d)", card, sess, tt.wantCard, tt.wantSess)
+			}
+		})
+	}
+}
+
+func TestApplyRotationCard(t *testing.T) {
+	base := session.ActivityConfig{
+		DetailsFormat:         "details",
+		StateFormat:           "state",
+		DetailsNoBranchFormat: "details no branch",
+		StateNoCostFormat:     "state no cost",
+	}
+
+	got := applyRotationCard(base, config.RotationCard{State: "{tokens} tokens"})
+	if got.DetailsFormat != "details" || got.DetailsNoBranchFormat != "details no branch" {
+		t.Errorf("details = %q / %q, want the display templates", got.DetailsFormat, got.DetailsNoBranchFormat)
+	}
+	if got.StateFormat != "{tokens} tokens" || got.StateNoCostFormat != "{tokens} tokens" {
+		t.Errorf("state = %q / %q, want the card's state for both", got.StateFormat, got.StateNoCostFormat)
+	}
+
+	got = applyRotationCard(base, config.RotationCard{Details: "{project}", DetailsNoBranch: "{client}"})
+	if got.DetailsFormat != "{project}" || got.DetailsNoBranchFormat != "{client}" {
+		t.Errorf("details = %q / %q, want the card's templates", got.DetailsFormat, got.DetailsNoBranchFormat)
+	}
+	if base.DetailsFormat != "details" {
+		t.Error("applyRotationCard modified the config it was given")
+	}
+}
+
+func TestFindLiveStates(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1_700_000_000, 0)
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	write(paths.StateFileForSession("claude-code", "b"),
+		fmt.Sprintf(`{"$version":2,"sessionId":"b","client":"claude-code","sessionStart":100,"lastActivity":%d}`, now.Unix()))
+	write(paths.StateFileForSession("cursor", "a"),
+		fmt.Sprintf(`{"$version":2,"sessionId":"a","client":"cursor","sessionStart":200,"lastActivity":%d}`, now.Unix()-60))
+	write(paths.StateFileForSession("claude-code", "stopped"),
+		fmt.Sprintf(`{"$version":2,"sessionId":"stopped","client":"claude-code","stopped":true,"lastActivity":%d}`, now.Unix()))
+	write(paths.StateFileForSession("claude-code", "stale"),
+		fmt.Sprintf(`{"$version":2,"sessionId":"stale","client":"claude-code","lastActivity":%d}`, now.Unix()-3600))
+	// An older legacy file for session b is superseded by its per-session file.
+	write(paths.StateFileForClient("claude-code"),
+		fmt.Sprintf(`{"$version":1,"sessionId":"b","project":"old","lastActivity":%d}`, now.Unix()-120))
+
+	live := findLiveStates(dir, 15*time.Minute, now)
+	if len(live) != 2 || live[0].SessionID != "b" || live[1].SessionID != "a" {
+		t.Fatalf("live = %v, want sessions b then a", stateIDs(live))
+	}
+	if live[0].Project == "old" {
+		t.Error("session b was read from the older legacy file")
+	}
+
+	if got := findLiveStates(dir, 0, now); len(got) != 3 {
+		t.Errorf("without idle limit = %v, want 3 sessions", stateIDs(got))
+	}
+}
+
+func TestRotateSession(t *testing.T) {
+	dir := t.TempDir()
+	dataPaths := DataPaths{Root: dir}
+	now := time.Now()
+	for i, id := range []string{"a", "b"} {
+		os.WriteFile(filepath.Join(dir, paths.StateFileForSession("claude-code", id)), []byte(fmt.Sprintf(
+			`{"$version":2,"sessionId":%q,"client":"claude-code","sessionStart":%d,"lastActivity":%d}`,
+			id, i+1, now.Unix())), 0o644)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Display.RotateSessions = true
+	cfg.Display.Rotation = []config.RotationCard{{State: "one"}, {State: "two"}}
+	latest := &session.State{SessionID: "b"}
+
+	for step, want := range []string{"a", "a", "b", "b", "a"} {
+		if got := rotateSession(cfg, dataPaths, latest, step, now); got.SessionID != want {
+			t.Errorf("step %d shows %q, want %q", step, got.SessionID, want)
+		}
+	}
+	if got := rotateSession(cfg, dataPaths, latest, 2, now); got != latest {
+		t.Error("the latest session should be returned as is when it is picked")
+	}
+
+	os.Remove(filepath.Join(dir, paths.StateFileForSession("claude-code", "a")))
+	if got := rotateSession(cfg, dataPaths, latest, 0, now); got != latest {
+		t.Errorf("single live session = %+v, want the latest state", got)
+	}
+}
+
+// stateIDs returns the session IDs of states, for test failure messages.
+func stateIDs(states []*session.State) []string {
+	ids := make([]string, len(states))
+	for i, s := range states {
+		ids[i] = s.SessionID
+	}
+	return ids
+}
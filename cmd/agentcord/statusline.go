@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/config"
+	"tools.zach/dev/agentcord/internal/pricing"
+	"tools.zach/dev/agentcord/internal/session"
+)
+
+// ///////////////////////////////////////////////
+// Statusline Command
+// ///////////////////////////////////////////////
+
+// runStatusline implements `agentcord statusline`, the command Claude Code's
+// statusLine setting runs. It reads the statusline payload from stdin, saves
+// it for the daemon's use_statusline source, and prints one line rendered
+// from the [statusline] segments with the presence card's template variables.
+// It only reads local data, so it never waits on the network. When the setup
+// script replaced an existing statusLine command, that command is run with
+// the same payload and its output appended to the line. Returns the exit code.
+func runStatusline(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("statusline", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dataDir := fs.String("data-dir", defaultDataDir(), "Data directory for config, state, and logs")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	body, err := io.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "statusline: reading stdin: %v\n", err)
+		return 1
+	}
+	now := time.Now()
+	sl, err := session.ParseStatusline(body, now)
+	if err != nil {
+		fmt.Fprintf(stderr, "statusline: %v\n", err)
+		return 1
+	}
+
+	dataPaths := DataPaths{Root: *dataDir}
+	if err := atomicfile.Write(dataPaths.Statusline(), body, 0o644); err != nil {
+		// The line is still useful without the daemon's copy.
+		fmt.Fprintf(stderr, "statusline: saving payload: %v\n", err)
+	}
+
+	cfg, err := config.Load(dataPaths.Root)
+	if err != nil {
+		fmt.Fprintf(stderr, "statusline: loading config: %v\n", err)
+		cfg = config.DefaultConfig()
+	}
+	line := renderStatusline(cfg, dataPaths, sl, now)
+	if chained := runChainedStatusline(dataPaths.StatuslineBackup(), body); chained != "" {
+		line += cfg.Statusline.Separator + chained
+	}
+	fmt.Fprintln(stdout, line)
+	return 0
+}
+
+// renderStatusline renders the configured statusline segments for sl. Token,
+// context and per-model variables come from the session's transcript, priced
+// with the cached pricing data; the session cost is Claude Code's own. The
+// transcript parse state and the prices of the models seen are saved between
+// runs, so each run reads only the lines appended since the last one and
+// loads the full pricing cache only when a new model appears.
+func renderStatusline(cfg *config.Config, dataPaths DataPaths, sl *session.Statusline, now time.Time) string {
+	rc := refreshConfig{
+		pricing:  buildPricingSource(cfg),
+		currency: buildCurrencySource(cfg),
+		dataDir:  dataPaths.Root,
+	}
+	store := seedTiersAndCurrency(rc)
+	state := sl.State()
+	actCfg := buildActivityConfig(cfg, store.tiers.Load(), state.Client)
+	// The terminal is private: show_cost only hides cost from Discord.
+	actCfg.ShowCost = true
+	if rate := store.currency.Load(); rate != nil {
+		actCfg.CurrencyRate = rate.PerUSD
+	}
+
+	caches, err := session.LoadJSONLCaches(dataPaths.StatuslineCache())
+	if err != nil {
+		slog.Debug("discarding statusline transcript caches", "error", err)
+		caches = session.NewJSONLCaches()
+	}
+	prices := loadStatuslinePrices(rc, dataPaths)
+	cost, totalTokens, model, jsonlData := resolveTokenData(
+		prices.pricingData(), buildTranscriptResolver(cfg, dataPaths), caches, state, now)
+	if jsonlData != nil && !prices.covers(jsonlData.UniqueModels) {
+		if pd := seedPricing(rc); pd != nil {
+			cost = priceModelUsage(pd, jsonlData)
+			jsonlData.ContextWindow = pd.ContextWindow(jsonlData.ContextModel)
+			prices.update(pd, jsonlData.UniqueModels)
+			if err := prices.save(dataPaths.StatuslinePrices()); err != nil {
+				slog.Debug("saving statusline prices", "error", err)
+			}
+		}
+	}
+	caches.EvictIdle(transcriptCacheIdle, now)
+	if err := caches.Save(dataPaths.StatuslineCache()); err != nil {
+		slog.Debug("saving statusline transcript caches", "error", err)
+	}
+	if sl.Cost.TotalCostUSD > 0 {
+		cost = sl.Cost.TotalCostUSD
+	}
+	if sl.Model.ID != "" {
+		model = sl.Model.ID
+	}
+
+	var parts []string
+	for _, seg := range cfg.Statusline.Segments {
+		out := session.RenderTemplate(seg, state, actCfg, cost, totalTokens, model, jsonlData)
+		if statuslineSegmentEmpty(seg, out) {
+			continue
+		}
+		parts = append(parts, out)
+	}
+	return strings.Join(parts, cfg.Statusline.Separator)
+}
+
+// templateVarRegex matches {name} and {name:format} template variables.
+var templateVarRegex = regexp.MustCompile(`\{\w+(?::[^}]+)?\}`)
+
+// statuslineSegmentEmpty reports whether the rendered segment out carries no
+// data: it is blank, or every variable in the template seg rendered empty so
+// only the literal text is left (e.g. " ctx" for "{context_pct} ctx").
+func statuslineSegmentEmpty(seg, out string) bool {
+	if strings.TrimSpace(out) == "" {
+		return true
+	}
+	return templateVarRegex.MatchString(seg) && out == templateVarRegex.ReplaceAllString(seg, "")
+}
+
+// ///////////////////////////////////////////////
+// Statusline Prices
+// ///////////////////////////////////////////////
+
+// statuslinePrices is the statusline command's snapshot of the prices of the
+// models it has seen, so a run reads a few entries instead of parsing the
+// whole pricing cache. It is tied to the pricing cache it was taken from and
+// the aliases it was resolved with.
+type statuslinePrices struct {
+	// Size and ModTime identify the pricing cache the prices were taken from.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Aliases are the [pricing] aliases the model IDs were resolved with.
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Models maps each model ID seen to its pricing.
+	Models map[string]pricing.ModelPricing `json:"models"`
+	// Unpriced lists the model IDs seen that have no pricing.
+	Unpriced []string `json:"unpriced,omitempty"`
+
+	// static is the inline pricing of a "static" source, used as is.
+	static *pricing.PricingData
+}
+
+// loadStatuslinePrices returns the saved price snapshot when it still matches
+// the pricing cache and aliases, and an empty one for them otherwise. A static
+// pricing source is cheap to build, so it is used directly. Returns nil when
+// no pricing is available.
+func loadStatuslinePrices(rc refreshConfig, dataPaths DataPaths) *statuslinePrices {
+	if rc.pricing.Source == "static" {
+		pd := seedPricing(rc)
+		if pd == nil {
+			return nil
+		}
+		return &statuslinePrices{static: pd}
+	}
+	info, err := os.Stat(dataPaths.PricingCache())
+	if err != nil {
+		return nil
+	}
+	fresh := &statuslinePrices{Size: info.Size(), ModTime: info.ModTime(), Aliases: rc.pricing.Aliases}
+
+	data, err := os.ReadFile(dataPaths.StatuslinePrices())
+	if err != nil {
+		return fresh
+	}
+	var saved statuslinePrices
+	if err := json.Unmarshal(data, &saved); err != nil {
+		slog.Debug("discarding statusline prices", "error", err)
+		return fresh
+	}
+	if saved.Size != fresh.Size || !saved.ModTime.Equal(fresh.ModTime) || !maps.Equal(saved.Aliases, fresh.Aliases) {
+		return fresh
+	}
+	return &saved
+}
+
+// pricingData returns pricing data holding the snapshot's prices, keyed by
+// the model IDs as seen so they match exactly. Nil when p is nil.
+func (p *statuslinePrices) pricingData() *pricing.PricingData {
+	if p == nil {
+		return nil
+	}
+	if p.static != nil {
+		return p.static
+	}
+	return &pricing.PricingData{Models: p.Models}
+}
+
+// covers reports whether the snapshot knows whether each model in ids is
+// priced. A nil or static snapshot needs nothing more.
+func (p *statuslinePrices) covers(ids []string) bool {
+	if p == nil || p.static != nil {
+		return true
+	}
+	for _, id := range ids {
+		if _, ok := p.Models[id]; !ok && !slices.Contains(p.Unpriced, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// update adds the prices of ids from the full pricing data pd.
+func (p *statuslinePrices) update(pd *pricing.PricingData, ids []string) {
+	if p.Models == nil {
+		p.Models = make(map[string]pricing.ModelPricing, len(ids))
+	}
+	found := pd.Subset(ids)
+	for _, id := range ids {
+		if mp, ok := found[id]; ok {
+			p.Models[id] = mp
+		} else if !slices.Contains(p.Unpriced, id) {
+			p.Unpriced = append(p.Unpriced, id)
+		}
+	}
+}
+
+// save writes the snapshot to path.
+func (p *statuslinePrices) save(path string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("encoding statusline prices: %w", err)
+	}
+	return atomicfile.Write(path, data, 0o644)
+}
+
+// ///////////////////////////////////////////////
+// Chained Statusline
+// ///////////////////////////////////////////////
+
+// chainedTimeout bounds the replaced statusline command so a slow one cannot
+// hold up Claude Code's statusline.
+const chainedTimeout = 2 * time.Second
+
+// runChainedStatusline runs the command of the statusLine setting backed up at
+// backupPath through the platform shell with payload on stdin, and returns its
+// output with trailing newlines trimmed. Returns "" when there is no backup or
+// the command fails.
+func runChainedStatusline(backupPath string, payload []byte) string {
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return ""
+	}
+	var backup struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal(data, &backup); err != nil || backup.Command == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chainedTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", backup.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", backup.Command)
+	}
+	cmd.Stdin = bytes.NewReader(payload)
+	out, err := cmd.Output()
+	if err != nil {
+		slog.Debug("chained statusline command failed", "command", backup.Command, "error", err)
+		return ""
+	}
+	return strings.TrimRight(string(out), "\r\n")
+}
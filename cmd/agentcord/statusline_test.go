@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"tools.zach/dev/agentcord/internal/pricing"
+)
+
+// ///////////////////////////////////////////////
+// runStatusline Tests
+// ///////////////////////////////////////////////
+
+func TestRunStatusline(t *testing.T) {
+	dir := t.TempDir()
+	transcript := filepath.Join(dir, "s1.jsonl")
+	os.WriteFile(transcript, []byte(`{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":1000,"cache_read_input_tokens":113000,"output_tokens":10}}}
+`), 0o644)
+	pricing.WritePricingCache(dir, &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, ContextWindow: 200_000},
+	}})
+
+	payload, _ := json.Marshal(map[string]any{
+		"session_id":      "s1",
+		"transcript_path": transcript,
+		"cwd":             dir,
+		"model":           map[string]string{"id": "claude-opus-4-6", "display_name": "Opus 4.6"},
+		"cost":            map[string]any{"total_cost_usd": 1.42},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := runStatusline([]string{"--data-dir", dir}, bytes.NewReader(payload), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("runStatusline exit = %d, stderr = %s", code, stderr.String())
+	}
+	if got, want := stdout.String(), "Opus 4.6 · $1.42 · 57% ctx\n"; got != want {
+		t.Errorf("statusline = %q, want %q", got, want)
+	}
+
+	saved, err := os.ReadFile(DataPaths{Root: dir}.Statusline())
+	if err != nil || !bytes.Equal(saved, payload) {
+		t.Errorf("saved payload = %q, %v; want the stdin payload", saved, err)
+	}
+}
+
+func TestRunStatusline_ResumesTranscript(t *testing.T) {
+	dir := t.TempDir()
+	transcript := filepath.Join(dir, "s1.jsonl")
+	line := `{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":1000,"cache_read_input_tokens":113000,"output_tokens":10}}}` + "\n"
+	os.WriteFile(transcript, []byte(line), 0o644)
+	pricing.WritePricingCache(dir, &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, ContextWindow: 200_000},
+	}})
+	payload, _ := json.Marshal(map[string]any{
+		"session_id":      "s1",
+		"transcript_path": transcript,
+		"model":           map[string]string{"id": "claude-opus-4-6", "display_name": "Opus 4.6"},
+		"cost":            map[string]any{"total_cost_usd": 1.42},
+	})
+
+	render := func() string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := runStatusline([]string{"--data-dir", dir}, bytes.NewReader(payload), &stdout, &stderr); code != 0 {
+			t.Fatalf("runStatusline exit = %d, stderr = %s", code, stderr.String())
+		}
+		return stdout.String()
+	}
+	render()
+
+	// Blank the parsed line: the second run must resume past it rather
+	// than parse the transcript from the start.
+	os.WriteFile(transcript, []byte(strings.Repeat(" ", len(line)-1)+"\n"), 0o644)
+	if got, want := render(), "Opus 4.6 · $1.42 · 57% ctx\n"; got != want {
+		t.Errorf("second statusline = %q, want %q", got, want)
+	}
+}
+
+func TestRunStatusline_PriceSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	dataPaths := DataPaths{Root: dir}
+	transcript := filepath.Join(dir, "s1.jsonl")
+	os.WriteFile(transcript, []byte(`{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":1000,"cache_read_input_tokens":113000,"output_tokens":10}}}
+`), 0o644)
+	pricing.WritePricingCache(dir, &pricing.PricingData{Models: map[string]pricing.ModelPricing{
+		"claude-opus-4-6":  {InputPerToken: 0.000015, ContextWindow: 200_000},
+		"claude-haiku-4-5": {InputPerToken: 0.000001, ContextWindow: 100_000},
+	}})
+	payload, _ := json.Marshal(map[string]any{
+		"session_id":      "s1",
+		"transcript_path": transcript,
+		"model":           map[string]string{"id": "claude-opus-4-6", "display_name": "Opus 4.6"},
+		"cost":            map[string]any{"total_cost_usd": 1.42},
+	})
+	render := func() string {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if code := runStatusline([]string{"--data-dir", dir}, bytes.NewReader(payload), &stdout, &stderr); code != 0 {
+			t.Fatalf("runStatusline exit = %d, stderr = %s", code, stderr.String())
+		}
+		return stdout.String()
+	}
+	render()
+
+	var snap statuslinePrices
+	if data, err := os.ReadFile(dataPaths.StatuslinePrices()); err != nil || json.Unmarshal(data, &snap) != nil {
+		t.Fatalf("reading price snapshot: %v", err)
+	}
+	if len(snap.Models) != 1 || snap.Models["claude-opus-4-6"].ContextWindow != 200_000 {
+		t.Errorf("snapshot models = %+v, want only opus", snap.Models)
+	}
+
+	// Corrupt the pricing cache without changing its size or mtime: a run
+	// that only needs known models must not read it.
+	info, _ := os.Stat(dataPaths.PricingCache())
+	os.WriteFile(dataPaths.PricingCache(), bytes.Repeat([]byte("x"), int(info.Size())), 0o644)
+	os.Chtimes(dataPaths.PricingCache(), info.ModTime(), info.ModTime())
+	if got, want := render(), "Opus 4.6 · $1.42 · 57% ctx\n"; got != want {
+		t.Errorf("statusline from snapshot = %q, want %q", got, want)
+	}
+}
+
+func TestRunStatusline_DropsEmptySegments(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	payload := `{"session_id":"s1","model":{"id":"claude-opus-4-6","display_name":"Opus 4.6"}}`
+	if code := runStatusline([]string{"--data-dir", dir}, strings.NewReader(payload), &stdout, &stderr); code != 0 {
+		t.Fatalf("runStatusline exit = %d, stderr = %s", code, stderr.String())
+	}
+	// Without pricing data the context window is unknown.
+	if got, want := stdout.String(), "Opus 4.6 · $0.00\n"; got != want {
+		t.Errorf("statusline = %q, want %q", got, want)
+	}
+}
+
+func TestRunStatusline_ChainsOriginal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("chained command uses sh")
+	}
+	dir := t.TempDir()
+	// The original command sees the same payload on stdin.
+	os.WriteFile(DataPaths{Root: dir}.StatuslineBackup(), []byte(`{"type":"command","command":"grep -o 's1-[a-z]*'"}`), 0o644)
+	var stdout, stderr bytes.Buffer
+	payload := `{"session_id":"s1-abc","model":{"id":"claude-opus-4-6","display_name":"Opus 4.6"}}`
+	if code := runStatusline([]string{"--data-dir", dir}, strings.NewReader(payload), &stdout, &stderr); code != 0 {
+		t.Fatalf("runStatusline exit = %d, stderr = %s", code, stderr.String())
+	}
+	if got, want := stdout.String(), "Opus 4.6 · $0.00 · s1-abc\n"; got != want {
+		t.Errorf("statusline = %q, want %q", got, want)
+	}
+
+	// A failing original command leaves the line alone.
+	os.WriteFile(DataPaths{Root: dir}.StatuslineBackup(), []byte(`{"type":"command","command":"exit 1"}`), 0o644)
+	stdout.Reset()
+	runStatusline([]string{"--data-dir", dir}, strings.NewReader(payload), &stdout, &stderr)
+	if got, want := stdout.String(), "Opus 4.6 · $0.00\n"; got != want {
+		t.Errorf("statusline with failing original = %q, want %q", got, want)
+	}
+}
+
+func TestRunStatusline_InvalidPayload(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runStatusline([]string{"--data-dir", t.TempDir()}, strings.NewReader("not json"), &stdout, &stderr); code != 1 {
+		t.Errorf("exit = %d, want 1", code)
+	}
+}
+
+func TestStatuslineSegmentEmpty(t *testing.T) {
+	tests := []struct {
+		seg, out string
+		want     bool
+	}{
+		{"{context_pct} ctx", " ctx", true},
+		{"{context_pct} ctx", "57% ctx", false},
+		{"{branch}", "", true},
+		{"+{lines_added} -{lines_removed}", "+0 -0", false},
+		{"agentcord", "agentcord", false},
+	}
+	for _, tt := range tests {
+		if got := statuslineSegmentEmpty(tt.seg, tt.out); got != tt.want {
+			t.Errorf("statuslineSegmentEmpty(%q, %q) = %v, want %v", tt.seg, tt.out, got, tt.want)
+		}
+	}
+}
@@ -130,6 +130,8 @@ func main() {
 // because the field has an omitempty tag and holds its zero value). This ensures
 // every documented option appears in the generated file, even when its value is
 // omitted from the encoded output. Keys are sorted for deterministic ordering.
+// Sub-tables are skipped (their docs are written with their own header), as
+// are entries with nothing to show, which another entry documents.
 func injectOmitted(out *[]string, sectionStack []string, emitted map[string]bool) {
 	if len(sectionStack) == 0 {
 		return
@@ -146,7 +148,10 @@ func injectOmitted(out *[]string, sectionStack []string, emitted map[string]bool
 		if strings.Contains(rest, ".") {
 			continue
 		}
-		if emitted[path] {
+		if emitted[path] || isTable(path) {
+			continue
+		}
+		if doc := config.ConfigDocs[path]; doc.Comment == "" && len(doc.Alternatives) == 0 {
 			continue
 		}
 		omitted = append(omitted, path)
@@ -170,6 +175,18 @@ func injectOmitted(out *[]string, sectionStack []string, emitted map[string]bool
 	}
 }
 
+// isTable reports whether path names a sub-table, i.e. other documented keys
+// are nested under it (e.g. "display.currency").
+func isTable(path string) bool {
+	prefix := path + "."
+	for p := range config.ConfigDocs {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // parseSectionPath splits a dotted TOML section header (e.g. "display.assets")
 // into its component path segments (["display", "assets"]). The returned slice
 // is used as a stack to track the current nesting depth during output generation.
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -82,3 +83,29 @@ func TestInjectOmittedNoSection(t *testing.T) {
 		t.Errorf("injectOmitted with nil sectionStack produced %d lines, want 0", len(out))
 	}
 }
+
+func TestInjectOmittedSkipsTablesAndEmptyDocs(t *testing.T) {
+	var out []string
+	emitted := map[string]bool{}
+	injectOmitted(&out, []string{"display"}, emitted)
+
+	text := strings.Join(out, "\n")
+	if strings.Contains(text, "Display currency") {
+		t.Errorf("section doc of [display.currency] injected into [display]:\n%s", text)
+	}
+	if emitted["display.state_url"] {
+		t.Error("display.state_url injected, want it left to the details_url entry")
+	}
+	if !emitted["display.rotation"] {
+		t.Error("display.rotation not injected")
+	}
+}
+
+func TestIsTable(t *testing.T) {
+	if !isTable("display.currency") {
+		t.Error(`isTable("display.currency") = false, want true`)
+	}
+	if isTable("display.rotation") {
+		t.Error(`isTable("display.rotation") = true, want false`)
+	}
+}
@@ -1,8 +1,10 @@
 // Package agentcord provides embedded assets for the Agentcord daemon.
 //
-// The root package exists solely to embed [config.default.toml] via
-// [DefaultConfigTOML]. The config package reads this at startup to
-// seed first-run defaults.
+// The root package exists solely to embed build-time data files:
+// [config.default.toml] via [DefaultConfigTOML], which the config package
+// reads at startup to seed first-run defaults, and data/tiers.json via
+// [DefaultTiersJSON], which the tiers package falls back to when neither the
+// remote nor the cache is available.
 package agentcord
 
 import _ "embed"
@@ -13,3 +15,10 @@ import _ "embed"
 //
 //go:embed config.default.toml
 var DefaultConfigTOML []byte
+
+// DefaultTiersJSON holds the raw bytes of data/tiers.json as of this build.
+// The [internal/tiers] package uses it as the last fallback, and as the only
+// source in offline mode when no cache exists.
+//
+//go:embed data/tiers.json
+var DefaultTiersJSON []byte
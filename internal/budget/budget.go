@@ -0,0 +1,294 @@
+// Package budget evaluates API-value spending against daily, weekly and
+// monthly budgets, and raises an alert when spending crosses the warning
+// threshold or the budget itself.
+//
+// Periods follow the local calendar: days start at midnight, weeks on Monday
+// and months on the first. Spending comes from a caller-supplied function,
+// normally backed by the usage ledger, so totals survive daemon restarts.
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/atomicfile"
+)
+
+// Budget periods.
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// commandTimeout bounds how long an alert command may run.
+const commandTimeout = 30 * time.Second
+
+// ///////////////////////////////////////////////
+// Types
+// ///////////////////////////////////////////////
+
+// Limits holds the configured budgets in USD. A zero budget is disabled.
+type Limits struct {
+	Daily   float64
+	Weekly  float64
+	Monthly float64
+}
+
+// Level classifies spending against a budget.
+type Level int
+
+const (
+	// LevelOK means spending is below the warning threshold.
+	LevelOK Level = iota
+	// LevelWarn means spending crossed the warning threshold.
+	LevelWarn
+	// LevelExceeded means spending reached the budget.
+	LevelExceeded
+)
+
+// String returns the level name used in alert state and the alert command
+// environment.
+func (l Level) String() string {
+	switch l {
+	case LevelWarn:
+		return "warn"
+	case LevelExceeded:
+		return "exceeded"
+	default:
+		return "ok"
+	}
+}
+
+// Status is the spending in the current period of one budget.
+type Status struct {
+	// Period is the budget period: [Daily], [Weekly] or [Monthly].
+	Period string
+	// Limit is the budget in USD.
+	Limit float64
+	// Spent is the USD cost recorded since Start.
+	Spent float64
+	// Start is the beginning of the current period.
+	Start time.Time
+}
+
+// UsedPct returns spending as a percentage of the budget.
+func (s Status) UsedPct() float64 {
+	if s.Limit <= 0 {
+		return 0
+	}
+	return s.Spent / s.Limit * 100
+}
+
+// Remaining returns the unspent part of the budget in USD, never negative.
+func (s Status) Remaining() float64 {
+	return max(0, s.Limit-s.Spent)
+}
+
+// Level classifies the status against warnPercent. A zero warnPercent only
+// reports [LevelExceeded].
+func (s Status) Level(warnPercent float64) Level {
+	pct := s.UsedPct()
+	switch {
+	case pct >= 100:
+		return LevelExceeded
+	case warnPercent > 0 && pct >= warnPercent:
+		return LevelWarn
+	default:
+		return LevelOK
+	}
+}
+
+// ///////////////////////////////////////////////
+// Evaluation
+// ///////////////////////////////////////////////
+
+// PeriodStart returns the beginning of the period that contains now, in
+// now's location.
+func PeriodStart(period string, now time.Time) time.Time {
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch period {
+	case Weekly:
+		// time.Weekday counts from Sunday; weeks start on Monday.
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	default:
+		return day
+	}
+}
+
+// Evaluate returns the status of each configured budget in daily, weekly,
+// monthly order. spent returns the USD cost recorded in [from, to).
+func Evaluate(l Limits, spent func(from, to time.Time) float64, now time.Time) []Status {
+	var out []Status
+	for _, b := range []struct {
+		period string
+		limit  float64
+	}{{Daily, l.Daily}, {Weekly, l.Weekly}, {Monthly, l.Monthly}} {
+		if b.limit <= 0 {
+			continue
+		}
+		start := PeriodStart(b.period, now)
+		out = append(out, Status{
+			Period: b.period,
+			Limit:  b.limit,
+			Spent:  spent(start, now.Add(time.Second)),
+			Start:  start,
+		})
+	}
+	return out
+}
+
+// Select returns the status for period, or the status with the highest
+// percentage used when period is empty or not configured. Reports false when
+// statuses is empty.
+func Select(statuses []Status, period string) (Status, bool) {
+	for _, s := range statuses {
+		if s.Period == period {
+			return s, true
+		}
+	}
+	if len(statuses) == 0 {
+		return Status{}, false
+	}
+	best := statuses[0]
+	for _, s := range statuses[1:] {
+		if s.UsedPct() > best.UsedPct() {
+			best = s
+		}
+	}
+	return best, true
+}
+
+// ///////////////////////////////////////////////
+// Alerts
+// ///////////////////////////////////////////////
+
+// Alerter runs a shell command the first time a budget reaches the warning
+// threshold or the budget itself in each period. Fired alerts are persisted,
+// so a daemon restart does not repeat them.
+type Alerter struct {
+	// mu protects fired.
+	mu sync.Mutex
+	// path is the JSON file holding fired alerts.
+	path string
+	// command is the shell command to run. Empty disables alerts.
+	command string
+	// warnPercent is the warning threshold in percent of the budget.
+	warnPercent float64
+	// fired maps alert keys (period, period start, level) to the Unix time
+	// the alert fired.
+	fired map[string]int64
+}
+
+// NewAlerter creates an Alerter that records fired alerts at path. Previously
+// fired alerts are loaded from path when it exists.
+func NewAlerter(path, command string, warnPercent float64) *Alerter {
+	a := &Alerter{
+		path:        path,
+		command:     command,
+		warnPercent: warnPercent,
+		fired:       make(map[string]int64),
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &a.fired); err != nil {
+			slog.Warn("ignoring unreadable budget alert state", "path", path, "error", err)
+			a.fired = make(map[string]int64)
+		}
+	}
+	return a
+}
+
+// Check fires the alert command for every status whose level rose above
+// [LevelOK] for the first time in its current period. The command runs in
+// the background.
+func (a *Alerter) Check(statuses []Status, now time.Time) {
+	if a == nil || a.command == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	changed := false
+	for _, s := range statuses {
+		level := s.Level(a.warnPercent)
+		if level == LevelOK {
+			continue
+		}
+		key := fmt.Sprintf("%s:%s:%s", s.Period, s.Start.Format(time.DateOnly), level)
+		if _, ok := a.fired[key]; ok {
+			continue
+		}
+		a.fired[key] = now.Unix()
+		changed = true
+		slog.Info("budget alert", "period", s.Period, "level", level.String(), "spent", s.Spent, "limit", s.Limit)
+		go runCommand(a.command, alertEnv(s, level))
+	}
+	if changed {
+		a.prune(now)
+		a.save()
+	}
+}
+
+// prune drops alerts fired more than two months ago, which can no longer
+// belong to a current period. The caller must hold a.mu.
+func (a *Alerter) prune(now time.Time) {
+	cutoff := now.AddDate(0, -2, 0).Unix()
+	for k, t := range a.fired {
+		if t < cutoff {
+			delete(a.fired, k)
+		}
+	}
+}
+
+// save persists fired alerts. The caller must hold a.mu.
+func (a *Alerter) save() {
+	data, err := json.Marshal(a.fired)
+	if err != nil {
+		slog.Warn("failed to encode budget alert state", "error", err)
+		return
+	}
+	if err := atomicfile.Write(a.path, data, 0o600); err != nil {
+		slog.Warn("failed to save budget alert state", "path", a.path, "error", err)
+	}
+}
+
+// alertEnv returns the environment variables describing an alert.
+func alertEnv(s Status, level Level) []string {
+	return []string{
+		"AGENTCORD_BUDGET_PERIOD=" + s.Period,
+		"AGENTCORD_BUDGET_LEVEL=" + level.String(),
+		fmt.Sprintf("AGENTCORD_BUDGET_LIMIT=%.2f", s.Limit),
+		fmt.Sprintf("AGENTCORD_BUDGET_SPENT=%.2f", s.Spent),
+		fmt.Sprintf("AGENTCORD_BUDGET_USED_PCT=%.0f", s.UsedPct()),
+	}
+}
+
+// runCommand runs command through the platform shell with env added to the
+// daemon's environment, logging failures.
+func runCommand(command string, env []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		slog.Warn("budget alert command failed", "error", err, "output", strings.TrimSpace(string(out)))
+	}
+}
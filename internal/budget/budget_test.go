@@ -0,0 +1,166 @@
+package budget
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Period Tests
+// ///////////////////////////////////////////////
+
+func TestPeriodStart(t *testing.T) {
+	// Thursday 2026-10-15 14:30.
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		want   time.Time
+	}{
+		{Daily, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{Weekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{Monthly, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := PeriodStart(tt.period, now); !got.Equal(tt.want) {
+			t.Errorf("PeriodStart(%s) = %v, want %v", tt.period, got, tt.want)
+		}
+	}
+
+	// A Sunday belongs to the week that started the Monday before.
+	sunday := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	if got := PeriodStart(Weekly, sunday); !got.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("PeriodStart(weekly, Sunday) = %v, want Monday 12th", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Evaluate Tests
+// ///////////////////////////////////////////////
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+	spent := func(from, to time.Time) float64 {
+		// $10 per day elapsed in the range, plus today's $5.
+		return float64(int(now.Sub(from).Hours()/24))*10 + 5
+	}
+
+	got := Evaluate(Limits{Daily: 10, Monthly: 200}, spent, now)
+	if len(got) != 2 || got[0].Period != Daily || got[1].Period != Monthly {
+		t.Fatalf("Evaluate = %+v, want daily and monthly", got)
+	}
+	if got[0].Spent != 5 || got[0].UsedPct() != 50 || got[0].Remaining() != 5 {
+		t.Errorf("daily = %+v (%.0f%%), want $5 of $10", got[0], got[0].UsedPct())
+	}
+	if got[1].Spent != 145 {
+		t.Errorf("monthly spent = %v, want 145", got[1].Spent)
+	}
+
+	if got := Evaluate(Limits{}, spent, now); len(got) != 0 {
+		t.Errorf("Evaluate with no budgets = %+v, want none", got)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	statuses := []Status{
+		{Period: Daily, Limit: 10, Spent: 5},
+		{Period: Weekly, Limit: 50, Spent: 40},
+	}
+	if s, _ := Select(statuses, ""); s.Period != Weekly {
+		t.Errorf("Select(\"\") = %s, want weekly (80%% used)", s.Period)
+	}
+	if s, _ := Select(statuses, Daily); s.Period != Daily {
+		t.Errorf("Select(daily) = %s, want daily", s.Period)
+	}
+	if s, _ := Select(statuses, Monthly); s.Period != Weekly {
+		t.Errorf("Select(unconfigured) = %s, want closest to limit", s.Period)
+	}
+	if _, ok := Select(nil, ""); ok {
+		t.Error("Select(nil) reported a status")
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		spent float64
+		warn  float64
+		want  Level
+	}{
+		{7, 80, LevelOK},
+		{8, 80, LevelWarn},
+		{10, 80, LevelExceeded},
+		{12, 80, LevelExceeded},
+		{9, 0, LevelOK},
+	}
+	for _, tt := range tests {
+		s := Status{Limit: 10, Spent: tt.spent}
+		if got := s.Level(tt.warn); got != tt.want {
+			t.Errorf("Level(spent=%v, warn=%v) = %s, want %s", tt.spent, tt.warn, got, tt.want)
+		}
+	}
+}
+
+// ///////////////////////////////////////////////
+// Alerter Tests
+// ///////////////////////////////////////////////
+
+func TestAlerter_FiresOncePerPeriodAndLevel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("alert command uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "alerts.log")
+	statePath := filepath.Join(dir, "alerts.json")
+	cmd := `echo "$AGENTCORD_BUDGET_PERIOD $AGENTCORD_BUDGET_LEVEL" >> ` + out
+
+	now := time.Date(2026, 10, 15, 14, 0, 0, 0, time.Local)
+	warn := []Status{{Period: Daily, Limit: 10, Spent: 9, Start: PeriodStart(Daily, now)}}
+	over := []Status{{Period: Daily, Limit: 10, Spent: 11, Start: PeriodStart(Daily, now)}}
+
+	a := NewAlerter(statePath, cmd, 80)
+	a.Check(warn, now)
+	a.Check(warn, now)
+	waitForLines(t, out, 1)
+
+	// A restarted daemon does not repeat the warning, but still alerts on exceeding.
+	a = NewAlerter(statePath, cmd, 80)
+	a.Check(warn, now)
+	a.Check(over, now)
+	waitForLines(t, out, 2)
+
+	data, _ := os.ReadFile(out)
+	if want := "daily warn\ndaily exceeded\n"; string(data) != want {
+		t.Errorf("alert log = %q, want %q", data, want)
+	}
+}
+
+func TestAlerter_NoCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+	a := NewAlerter(path, "", 80)
+	a.Check([]Status{{Period: Daily, Limit: 1, Spent: 2}}, time.Now())
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("alert state written without an alert command")
+	}
+}
+
+// waitForLines waits until path holds n lines.
+func waitForLines(t *testing.T, path string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(path)
+		lines := 0
+		for _, b := range data {
+			if b == '\n' {
+				lines++
+			}
+		}
+		if lines >= n {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d lines in %s", n, path)
+}
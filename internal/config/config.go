@@ -11,6 +11,7 @@ import (
 	"bytes"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -91,14 +92,39 @@ type Config struct {
 	Privacy PrivacyConfig `toml:"privacy"`
 	// Behavior holds daemon behavior and idle settings.
 	Behavior BehaviorConfig `toml:"behavior"`
+	// Budget holds API-value spending budgets and their warnings.
+	Budget BudgetConfig `toml:"budget"`
+	// Statusline holds the terminal statusline printed by `agentcord statusline`.
+	Statusline StatuslineConfig `toml:"statusline"`
 	// Pricing holds model pricing data source settings.
 	Pricing PricingConfig `toml:"pricing"`
+	// Network holds settings shared by every outbound HTTP request.
+	Network NetworkConfig `toml:"network"`
 	// Log holds logging settings.
 	Log LogConfig `toml:"log"`
 	// Clients holds per-client overrides keyed by client name (e.g. "cursor", "windsurf").
 	Clients map[string]ClientConfig `toml:"clients,omitempty"`
 }
 
+// NetworkConfig holds settings shared by every outbound HTTP request
+// (pricing, model tiers, update check).
+type NetworkConfig struct {
+	// Offline disables all network access. Pricing comes from the cache or
+	// static config, and tiers from the cache or the copy built into the binary.
+	Offline bool `toml:"offline"`
+	// Proxy is an http, https, or socks5 proxy URL. Empty uses the
+	// HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables.
+	Proxy string `toml:"proxy,omitempty"`
+	// CABundle is a PEM file of extra trusted CA certificates.
+	CABundle string `toml:"ca_bundle,omitempty"`
+	// UserAgent overrides the User-Agent header. Empty uses "agentcord/<version>".
+	UserAgent string `toml:"user_agent,omitempty"`
+	// TimeoutSeconds bounds each request, including reading the response.
+	TimeoutSeconds int `toml:"timeout_seconds"`
+	// ConnectTimeoutSeconds bounds connecting and the TLS handshake.
+	ConnectTimeoutSeconds int `toml:"connect_timeout_seconds"`
+}
+
 // LogConfig holds logging settings.
 type LogConfig struct {
 	// Level is the minimum log level (trace, debug, info, warn, error).
@@ -123,6 +149,30 @@ type ClientConfig struct {
 	SmallImage string `toml:"small_image,omitempty"`
 	// SmallText overrides the small image tooltip for this client.
 	SmallText string `toml:"small_text,omitempty"`
+	// ActivityType overrides the activity type for this client.
+	ActivityType string `toml:"activity_type,omitempty"`
+	// StatusDisplay overrides what the profile status line shows for this client.
+	StatusDisplay string `toml:"status_display,omitempty"`
+	// DetailsURL overrides the details line link template for this client.
+	DetailsURL string `toml:"details_url,omitempty"`
+	// StateURL overrides the state line link template for this client.
+	StateURL string `toml:"state_url,omitempty"`
+	// LargeURL overrides the large image link template for this client.
+	LargeURL string `toml:"large_url,omitempty"`
+	// SmallURL overrides the small image link template for this client.
+	SmallURL string `toml:"small_url,omitempty"`
+	// EndMinutes overrides the countdown length for this client. Nil keeps
+	// the [display.timestamps] setting; 0 turns the countdown off.
+	EndMinutes *int `toml:"end_minutes,omitempty"`
+	// Instance overrides whether the activity is an instanced session. Nil
+	// keeps the [display] setting.
+	Instance *bool `toml:"instance,omitempty"`
+	// PartyID overrides the party ID for this client.
+	PartyID string `toml:"party_id,omitempty"`
+	// PartySize overrides the party size for this client.
+	PartySize int `toml:"party_size,omitempty"`
+	// PartyMax overrides the party maximum for this client.
+	PartyMax int `toml:"party_max,omitempty"`
 }
 
 // DiscordConfig holds Discord connection settings.
@@ -141,6 +191,18 @@ type DisplayConfig struct {
 	DetailsNoBranch string `toml:"details_no_branch"`
 	// StateNoCost is the state template used when cost data is unavailable.
 	StateNoCost string `toml:"state_no_cost"`
+	// ActivityType is the verb shown before the app name: "playing",
+	// "listening", "watching", or "competing".
+	ActivityType string `toml:"activity_type"`
+	// StatusDisplay selects what the profile status line shows: "name" (the
+	// app name), "state", or "details".
+	StatusDisplay string `toml:"status_display"`
+	// DetailsURL is the link template for the details line. Empty adds no link.
+	DetailsURL string `toml:"details_url,omitempty"`
+	// StateURL is the link template for the state line. Empty adds no link.
+	StateURL string `toml:"state_url,omitempty"`
+	// Instance marks the activity as an instanced game session.
+	Instance bool `toml:"instance"`
 	// Assets holds Discord Rich Presence asset settings.
 	Assets AssetsConfig `toml:"assets"`
 	// Buttons holds Discord Rich Presence button settings.
@@ -149,6 +211,39 @@ type DisplayConfig struct {
 	Format FormatConfig `toml:"format"`
 	// Timestamps holds timestamp display settings.
 	Timestamps TimestampsConfig `toml:"timestamps"`
+	// Currency holds the display currency and its exchange rate source.
+	Currency CurrencyConfig `toml:"currency"`
+	// Party holds the party shown on the card.
+	Party PartyConfig `toml:"party"`
+	// RotationSeconds is how long each rotation card or session is shown
+	// before the next. 0 disables rotation.
+	RotationSeconds int `toml:"rotation_seconds"`
+	// RotateSessions cycles the card through the live sessions instead of
+	// showing only the most recent one.
+	RotateSessions bool `toml:"rotate_sessions"`
+	// Rotation is the list of cards the presence cycles through. Empty
+	// shows the details and state templates above.
+	Rotation []RotationCard `toml:"rotation,omitempty"`
+}
+
+// MinRotationSeconds is the shortest allowed rotation interval. Discord
+// accepts 5 activity updates per 20 seconds; rotating no faster than this
+// leaves room for the updates caused by session activity.
+const MinRotationSeconds = 15
+
+// RotationCard is one card of the presence carousel. Empty templates fall
+// back to the matching [display] template.
+type RotationCard struct {
+	// Details is the format string for the top line.
+	Details string `toml:"details,omitempty"`
+	// State is the format string for the bottom line.
+	State string `toml:"state,omitempty"`
+	// DetailsNoBranch is the details template used when no git branch is
+	// available. Empty uses Details.
+	DetailsNoBranch string `toml:"details_no_branch,omitempty"`
+	// StateNoCost is the state template used when cost data is unavailable.
+	// Empty uses State.
+	StateNoCost string `toml:"state_no_cost,omitempty"`
 }
 
 // AssetsConfig holds Discord Rich Presence asset settings.
@@ -159,6 +254,10 @@ type AssetsConfig struct {
 	LargeText string `toml:"large_text"`
 	// ShowModelIcon enables the small image overlay showing the active model tier.
 	ShowModelIcon bool `toml:"show_model_icon"`
+	// LargeURL is the link template for the large image. Empty adds no link.
+	LargeURL string `toml:"large_url,omitempty"`
+	// SmallURL is the link template for the small image. Empty adds no link.
+	SmallURL string `toml:"small_url,omitempty"`
 }
 
 // ButtonsConfig holds Discord Rich Presence button settings.
@@ -191,6 +290,39 @@ type FormatConfig struct {
 type TimestampsConfig struct {
 	// Mode controls what the elapsed timer tracks: "session", "elapsed", or "none".
 	Mode string `toml:"mode"`
+	// EndMinutes sets an end time this many minutes after the start, so the
+	// timer counts down instead of up. 0 shows elapsed time.
+	EndMinutes int `toml:"end_minutes"`
+}
+
+// PartyConfig holds the party shown on the card as "(size of max)".
+type PartyConfig struct {
+	// ID identifies the party. Empty uses a default ID.
+	ID string `toml:"id,omitempty"`
+	// Size is the party size. 0 shows no party outside aggregate mode, where
+	// the size is the live session count.
+	Size int `toml:"size"`
+	// Max is the maximum party size. Values below Size show Size.
+	Max int `toml:"max"`
+}
+
+// CurrencyConfig holds the display currency for costs and where its exchange
+// rate comes from. Costs are computed in USD and converted at render time.
+type CurrencyConfig struct {
+	// Code is the ISO 4217 code of the display currency (e.g. "EUR").
+	Code string `toml:"code"`
+	// Symbol overrides the currency symbol. Empty derives it from Code.
+	Symbol string `toml:"symbol,omitempty"`
+	// SymbolPosition places the symbol "before" or "after" the amount.
+	SymbolPosition string `toml:"symbol_position"`
+	// Source selects the exchange rate source: "url", "file", or "static".
+	Source string `toml:"source"`
+	// Rate is the number of Code units per USD for source "static".
+	Rate float64 `toml:"rate,omitempty"`
+	// File is the local rate table path for source "file".
+	File string `toml:"file,omitempty"`
+	// URL is a custom rate table endpoint for source "url".
+	URL string `toml:"url,omitempty"`
 }
 
 // PrivacyOverride applies privacy settings to projects matching a glob pattern.
@@ -223,8 +355,15 @@ type BehaviorConfig struct {
 	ShowTokens bool `toml:"show_tokens"`
 	// ShowBranch enables git branch display in the details line.
 	ShowBranch bool `toml:"show_branch"`
-	// UseStatusline reads session data from the Claude Code statusline instead of state.json.
+	// UseStatusline reads session data from the Claude Code statusline payload
+	// in addition to the hook state files.
 	UseStatusline bool `toml:"use_statusline"`
+	// AggregateSessions shows every live session on one card, summing their
+	// cost and tokens, instead of only the most recent session.
+	AggregateSessions bool `toml:"aggregate_sessions"`
+	// AggregatePartyMax is the maximum of the party size shown in aggregate
+	// mode ("3 of N"). 0 uses the number of live sessions.
+	AggregatePartyMax int `toml:"aggregate_party_max"`
 	// CostShowThreshold is the minimum cost value before cost is displayed (0 = always).
 	CostShowThreshold float64 `toml:"cost_show_threshold"`
 	// TokensShowThreshold is the minimum token count before tokens are displayed (0 = always).
@@ -241,10 +380,55 @@ type BehaviorConfig struct {
 	DaemonIdleMinutes int `toml:"daemon_idle_minutes"`
 	// PollIntervalSeconds is the fallback polling interval for state changes.
 	PollIntervalSeconds int `toml:"poll_interval_seconds"`
-	// ReconnectIntervalSeconds is the Discord reconnect interval.
+	// ReconnectIntervalSeconds is the delay before the first Discord
+	// reconnect attempt; later attempts back off exponentially.
 	ReconnectIntervalSeconds int `toml:"reconnect_interval_seconds"`
-	// SessionCleanupHours is how old a session marker must be before it is removed.
+	// SessionCleanupHours is how old a session marker or state file must be before it is removed.
 	SessionCleanupHours int `toml:"session_cleanup_hours"`
+	// PricingRefreshMinutes is how often pricing data is re-fetched. 0 disables refresh.
+	PricingRefreshMinutes int `toml:"pricing_refresh_minutes"`
+	// TiersRefreshMinutes is how often model tier data is re-fetched. 0 disables refresh.
+	TiersRefreshMinutes int `toml:"tiers_refresh_minutes"`
+	// CurrencyRefreshMinutes is how often the exchange rate is re-fetched. 0 disables refresh.
+	CurrencyRefreshMinutes int `toml:"currency_refresh_minutes"`
+	// TranscriptRoots are directories searched for session transcripts, each
+	// holding one directory per project. A leading ~ expands to the home directory.
+	TranscriptRoots []string `toml:"transcript_roots"`
+}
+
+// BudgetConfig holds API-value spending budgets in USD and how the presence
+// card reacts when spending approaches them.
+type BudgetConfig struct {
+	// Daily is the budget per calendar day in USD. 0 disables it.
+	Daily float64 `toml:"daily"`
+	// Weekly is the budget per week (Monday to Sunday) in USD. 0 disables it.
+	Weekly float64 `toml:"weekly"`
+	// Monthly is the budget per calendar month in USD. 0 disables it.
+	Monthly float64 `toml:"monthly"`
+	// WarnPercent is the share of a budget, in percent, at which the warning
+	// templates and alert command take effect.
+	WarnPercent float64 `toml:"warn_percent"`
+	// WarnDetails replaces the details template while a budget is over WarnPercent.
+	WarnDetails string `toml:"warn_details,omitempty"`
+	// WarnState replaces the state template while a budget is over WarnPercent.
+	WarnState string `toml:"warn_state,omitempty"`
+	// WarnSmallImage replaces the small image while a budget is over WarnPercent.
+	WarnSmallImage string `toml:"warn_small_image,omitempty"`
+	// WarnSmallText replaces the small image tooltip while a budget is over WarnPercent.
+	WarnSmallText string `toml:"warn_small_text,omitempty"`
+	// AlertCommand is a shell command run once per period when a budget
+	// crosses WarnPercent and again when it is exceeded.
+	AlertCommand string `toml:"alert_command,omitempty"`
+}
+
+// StatuslineConfig holds the format of the terminal statusline printed by
+// `agentcord statusline`, which Claude Code's statusLine setting runs.
+type StatuslineConfig struct {
+	// Segments are templates rendered with the presence card variables.
+	// Segments whose variables all render empty are dropped.
+	Segments []string `toml:"segments"`
+	// Separator joins the rendered segments.
+	Separator string `toml:"separator"`
 }
 
 // PricingConfig holds settings for where and how pricing data is loaded.
@@ -259,6 +443,9 @@ type PricingConfig struct {
 	File string `toml:"file,omitempty"`
 	// Models holds inline per-model pricing for source "static".
 	Models map[string]PricingModelConfig `toml:"models,omitempty"`
+	// Aliases maps model IDs to pricing keys for models the automatic
+	// normalization cannot match (e.g. custom proxy model names).
+	Aliases map[string]string `toml:"aliases,omitempty"`
 }
 
 // PricingModelConfig holds per-token pricing for a model in static config.
@@ -267,6 +454,34 @@ type PricingModelConfig struct {
 	InputPerToken float64 `toml:"input_per_token"`
 	// OutputPerToken is the cost per output token in USD.
 	OutputPerToken float64 `toml:"output_per_token"`
+	// CacheWrite5mPerToken is the cost per token written to the 5-minute prompt cache in USD.
+	CacheWrite5mPerToken float64 `toml:"cache_write_5m_per_token,omitempty"`
+	// CacheWrite1hPerToken is the cost per token written to the 1-hour prompt cache in USD.
+	// Falls back to CacheWrite5mPerToken when unset.
+	CacheWrite1hPerToken float64 `toml:"cache_write_1h_per_token,omitempty"`
+	// CacheReadPerToken is the cost per token read from the prompt cache in USD.
+	CacheReadPerToken float64 `toml:"cache_read_per_token,omitempty"`
+	// Bands holds long-context rates for requests whose prompt exceeds a threshold.
+	Bands []PricingBandConfig `toml:"bands,omitempty"`
+	// ContextWindow is the model's maximum prompt size in tokens, used by {context_pct}.
+	ContextWindow int64 `toml:"context_window,omitempty"`
+}
+
+// PricingBandConfig holds long-context per-token pricing that replaces the
+// base rates for requests above a prompt size. Zero rates inherit the base rate.
+type PricingBandConfig struct {
+	// AboveInputTokens is the prompt size (input plus cache tokens) above which the band applies.
+	AboveInputTokens int64 `toml:"above_input_tokens"`
+	// InputPerToken is the cost per input token in USD.
+	InputPerToken float64 `toml:"input_per_token,omitempty"`
+	// OutputPerToken is the cost per output token in USD.
+	OutputPerToken float64 `toml:"output_per_token,omitempty"`
+	// CacheWrite5mPerToken is the cost per token written to the 5-minute prompt cache in USD.
+	CacheWrite5mPerToken float64 `toml:"cache_write_5m_per_token,omitempty"`
+	// CacheWrite1hPerToken is the cost per token written to the 1-hour prompt cache in USD.
+	CacheWrite1hPerToken float64 `toml:"cache_write_1h_per_token,omitempty"`
+	// CacheReadPerToken is the cost per token read from the prompt cache in USD.
+	CacheReadPerToken float64 `toml:"cache_read_per_token,omitempty"`
 }
 
 // ///////////////////////////////////////////////
@@ -282,9 +497,11 @@ func DefaultConfig() *Config {
 		},
 		Display: DisplayConfig{
 			Details:         "Working on: {project} ({branch})",
-			State:           "{model} · ~${cost} API value",
+			State:           "{model} · ~{cost} API value",
 			DetailsNoBranch: "Working on: {project}",
 			StateNoCost:     "{model} · {tokens} tokens",
+			ActivityType:    "playing",
+			StatusDisplay:   "name",
 			Assets: AssetsConfig{
 				LargeImage:    "app_icon",
 				LargeText:     "Agentcord",
@@ -304,6 +521,11 @@ func DefaultConfig() *Config {
 			Timestamps: TimestampsConfig{
 				Mode: "session",
 			},
+			Currency: CurrencyConfig{
+				Code:           "USD",
+				SymbolPosition: "before",
+				Source:         "url",
+			},
 		},
 		Privacy: PrivacyConfig{
 			HideProjectName:   false,
@@ -325,11 +547,27 @@ func DefaultConfig() *Config {
 			PollIntervalSeconds:      5,
 			ReconnectIntervalSeconds: 15,
 			SessionCleanupHours:      24,
+			PricingRefreshMinutes:    360,
+			TiersRefreshMinutes:      1440,
+			CurrencyRefreshMinutes:   720,
+			TranscriptRoots:          []string{"~/.claude/projects"},
+		},
+		Budget: BudgetConfig{
+			WarnPercent: 80,
+			WarnState:   "{model} · {budget_used_pct} of {budget_period} budget used",
+		},
+		Statusline: StatuslineConfig{
+			Segments:  []string{"{model:display}", "{cost}", "{context_pct} ctx"},
+			Separator: " · ",
 		},
 		Pricing: PricingConfig{
 			Source: "url",
 			Format: "openrouter",
 		},
+		Network: NetworkConfig{
+			TimeoutSeconds:        10,
+			ConnectTimeoutSeconds: 5,
+		},
 		Log: LogConfig{
 			Level:     "info",
 			MaxSizeMB: 10,
@@ -443,6 +681,9 @@ func (c *Config) Save(path string) error {
 // Validation
 // ///////////////////////////////////////////////
 
+// currencyCodeRe matches a three-letter ISO 4217 currency code.
+var currencyCodeRe = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
 // costFormatRe matches a valid fmt-style format string for a single float verb.
 var costFormatRe = regexp.MustCompile(`^[^%]*%[0-9.*]*[fFeEgG][^%]*$`)
 
@@ -465,6 +706,41 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid timestamps.mode %q: must be session, elapsed, or none", c.Display.Timestamps.Mode)
 	}
 
+	if err := validateActivityType("display.activity_type", c.Display.ActivityType); err != nil {
+		return err
+	}
+	if err := validateStatusDisplay("display.status_display", c.Display.StatusDisplay); err != nil {
+		return err
+	}
+	for field, u := range map[string]string{
+		"display.details_url":      c.Display.DetailsURL,
+		"display.state_url":        c.Display.StateURL,
+		"display.assets.large_url": c.Display.Assets.LargeURL,
+		"display.assets.small_url": c.Display.Assets.SmallURL,
+	} {
+		if err := validateLinkURL(field, u); err != nil {
+			return err
+		}
+	}
+
+	if c.Display.Timestamps.EndMinutes < 0 {
+		return fmt.Errorf("timestamps.end_minutes must be >= 0, got %d", c.Display.Timestamps.EndMinutes)
+	}
+
+	if p := c.Display.Party; p.Size < 0 || p.Max < 0 {
+		return fmt.Errorf("party size and max must be >= 0, got size=%d max=%d", p.Size, p.Max)
+	}
+
+	for id, cl := range c.Clients {
+		if err := validateClient(id, cl); err != nil {
+			return err
+		}
+	}
+
+	if r := c.Display.RotationSeconds; r < 0 || (r > 0 && r < MinRotationSeconds) {
+		return fmt.Errorf("rotation_seconds must be 0 or at least %d, got %d", MinRotationSeconds, r)
+	}
+
 	if !validLogLevels[strings.ToLower(c.Log.Level)] {
 		return fmt.Errorf("invalid log.level %q: must be trace, debug, info, warn, or error", c.Log.Level)
 	}
@@ -481,10 +757,34 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("daemon_idle_minutes must be >= 0, got %d", c.Behavior.DaemonIdleMinutes)
 	}
 
+	if c.Behavior.AggregatePartyMax < 0 {
+		return fmt.Errorf("aggregate_party_max must be >= 0, got %d", c.Behavior.AggregatePartyMax)
+	}
+
 	if c.Behavior.SessionCleanupHours <= 0 {
 		return fmt.Errorf("session_cleanup_hours must be > 0, got %d", c.Behavior.SessionCleanupHours)
 	}
 
+	if c.Behavior.PricingRefreshMinutes < 0 {
+		return fmt.Errorf("pricing_refresh_minutes must be >= 0, got %d", c.Behavior.PricingRefreshMinutes)
+	}
+
+	if c.Behavior.TiersRefreshMinutes < 0 {
+		return fmt.Errorf("tiers_refresh_minutes must be >= 0, got %d", c.Behavior.TiersRefreshMinutes)
+	}
+
+	if c.Behavior.CurrencyRefreshMinutes < 0 {
+		return fmt.Errorf("currency_refresh_minutes must be >= 0, got %d", c.Behavior.CurrencyRefreshMinutes)
+	}
+
+	b := c.Budget
+	if b.Daily < 0 || b.Weekly < 0 || b.Monthly < 0 {
+		return fmt.Errorf("budget amounts must be >= 0, got daily=%g weekly=%g monthly=%g", b.Daily, b.Weekly, b.Monthly)
+	}
+	if b.WarnPercent < 0 || b.WarnPercent > 100 {
+		return fmt.Errorf("budget.warn_percent must be between 0 and 100, got %g", b.WarnPercent)
+	}
+
 	switch c.Pricing.Source {
 	case "url", "file", "static":
 	default:
@@ -515,6 +815,55 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid pricing.format %q: must be openrouter, litellm, or agentcord", c.Pricing.Format)
 	}
 
+	for model, m := range c.Pricing.Models {
+		for _, b := range m.Bands {
+			if b.AboveInputTokens <= 0 {
+				return fmt.Errorf("pricing.models.%s.bands: above_input_tokens must be > 0, got %d", model, b.AboveInputTokens)
+			}
+		}
+	}
+
+	cur := c.Display.Currency
+	if !currencyCodeRe.MatchString(cur.Code) {
+		return fmt.Errorf("invalid currency.code %q: must be a three-letter ISO 4217 code", cur.Code)
+	}
+	switch cur.SymbolPosition {
+	case "before", "after":
+	default:
+		return fmt.Errorf("invalid currency.symbol_position %q: must be before or after", cur.SymbolPosition)
+	}
+	switch cur.Source {
+	case "url", "file", "static":
+	default:
+		return fmt.Errorf("invalid currency.source %q: must be url, file, or static", cur.Source)
+	}
+	if cur.Rate < 0 {
+		return fmt.Errorf("currency.rate must be >= 0, got %g", cur.Rate)
+	}
+	if cur.Source == "static" && cur.Rate == 0 && !strings.EqualFold(cur.Code, "USD") {
+		return fmt.Errorf("currency.rate must be set for source \"static\" with code %q", cur.Code)
+	}
+
+	if c.Network.Proxy != "" {
+		u, err := url.Parse(c.Network.Proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid network.proxy %q: must be a URL like http://host:port", c.Network.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("invalid network.proxy scheme %q: must be http, https, socks5, or socks5h", u.Scheme)
+		}
+	}
+
+	if c.Network.TimeoutSeconds <= 0 {
+		return fmt.Errorf("network.timeout_seconds must be > 0, got %d", c.Network.TimeoutSeconds)
+	}
+
+	if c.Network.ConnectTimeoutSeconds <= 0 {
+		return fmt.Errorf("network.connect_timeout_seconds must be > 0, got %d", c.Network.ConnectTimeoutSeconds)
+	}
+
 	if !costFormatRe.MatchString(c.Display.Format.CostFormat) {
 		return fmt.Errorf("invalid cost_format %q: must contain exactly one float format verb (%%f, %%e, %%g)", c.Display.Format.CostFormat)
 	}
@@ -522,6 +871,64 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateActivityType checks an activity type setting. Streaming and custom
+// activities cannot be set over IPC, so they are not accepted.
+func validateActivityType(field, v string) error {
+	switch v {
+	case "playing", "listening", "watching", "competing":
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q: must be playing, listening, watching, or competing", field, v)
+}
+
+// validateStatusDisplay checks a status display setting.
+func validateStatusDisplay(field, v string) error {
+	switch v {
+	case "name", "state", "details":
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q: must be name, state, or details", field, v)
+}
+
+// validateLinkURL checks a link template. Discord only accepts http and https
+// links; the rest of the URL may contain template variables. Empty is valid.
+func validateLinkURL(field, v string) error {
+	if v == "" || strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "http://") {
+		return nil
+	}
+	return fmt.Errorf("invalid %s %q: must start with http:// or https://", field, v)
+}
+
+// validateClient checks the [clients.id] overrides. Empty values keep the
+// [display] settings.
+func validateClient(id string, cl ClientConfig) error {
+	prefix := "clients." + id + "."
+	if cl.ActivityType != "" {
+		if err := validateActivityType(prefix+"activity_type", cl.ActivityType); err != nil {
+			return err
+		}
+	}
+	if cl.StatusDisplay != "" {
+		if err := validateStatusDisplay(prefix+"status_display", cl.StatusDisplay); err != nil {
+			return err
+		}
+	}
+	for field, u := range map[string]string{
+		"details_url": cl.DetailsURL,
+		"state_url":   cl.StateURL,
+		"large_url":   cl.LargeURL,
+		"small_url":   cl.SmallURL,
+	} {
+		if err := validateLinkURL(prefix+field, u); err != nil {
+			return err
+		}
+	}
+	if (cl.EndMinutes != nil && *cl.EndMinutes < 0) || cl.PartySize < 0 || cl.PartyMax < 0 {
+		return fmt.Errorf("%send_minutes, party_size and party_max must be >= 0", prefix)
+	}
+	return nil
+}
+
 // ///////////////////////////////////////////////
 // Formatting Helpers
 // ///////////////////////////////////////////////
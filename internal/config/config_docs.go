@@ -34,14 +34,37 @@ var ConfigDocs = map[string]FieldDoc{
 
 	// ── Display ──────────────────────────────────────────────────
 	"display.details": {
-		Comment: "Format strings for the presence card.\nAvailable variables: {project}, {branch}, {model}, {cost}, {tokens}\nAgentic variables: {tool}, {tool_target}, {file}, {agent_state}, {permission}, {client}\nExtended tokens: {input_tokens}, {output_tokens}, {cache_tokens}, {turns}\nGit extended: {git_owner}, {git_repo}\nFormat suffixes: {file:basename}, {file:dir}, {file:ext}, {model:short}, {model:full}, {model:raw}\n\ndetails = top line, state = bottom line",
+		Comment: "Format strings for the presence card.\nAvailable variables: {project}, {branch}, {model}, {cost}, {tokens}\nAgentic variables: {tool}, {tool_target}, {file}, {agent_state}, {permission}, {client}\nExtended tokens: {input_tokens}, {output_tokens}, {cache_tokens}, {turns}\nPer-model: {model_mix}, {cost:opus} (cost of models whose ID contains \"opus\")\nSubagents: {subagents}, {subagent_cost}, {subagent_turns}\nContext window: {context_pct}, {context_tokens}\nConcurrent sessions: {session_count}, {total_cost}, {projects} (see behavior.aggregate_sessions)\nCurrency: {cost} uses [display.currency]; {cost:usd} always shows unconverted USD\nGit extended: {git_owner}, {git_repo}\nFormat suffixes: {file:basename}, {file:dir}, {file:ext}, {model:short}, {model:full}, {model:raw}\n\ndetails = top line, state = bottom line",
 	},
 	"display.state": {},
 	"display.details_no_branch": {
 		Comment: "What to show when there's no git branch",
 	},
 	"display.state_no_cost": {
-		Comment: "What to show when cost is unavailable (pricing source unreachable, no pricing data)",
+		Comment: "What to show when cost is unavailable (pricing still loading, source unreachable, no pricing data)",
+	},
+	"display.activity_type": {
+		Comment: "Verb shown before the app name. Options: \"playing\", \"listening\", \"watching\", \"competing\"",
+		Alternatives: []string{
+			`activity_type = "competing"`,
+		},
+	},
+	"display.status_display": {
+		Comment: "What your status line in the member list shows. Options: \"name\", \"state\", \"details\"\n  name:    the app name (\"Playing Agentcord\")\n  state:   the state line\n  details: the details line, e.g. the project",
+		Alternatives: []string{
+			`status_display = "details"`,
+		},
+	},
+	"display.details_url": {
+		Comment: "Links opened when the details or state line is clicked. Templates like details\nand state; must start with http:// or https://. Empty = no link.",
+		Alternatives: []string{
+			`details_url = "https://github.com/{git_owner}/{git_repo}"`,
+			`state_url = "https://example.com"`,
+		},
+	},
+	"display.state_url": {},
+	"display.instance": {
+		Comment: "Mark the activity as an instanced game session.",
 	},
 
 	// ── Assets ───────────────────────────────────────────────────
@@ -52,12 +75,32 @@ var ConfigDocs = map[string]FieldDoc{
 	"display.assets.show_model_icon": {
 		Comment: "Small image shows the active model tier.\nUpload icons named \"opus\", \"sonnet\", \"haiku\" to your Discord app's Rich Presence assets.\nThe daemon automatically sets small_image based on the current model.\nSet to false to disable the small image overlay entirely.",
 	},
+	"display.assets.large_url": {
+		Comment: "Links opened when the large or small image is clicked (templates, http(s) only).\nA link is only sent while its image is shown.",
+		Alternatives: []string{
+			`large_url = "https://github.com/{git_owner}/{git_repo}"`,
+			`small_url = "https://example.com"`,
+		},
+	},
+	"display.assets.small_url": {},
 
 	// ── Buttons ──────────────────────────────────────────────────
 	"display.buttons.show_repo_button": {
 		Comment: "Auto-detect git remote URL and show a \"View Repository\" button on the card.\nOnly works when the project CWD has a git remote configured.",
 	},
 	"display.buttons.repo_button_label": {},
+	"display.rotation_seconds": {
+		Comment: "Rotate the card every this many seconds through the [[display.rotation]]\ncards and, with rotate_sessions, the live sessions. 0 = no rotation.\nAt least 15, so updates stay within Discord's rate limit.",
+		Alternatives: []string{
+			`rotation_seconds = 20`,
+		},
+	},
+	"display.rotate_sessions": {
+		Comment: "Cycle through the live sessions instead of showing only the most recent one.\nEach session shows every rotation card before the next session.",
+	},
+	"display.rotation": {
+		Comment: "Cards the presence rotates through every rotation_seconds. Empty templates\nfall back to the [display] ones; details_no_branch and state_no_cost default\nto the card's details and state.\n[[display.rotation]]\ndetails = \"Working on: {project} ({branch})\"\nstate = \"{model} · ~{cost} API value\"\n\n[[display.rotation]]\nstate = \"{tokens} tokens · {turns} turns\"\n\n[[display.rotation]]\nstate = \"{agent_state} · {tool} {tool_target:basename}\"",
+	},
 	"display.buttons.custom_button_label": {
 		Comment: "Custom second button (optional). Both label and url must be set.",
 		Alternatives: []string{
@@ -98,6 +141,55 @@ var ConfigDocs = map[string]FieldDoc{
 		Comment: "Branches hidden when branch format is \"hide_default\".",
 	},
 
+	// ── Currency ─────────────────────────────────────────────────
+	"display.currency": {
+		Comment: "Display currency for costs. Costs are computed in USD and converted with the\nexchange rate below. Use {cost:usd} in a template to show the unconverted USD value.",
+	},
+	"display.currency.code": {
+		Comment: "ISO 4217 currency code.",
+		Alternatives: []string{
+			`code = "EUR"`,
+			`code = "GBP"`,
+		},
+	},
+	"display.currency.symbol": {
+		Comment: "Currency symbol. Empty derives it from the code (€, £, ¥, ...).",
+		Alternatives: []string{
+			`# symbol = "EUR "`,
+		},
+	},
+	"display.currency.symbol_position": {
+		Comment: "Where the symbol goes. Options: \"before\" ($1.23), \"after\" (1.23 €)",
+		Alternatives: []string{
+			`symbol_position = "after"`,
+		},
+	},
+	"display.currency.source": {
+		Comment: "Where to get the exchange rate (ignored for USD). Options: \"url\", \"file\", \"static\"\n  url: fetch daily reference rates (default https://api.frankfurter.app/latest?from=USD), cached locally\n  file: read a local JSON file: {\"base\": \"USD\", \"rates\": {\"EUR\": 0.92}}\n  static: use the fixed rate below",
+		Alternatives: []string{
+			`source = "file"`,
+			`source = "static"`,
+		},
+	},
+	"display.currency.rate": {
+		Comment: "Units of the display currency per USD (for source = \"static\").",
+		Alternatives: []string{
+			`rate = 0.92`,
+		},
+	},
+	"display.currency.file": {
+		Comment: "Local rate table path (for source = \"file\").",
+		Alternatives: []string{
+			`# file = "/path/to/rates.json"`,
+		},
+	},
+	"display.currency.url": {
+		Comment: "Custom rate table URL (for source = \"url\"). Must return USD-based rates.",
+		Alternatives: []string{
+			`# url = "https://open.er-api.com/v6/latest/USD"`,
+		},
+	},
+
 	// ── Timestamps ───────────────────────────────────────────────
 	"display.timestamps.mode": {
 		Comment: "What the elapsed timer tracks. Options: \"session\", \"elapsed\", \"none\"\n  session: resets when session_id changes (per Claude Code session)\n  elapsed: resets when daemon starts (persists across sessions)\n  none:    no timestamp shown",
@@ -106,6 +198,26 @@ var ConfigDocs = map[string]FieldDoc{
 			`mode = "none"`,
 		},
 	},
+	"display.timestamps.end_minutes": {
+		Comment: "Count down to this many minutes after the start instead of counting up\n(e.g. a 25 minute focus block). 0 = show elapsed time.",
+		Alternatives: []string{
+			`end_minutes = 25`,
+		},
+	},
+
+	// ── Party ────────────────────────────────────────────────────
+	"display.party.id": {
+		Comment: "Party ID. Empty uses a default.",
+		Alternatives: []string{
+			`id = "my-team"`,
+		},
+	},
+	"display.party.size": {
+		Comment: "Party shown as \"(size of max)\" after the state line. 0 = no party, except in\naggregate mode, where the size is the live session count.",
+	},
+	"display.party.max": {
+		Comment: "Maximum party size. Values below size show size.",
+	},
 
 	// ── Privacy ──────────────────────────────────────────────────
 	"privacy.hide_project_name": {
@@ -151,7 +263,13 @@ var ConfigDocs = map[string]FieldDoc{
 		Comment: "State line when idle (only used with idle_mode = \"idle_text\")",
 	},
 	"behavior.use_statusline": {
-		Comment: "Use Claude Code statusline instead of state.json for session data.\nRequires Claude Code v1.0.33+.",
+		Comment: "Read Claude Code's statusline payload (statusline.json, saved by\n`agentcord statusline`) as a session data source. It is merged into the hook\nstate of the same session and shown on its own when no hook state is newer.\nIts cost replaces the transcript estimate and it adds {lines_added},\n{lines_removed} and {model:display}. Requires Claude Code v1.0.33+.",
+	},
+	"behavior.aggregate_sessions": {
+		Comment: "Show every live session on one card instead of only the most recent one.\nA session is live while its marker in sessions/ was touched within\npresence_idle_minutes. Adds {session_count}, {total_cost} and {projects}, and\nshows the session count as Discord's party size (\"3 of 3\"), e.g.\nstate = \"{session_count} agents · {total_cost}\"",
+	},
+	"behavior.aggregate_party_max": {
+		Comment: "Maximum of the party size in aggregate mode (\"3 of N\"). 0 = the live session count.",
 	},
 	"behavior.show_tokens": {
 		Comment: "Show token count (used when cost is unavailable, or alongside cost)",
@@ -169,10 +287,74 @@ var ConfigDocs = map[string]FieldDoc{
 		Comment: "How often to poll for state changes (seconds). fsnotify is primary,\nthis is the fallback interval.",
 	},
 	"behavior.reconnect_interval_seconds": {
-		Comment: "Discord reconnect interval (seconds)",
+		Comment: "Seconds before the first retry when Discord is not running or the connection\nis lost. Doubles after each failed attempt, up to 5 minutes. The daemon keeps\nwaiting and also connects as soon as Discord's IPC socket appears.",
 	},
 	"behavior.session_cleanup_hours": {
-		Comment: "Remove orphaned session markers older than this many hours.\nOrphans appear when Claude Code exits without firing the stop hook.",
+		Comment: "Remove orphaned session markers and state files older than this many hours.\nOrphans appear when Claude Code exits without firing the stop hook.",
+	},
+	"behavior.pricing_refresh_minutes": {
+		Comment: "How often to re-fetch pricing data while the daemon runs (minutes). 0 = never.\nUnchanged sources are revalidated with a conditional request.",
+	},
+	"behavior.tiers_refresh_minutes": {
+		Comment: "How often to re-fetch model tier data while the daemon runs (minutes). 0 = never.",
+	},
+	"behavior.currency_refresh_minutes": {
+		Comment: "How often to re-fetch the display currency exchange rate (minutes). 0 = never.",
+	},
+	"behavior.transcript_roots": {
+		Comment: "Directories searched for session transcripts (tokens and cost), each holding\none directory per project as in ~/.claude/projects/<encoded-cwd>/<session>.jsonl.\nThe transcript path reported by the hook is used first when present.",
+	},
+
+	// ── Statusline ──────────────────────────────────────────────
+	"statusline": {
+		Comment: "Terminal statusline printed by `agentcord statusline`, which Claude Code's\nstatusLine setting runs. It also saves the payload for use_statusline.\nSegments use the same variables as [display] and are dropped when all of their\nvariables are empty, e.g. {context_pct} before the pricing data is cached.",
+	},
+	"statusline.segments": {
+		Alternatives: []string{
+			`segments = ["{project}", "{branch}", "{model:display}", "{cost}", "+{lines_added} -{lines_removed}"]`,
+		},
+	},
+	"statusline.separator": {},
+
+	// ── Budget ──────────────────────────────────────────────────
+	"budget": {
+		Comment: "API-value spending budgets in USD. 0 disables a budget.\nSpending is recorded in usage-ledger.jsonl, so totals survive daemon restarts.\nTemplate variables: {budget_used_pct}, {budget_remaining}, {budget_spent}, {budget_period}\nThey show the budget closest to its limit; add :daily, :weekly or :monthly to pick one.",
+	},
+	"budget.daily": {
+		Alternatives: []string{
+			`daily = 50.0`,
+		},
+	},
+	"budget.weekly":  {},
+	"budget.monthly": {},
+	"budget.warn_percent": {
+		Comment: "Percent of a budget at which the warning below replaces the normal card.",
+	},
+	"budget.warn_details": {
+		Comment: "Details line while over warn_percent. Empty keeps the normal details line.",
+		Alternatives: []string{
+			`# warn_details = "Working on: {project} · {budget_remaining} left today"`,
+		},
+	},
+	"budget.warn_state": {
+		Comment: "State line while over warn_percent. Empty keeps the normal state line.",
+	},
+	"budget.warn_small_image": {
+		Comment: "Small image key while over warn_percent (must be uploaded to your Discord app).",
+		Alternatives: []string{
+			`# warn_small_image = "budget_warning"`,
+		},
+	},
+	"budget.warn_small_text": {
+		Alternatives: []string{
+			`# warn_small_text = "{budget_used_pct} of {budget_period} budget"`,
+		},
+	},
+	"budget.alert_command": {
+		Comment: "Shell command run once per period when a budget crosses warn_percent, and again\nwhen it is exceeded. AGENTCORD_BUDGET_PERIOD, _LEVEL (warn, exceeded), _LIMIT,\n_SPENT and _USED_PCT are set in its environment.",
+		Alternatives: []string{
+			`# alert_command = "notify-send Agentcord \"$AGENTCORD_BUDGET_PERIOD budget at $AGENTCORD_BUDGET_USED_PCT%\""`,
+		},
 	},
 
 	// ── Pricing ─────────────────────────────────────────────────
@@ -203,7 +385,45 @@ var ConfigDocs = map[string]FieldDoc{
 		},
 	},
 	"pricing.models": {
-		Comment: "Inline prices (for source = \"static\").\n# [pricing.models.claude-opus-4-6]\n# input_per_token = 0.000015\n# output_per_token = 0.000075",
+		Comment: "Inline prices (for source = \"static\").\n# [pricing.models.claude-opus-4-6]\n# input_per_token = 0.000015\n# output_per_token = 0.000075\n# cache_write_5m_per_token = 0.00001875\n# cache_write_1h_per_token = 0.00003\n# cache_read_per_token = 0.0000015\n# context_window = 200000\n# Long-context rates for requests whose prompt exceeds a threshold:\n# [[pricing.models.claude-opus-4-6.bands]]\n# above_input_tokens = 200000\n# input_per_token = 0.00003\n# output_per_token = 0.0001125",
+	},
+	"pricing.aliases": {
+		Comment: "Map model IDs to pricing keys when automatic matching fails.\nProvider prefixes, dots vs dashes, and date suffixes are matched automatically.\nUnmatched models are logged and listed in pricing-diagnostics.json.\n# [pricing.aliases]\n# \"my-proxy-opus\" = \"claude-opus-4-6\"",
+	},
+
+	// ── Network ─────────────────────────────────────────────────
+	"network": {
+		Comment: "Settings shared by every outbound request (pricing, model tiers, update check).\nReading the GitHub repository from the local git remote never uses the network,\nso it keeps its own short timeout and also runs in offline mode.",
+	},
+	"network.offline": {
+		Comment: "Disable all network access. Pricing comes from the cache or static prices,\nand model tiers from the cache or the copy built into the binary.",
+		Alternatives: []string{
+			`offline = true`,
+		},
+	},
+	"network.proxy": {
+		Comment: "Proxy URL (http, https, socks5). Empty uses HTTP_PROXY / HTTPS_PROXY / NO_PROXY.",
+		Alternatives: []string{
+			`# proxy = "http://proxy.internal:3128"`,
+		},
+	},
+	"network.ca_bundle": {
+		Comment: "PEM file of extra CA certificates to trust (e.g. a corporate TLS proxy).",
+		Alternatives: []string{
+			`# ca_bundle = "/etc/ssl/certs/corp-ca.pem"`,
+		},
+	},
+	"network.user_agent": {
+		Comment: "User-Agent header. Empty uses \"agentcord/<version>\".",
+		Alternatives: []string{
+			`# user_agent = "agentcord (ops@example.com)"`,
+		},
+	},
+	"network.timeout_seconds": {
+		Comment: "Maximum time for a whole request, including the download (seconds).",
+	},
+	"network.connect_timeout_seconds": {
+		Comment: "Maximum time to connect and complete the TLS handshake (seconds).",
 	},
 
 	// ── Log ──────────────────────────────────────────────────────
@@ -221,11 +441,12 @@ var ConfigDocs = map[string]FieldDoc{
 		Comment: "Maximum log file size in megabytes before rotation.",
 	},
 	"clients": {
-		Comment: "Per-client display overrides keyed by client name (e.g. cursor, windsurf).",
+		Comment: "Per-client display overrides keyed by client name (e.g. cursor, windsurf).\nAlso: app_id, details, state, small_image, small_text, status_display,\ndetails_url, state_url, large_url, small_url, end_minutes, instance,\nparty_id, party_size, party_max.",
 		Alternatives: []string{
 			`[clients.cursor]`,
 			`large_image = "cursor"`,
 			`large_text = "Cursor"`,
+			`activity_type = "competing"`,
 		},
 	},
 }
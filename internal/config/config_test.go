@@ -69,6 +69,75 @@ daemon_idle_minutes = 60
 				}
 			},
 		},
+		{
+			name: "rotation cards",
+			config: `
+version = 2
+
+[display]
+rotation_seconds = 20
+
+[[display.rotation]]
+details = "{project}"
+
+[[display.rotation]]
+state = "{tokens} tokens"
+`,
+			check: func(t *testing.T, cfg *Config) {
+				t.Helper()
+				if cfg.Display.RotationSeconds != 20 {
+					t.Errorf("RotationSeconds = %d, want 20", cfg.Display.RotationSeconds)
+				}
+				if len(cfg.Display.Rotation) != 2 ||
+					cfg.Display.Rotation[0].Details != "{project}" ||
+					cfg.Display.Rotation[1].State != "{tokens} tokens" {
+					t.Errorf("Rotation = %+v, want the two configured cards", cfg.Display.Rotation)
+				}
+			},
+		},
+		{
+			name: "activity payload",
+			config: `
+version = 2
+
+[display]
+activity_type = "watching"
+status_display = "details"
+details_url = "https://github.com/{git_owner}/{git_repo}"
+
+[display.timestamps]
+end_minutes = 25
+
+[display.party]
+id = "team"
+size = 2
+max = 4
+
+[clients.cursor]
+activity_type = "competing"
+instance = true
+`,
+			check: func(t *testing.T, cfg *Config) {
+				t.Helper()
+				d := cfg.Display
+				if d.ActivityType != "watching" || d.StatusDisplay != "details" {
+					t.Errorf("ActivityType, StatusDisplay = %q, %q", d.ActivityType, d.StatusDisplay)
+				}
+				if d.DetailsURL != "https://github.com/{git_owner}/{git_repo}" {
+					t.Errorf("DetailsURL = %q", d.DetailsURL)
+				}
+				if d.Timestamps.EndMinutes != 25 {
+					t.Errorf("EndMinutes = %d, want 25", d.Timestamps.EndMinutes)
+				}
+				if d.Party != (PartyConfig{ID: "team", Size: 2, Max: 4}) {
+					t.Errorf("Party = %+v", d.Party)
+				}
+				cl := cfg.Clients["cursor"]
+				if cl.ActivityType != "competing" || cl.Instance == nil || !*cl.Instance {
+					t.Errorf("cursor override = %+v", cl)
+				}
+			},
+		},
 		{
 			name: "partial override preserves other defaults",
 			config: `
@@ -567,13 +636,13 @@ func TestLoad_Migration(t *testing.T) {
 			config: `
 [discord]
 app_id = "test"
-`, // version 0 (missing) -- should be normalized to 1
-			wantVersion: 1,
+`, // version 0 (missing) -- normalized to 1, then migrated
+			wantVersion: 2,
 		},
 		{
 			name:        "skips migration when current",
-			config:      "version = 1",
-			wantVersion: 1,
+			config:      "version = 2",
+			wantVersion: 2,
 		},
 	}
 
@@ -594,6 +663,30 @@ app_id = "test"
 	}
 }
 
+func TestLoad_MigrationDropsDollarBeforeCost(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, `version = 1
+[display]
+state = "{model} · ~${cost} API value"
+state_no_cost = "{model} · {tokens} tokens"
+details = "Opus ${cost:opus}"
+`)
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Display.State != "{model} · ~{cost} API value" {
+		t.Errorf("State = %q, want literal $ removed", cfg.Display.State)
+	}
+	if cfg.Display.Details != "Opus {cost:opus}" {
+		t.Errorf("Details = %q, want literal $ removed", cfg.Display.Details)
+	}
+	if cfg.Display.StateNoCost != "{model} · {tokens} tokens" {
+		t.Errorf("StateNoCost = %q, want unchanged", cfg.Display.StateNoCost)
+	}
+}
+
 // ///////////////////////////////////////////////
 // PeekVersion
 // ///////////////////////////////////////////////
@@ -636,8 +729,8 @@ func TestExampleConfig(t *testing.T) {
 		t.Fatal("ExampleConfig returned nil")
 		return
 	}
-	if cfg.Version != 1 {
-		t.Errorf("Version = %d, want 1", cfg.Version)
+	if cfg.Version != 2 {
+		t.Errorf("Version = %d, want 2", cfg.Version)
 	}
 	if cfg.Discord.AppID == "" {
 		t.Error("expected non-empty app_id")
@@ -892,6 +985,187 @@ func TestConfig_Validate(t *testing.T) {
 			setup:   func(cfg *Config) { cfg.Display.Format.Branch = "short" },
 			wantErr: true,
 		},
+		{
+			name:    "negative pricing_refresh_minutes",
+			setup:   func(cfg *Config) { cfg.Behavior.PricingRefreshMinutes = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "negative tiers_refresh_minutes",
+			setup:   func(cfg *Config) { cfg.Behavior.TiersRefreshMinutes = -1 },
+			wantErr: true,
+		},
+		{
+			name: "pricing band without threshold",
+			setup: func(cfg *Config) {
+				cfg.Pricing.Models = map[string]PricingModelConfig{
+					"claude-opus-4-6": {Bands: []PricingBandConfig{{InputPerToken: 0.00003}}},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "negative currency_refresh_minutes",
+			setup:   func(cfg *Config) { cfg.Behavior.CurrencyRefreshMinutes = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "invalid currency.code",
+			setup:   func(cfg *Config) { cfg.Display.Currency.Code = "EURO" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid currency.symbol_position",
+			setup:   func(cfg *Config) { cfg.Display.Currency.SymbolPosition = "middle" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid currency.source",
+			setup:   func(cfg *Config) { cfg.Display.Currency.Source = "api" },
+			wantErr: true,
+		},
+		{
+			name: "static currency without rate",
+			setup: func(cfg *Config) {
+				cfg.Display.Currency.Code = "EUR"
+				cfg.Display.Currency.Source = "static"
+			},
+			wantErr: true,
+		},
+		{
+			name: "static currency with rate",
+			setup: func(cfg *Config) {
+				cfg.Display.Currency.Code = "EUR"
+				cfg.Display.Currency.Source = "static"
+				cfg.Display.Currency.Rate = 0.92
+			},
+			wantErr: false,
+		},
+		{
+			name:    "static USD without rate",
+			setup:   func(cfg *Config) { cfg.Display.Currency.Source = "static" },
+			wantErr: false,
+		},
+		{
+			name:    "network proxy with socks5 scheme",
+			setup:   func(cfg *Config) { cfg.Network.Proxy = "socks5://127.0.0.1:1080" },
+			wantErr: false,
+		},
+		{
+			name:    "network proxy without host",
+			setup:   func(cfg *Config) { cfg.Network.Proxy = "proxy.internal" },
+			wantErr: true,
+		},
+		{
+			name:    "network proxy with unsupported scheme",
+			setup:   func(cfg *Config) { cfg.Network.Proxy = "ftp://proxy.internal:21" },
+			wantErr: true,
+		},
+		{
+			name:    "network.timeout_seconds = 0",
+			setup:   func(cfg *Config) { cfg.Network.TimeoutSeconds = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "network.connect_timeout_seconds = 0",
+			setup:   func(cfg *Config) { cfg.Network.ConnectTimeoutSeconds = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "negative daily budget",
+			setup:   func(cfg *Config) { cfg.Budget.Daily = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "budget.warn_percent > 100",
+			setup:   func(cfg *Config) { cfg.Budget.WarnPercent = 150 },
+			wantErr: true,
+		},
+		{
+			name:    "monthly budget with warn_percent 0",
+			setup:   func(cfg *Config) { cfg.Budget.Monthly = 200; cfg.Budget.WarnPercent = 0 },
+			wantErr: false,
+		},
+		{
+			name:    "rotation_seconds below the minimum",
+			setup:   func(cfg *Config) { cfg.Display.RotationSeconds = 5 },
+			wantErr: true,
+		},
+		{
+			name:    "negative rotation_seconds",
+			setup:   func(cfg *Config) { cfg.Display.RotationSeconds = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "rotation_seconds at the minimum",
+			setup:   func(cfg *Config) { cfg.Display.RotationSeconds = MinRotationSeconds },
+			wantErr: false,
+		},
+		{
+			name:    "invalid activity_type",
+			setup:   func(cfg *Config) { cfg.Display.ActivityType = "streaming" },
+			wantErr: true,
+		},
+		{
+			name:    "invalid status_display",
+			setup:   func(cfg *Config) { cfg.Display.StatusDisplay = "project" },
+			wantErr: true,
+		},
+		{
+			name:    "details_url template",
+			setup:   func(cfg *Config) { cfg.Display.DetailsURL = "https://github.com/{git_owner}/{git_repo}" },
+			wantErr: false,
+		},
+		{
+			name:    "details_url without scheme",
+			setup:   func(cfg *Config) { cfg.Display.DetailsURL = "github.com/{git_owner}" },
+			wantErr: true,
+		},
+		{
+			name:    "large_url with unsupported scheme",
+			setup:   func(cfg *Config) { cfg.Display.Assets.LargeURL = "ftp://example.com" },
+			wantErr: true,
+		},
+		{
+			name:    "negative end_minutes",
+			setup:   func(cfg *Config) { cfg.Display.Timestamps.EndMinutes = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "negative party size",
+			setup:   func(cfg *Config) { cfg.Display.Party.Size = -1 },
+			wantErr: true,
+		},
+		{
+			name: "valid client overrides",
+			setup: func(cfg *Config) {
+				cfg.Clients = map[string]ClientConfig{
+					"cursor": {ActivityType: "competing", StatusDisplay: "details", StateURL: "https://cursor.com"},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid client activity_type",
+			setup: func(cfg *Config) {
+				cfg.Clients = map[string]ClientConfig{"cursor": {ActivityType: "custom"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid client url",
+			setup: func(cfg *Config) {
+				cfg.Clients = map[string]ClientConfig{"cursor": {SmallURL: "cursor.com"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative client party_max",
+			setup: func(cfg *Config) {
+				cfg.Clients = map[string]ClientConfig{"cursor": {PartyMax: -1}}
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -919,6 +1193,13 @@ func TestConfig_Validate_EnumPositive(t *testing.T) {
 		{name: "timestamps.mode session", setup: func(cfg *Config) { cfg.Display.Timestamps.Mode = "session" }},
 		{name: "timestamps.mode elapsed", setup: func(cfg *Config) { cfg.Display.Timestamps.Mode = "elapsed" }},
 		{name: "timestamps.mode none", setup: func(cfg *Config) { cfg.Display.Timestamps.Mode = "none" }},
+		// activity_type
+		{name: "activity_type listening", setup: func(cfg *Config) { cfg.Display.ActivityType = "listening" }},
+		{name: "activity_type watching", setup: func(cfg *Config) { cfg.Display.ActivityType = "watching" }},
+		{name: "activity_type competing", setup: func(cfg *Config) { cfg.Display.ActivityType = "competing" }},
+		// status_display
+		{name: "status_display state", setup: func(cfg *Config) { cfg.Display.StatusDisplay = "state" }},
+		{name: "status_display details", setup: func(cfg *Config) { cfg.Display.StatusDisplay = "details" }},
 		// format.branch
 		{name: "format.branch show", setup: func(cfg *Config) { cfg.Display.Format.Branch = "show" }},
 		{name: "format.branch hide", setup: func(cfg *Config) { cfg.Display.Format.Branch = "hide" }},
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"bytes"
+
+	"tools.zach/dev/agentcord/internal/migrate"
+)
+
+// ///////////////////////////////////////////////
+// Config Migrations
+// ///////////////////////////////////////////////
+
+func init() {
+	migrate.Config.Register(migrate.Migration{
+		Version:     2,
+		Description: "drop literal $ before {cost}, which now renders its own currency symbol",
+		Upgrade:     dropDollarBeforeCost,
+	})
+}
+
+// dropDollarBeforeCost rewrites "${cost" to "{cost" in templates. {cost}
+// always rendered a "$" prefix, so templates written as "${cost}" showed
+// "$$1.23", and would show "$€1.23" once a display currency is configured.
+func dropDollarBeforeCost(data []byte) ([]byte, error) {
+	return bytes.ReplaceAll(data, []byte("${cost"), []byte("{cost")), nil
+}
@@ -0,0 +1,316 @@
+// Package currency converts USD costs into a display currency.
+//
+// Costs are always computed in USD. The exchange rate to the display currency
+// comes from one of three source types: a static rate defined in config, a
+// local JSON file, or a remote URL. File and URL sources use the same
+// double-fallback strategy as pricing: primary source, then the on-disk cache.
+// URL sources are revalidated with conditional requests and are read only
+// from the cache in offline mode.
+//
+// File and URL sources hold a rate table in the common exchange-rate API
+// shape, as served by Frankfurter and open.er-api.com:
+//
+//	{"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.79}}
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/httpfetch"
+	"tools.zach/dev/agentcord/internal/netclient"
+	"tools.zach/dev/agentcord/internal/paths"
+)
+
+// DefaultURL is the exchange rate endpoint used when source is "url" and no
+// URL is configured. It serves European Central Bank reference rates.
+const DefaultURL = "https://api.frankfurter.app/latest?from=USD"
+
+// maxResponseBytes caps the size of a rate table download.
+const maxResponseBytes = 1 << 20 // 1 MiB
+
+// ///////////////////////////////////////////////
+// Types
+// ///////////////////////////////////////////////
+
+// SourceConfig describes the display currency and where its rate comes from.
+type SourceConfig struct {
+	// Code is the ISO 4217 code of the display currency (e.g. "EUR").
+	Code string
+	// Source selects the rate source: "static", "file", or "url".
+	Source string
+	// Rate is the number of Code units per USD for source "static".
+	Rate float64
+	// File is the rate table path for source "file".
+	File string
+	// URL is the rate table endpoint for source "url". Empty uses [DefaultURL].
+	URL string
+}
+
+// Rate is a resolved exchange rate.
+type Rate struct {
+	// Code is the ISO 4217 code of the display currency.
+	Code string
+	// PerUSD is the number of Code units per USD.
+	PerUSD float64
+}
+
+// Table is an exchange rate table as stored in rate files and the cache.
+type Table struct {
+	// Base is the currency the rates are quoted against. Empty means USD.
+	Base string `json:"base,omitempty"`
+	// Rates maps ISO 4217 codes to units per Base.
+	Rates map[string]float64 `json:"rates"`
+}
+
+// lookup returns the rate for code from t. The table must be quoted in USD.
+func (t *Table) lookup(code string) (*Rate, error) {
+	if t.Base != "" && !strings.EqualFold(t.Base, "USD") {
+		return nil, fmt.Errorf("rate table is quoted in %s, want USD", t.Base)
+	}
+	r, ok := t.Rates[strings.ToUpper(code)]
+	if !ok || r <= 0 {
+		return nil, fmt.Errorf("rate table has no rate for %s", code)
+	}
+	return &Rate{Code: strings.ToUpper(code), PerUSD: r}, nil
+}
+
+// ///////////////////////////////////////////////
+// Public API
+// ///////////////////////////////////////////////
+
+// Fetch resolves the exchange rate for src.
+//
+// USD always resolves to 1 without touching any source. For "static" the
+// configured rate is returned directly. For "file" and "url" sources the
+// primary source is tried first, then the cache; the returned error is
+// non-nil when the rate came from the cache fallback. In offline mode "url"
+// sources are served from the cache without an error.
+//
+// Returns nil with an error when no rate is available.
+func Fetch(src SourceConfig, cacheDir string) (*Rate, error) {
+	if isUSD(src.Code) {
+		return &Rate{Code: "USD", PerUSD: 1}, nil
+	}
+	switch src.Source {
+	case "static":
+		if src.Rate <= 0 {
+			return nil, fmt.Errorf("static rate for %s must be > 0", src.Code)
+		}
+		return &Rate{Code: strings.ToUpper(src.Code), PerUSD: src.Rate}, nil
+	case "file":
+		return withFallback(src.Code, cacheDir, func() (*Table, httpfetch.Meta, error) {
+			t, err := readTable(src.File)
+			return t, httpfetch.Meta{FetchedAt: time.Now(), SourceURL: src.File}, err
+		})
+	default: // "url"
+		if netclient.Offline() {
+			return ReadCache(cacheDir, src.Code)
+		}
+		return withFallback(src.Code, cacheDir, func() (*Table, httpfetch.Meta, error) {
+			return fetchTable(sourceURL(src), httpfetch.Meta{})
+		})
+	}
+}
+
+// Refresh re-fetches the rate for a long-running daemon. URL sources are
+// revalidated with the ETag and Last-Modified values recorded by the previous
+// fetch; file sources are re-read only when the file changed since then.
+//
+// Returns nil and a nil error when the source is unchanged, static, USD, or a
+// "url" source in offline mode. On error the caller should keep its current rate.
+func Refresh(src SourceConfig, cacheDir string) (*Rate, error) {
+	if isUSD(src.Code) || src.Source == "static" {
+		return nil, nil
+	}
+	prev, _ := httpfetch.ReadMeta(filepath.Join(cacheDir, paths.CurrencyCacheMetaFile))
+
+	var t *Table
+	switch src.Source {
+	case "file":
+		info, err := os.Stat(src.File)
+		if err != nil {
+			return nil, fmt.Errorf("stat rate file %s: %w", src.File, err)
+		}
+		if prev.SourceURL == src.File && !info.ModTime().After(prev.FetchedAt) {
+			return nil, nil
+		}
+		if t, err = readTable(src.File); err != nil {
+			return nil, err
+		}
+		prev = httpfetch.Meta{FetchedAt: time.Now(), SourceURL: src.File}
+	default: // "url"
+		if netclient.Offline() {
+			return nil, nil
+		}
+		var err error
+		if t, prev, err = fetchTable(sourceURL(src), prev); err != nil {
+			return nil, err
+		}
+		if t == nil {
+			writeMeta(cacheDir, prev)
+			return nil, nil
+		}
+	}
+
+	rate, err := t.lookup(src.Code)
+	if err != nil {
+		return nil, err
+	}
+	writeCache(cacheDir, t)
+	writeMeta(cacheDir, prev)
+	return rate, nil
+}
+
+// ReadLocal resolves the rate for src without network access: USD and static
+// rates directly, file and URL sources from the cache only.
+func ReadLocal(src SourceConfig, cacheDir string) (*Rate, error) {
+	if isUSD(src.Code) || src.Source == "static" {
+		return Fetch(src, cacheDir)
+	}
+	return ReadCache(cacheDir, src.Code)
+}
+
+// ReadCache resolves the rate for code from the on-disk cache only.
+func ReadCache(cacheDir, code string) (*Rate, error) {
+	t, err := readTable(filepath.Join(cacheDir, paths.CurrencyCacheFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading rate cache: %w", err)
+	}
+	return t.lookup(code)
+}
+
+// symbols maps common ISO 4217 codes to their display symbols.
+var symbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+	"BRL": "R$",
+	"CAD": "CA$",
+	"AUD": "A$",
+	"NZD": "NZ$",
+	"CHF": "CHF",
+	"SEK": "kr",
+	"NOK": "kr",
+	"DKK": "kr",
+	"PLN": "zł",
+	"TRY": "₺",
+	"ILS": "₪",
+	"UAH": "₴",
+}
+
+// Symbol returns the display symbol for code, or the code itself when the
+// currency has no well-known symbol.
+func Symbol(code string) string {
+	code = strings.ToUpper(code)
+	if s, ok := symbols[code]; ok {
+		return s
+	}
+	return code
+}
+
+// ///////////////////////////////////////////////
+// Internal helpers
+// ///////////////////////////////////////////////
+
+// isUSD reports whether code names US dollars. Empty defaults to USD.
+func isUSD(code string) bool {
+	return code == "" || strings.EqualFold(code, "USD")
+}
+
+// sourceURL returns the rate URL for src, falling back to [DefaultURL].
+func sourceURL(src SourceConfig) string {
+	if src.URL != "" {
+		return src.URL
+	}
+	return DefaultURL
+}
+
+// withFallback resolves code from the table returned by primary, updating
+// the cache on success. Falls back to the cache when the primary fetch fails
+// or the table lacks the currency.
+func withFallback(code, cacheDir string, primary func() (*Table, httpfetch.Meta, error)) (*Rate, error) {
+	t, meta, err := primary()
+	if err == nil {
+		var rate *Rate
+		if rate, err = t.lookup(code); err == nil {
+			writeCache(cacheDir, t)
+			writeMeta(cacheDir, meta)
+			return rate, nil
+		}
+	}
+	slog.Warn("failed to fetch exchange rate from primary source, trying cache", "error", err)
+
+	rate, cacheErr := ReadCache(cacheDir, code)
+	if cacheErr == nil {
+		return rate, fmt.Errorf("using cached exchange rate: primary fetch failed: %w", err)
+	}
+	return nil, fmt.Errorf("all exchange rate sources failed: primary: %w; cache: %w", err, cacheErr)
+}
+
+// fetchTable downloads a rate table with the shared [netclient.Client],
+// revalidating against prev. Returns a nil table with the refreshed metadata
+// when the server reports the cached copy is current.
+func fetchTable(url string, prev httpfetch.Meta) (*Table, httpfetch.Meta, error) {
+	res, err := httpfetch.Get(netclient.Client(), url, prev, maxResponseBytes)
+	if err != nil {
+		return nil, httpfetch.Meta{}, err
+	}
+	if res.NotModified {
+		return nil, res.Meta, nil
+	}
+	t, err := parseTable(res.Body)
+	return t, res.Meta, err
+}
+
+// readTable reads and parses a rate table from path.
+func readTable(path string) (*Table, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rate file %s: %w", path, err)
+	}
+	return parseTable(b)
+}
+
+// parseTable parses a rate table. A table without rates is an error.
+func parseTable(body []byte) (*Table, error) {
+	var t Table
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, fmt.Errorf("parsing rate table: %w", err)
+	}
+	if len(t.Rates) == 0 {
+		return nil, fmt.Errorf("rate table has no rates")
+	}
+	return &t, nil
+}
+
+// writeCache persists t as the rate cache. Failures are logged; they only
+// cost the fallback when the source is next unavailable.
+func writeCache(cacheDir string, t *Table) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		slog.Debug("failed to marshal rate table for cache", "error", err)
+		return
+	}
+	if err := atomicfile.Write(filepath.Join(cacheDir, paths.CurrencyCacheFile), b, 0o644); err != nil {
+		slog.Debug("failed to write rate cache", "error", err)
+	}
+}
+
+// writeMeta persists fetch metadata next to the rate cache so the next
+// [Refresh] can send a conditional request.
+func writeMeta(cacheDir string, m httpfetch.Meta) {
+	if err := httpfetch.WriteMeta(filepath.Join(cacheDir, paths.CurrencyCacheMetaFile), m); err != nil {
+		slog.Debug("failed to write rate fetch metadata", "error", err)
+	}
+}
@@ -0,0 +1,203 @@
+package currency
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/netclient"
+)
+
+// ///////////////////////////////////////////////
+// Fetch Tests
+// ///////////////////////////////////////////////
+
+func TestFetch_USDIsIdentity(t *testing.T) {
+	for _, code := range []string{"", "USD", "usd"} {
+		rate, err := Fetch(SourceConfig{Code: code, Source: "url", URL: "http://127.0.0.1:0/"}, t.TempDir())
+		if err != nil || rate.PerUSD != 1 || rate.Code != "USD" {
+			t.Errorf("Fetch(%q) = (%+v, %v), want USD at 1", code, rate, err)
+		}
+	}
+}
+
+func TestFetch_Static(t *testing.T) {
+	rate, err := Fetch(SourceConfig{Code: "eur", Source: "static", Rate: 0.92}, t.TempDir())
+	if err != nil || rate.PerUSD != 0.92 || rate.Code != "EUR" {
+		t.Errorf("Fetch static = (%+v, %v), want EUR at 0.92", rate, err)
+	}
+	if rate, err := Fetch(SourceConfig{Code: "EUR", Source: "static"}, t.TempDir()); err == nil {
+		t.Errorf("Fetch static without rate = %+v, want error", rate)
+	}
+}
+
+func TestFetch_File(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rates.json")
+	os.WriteFile(file, []byte(`{"base":"USD","rates":{"EUR":0.92,"GBP":0.79}}`), 0o644)
+
+	rate, err := Fetch(SourceConfig{Code: "GBP", Source: "file", File: file}, dir)
+	if err != nil || math.Abs(rate.PerUSD-0.79) > 1e-12 {
+		t.Fatalf("Fetch file = (%+v, %v), want GBP at 0.79", rate, err)
+	}
+
+	// A currency missing from the table falls back to the cache written above.
+	os.WriteFile(file, []byte(`{"rates":{"EUR":0.93}}`), 0o644)
+	rate, err = Fetch(SourceConfig{Code: "GBP", Source: "file", File: file}, dir)
+	if err == nil || rate == nil || math.Abs(rate.PerUSD-0.79) > 1e-12 {
+		t.Errorf("Fetch with missing currency = (%+v, %v), want cached GBP with fallback error", rate, err)
+	}
+}
+
+func TestFetch_RejectsNonUSDBase(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rates.json")
+	os.WriteFile(file, []byte(`{"base":"EUR","rates":{"GBP":0.85}}`), 0o644)
+
+	if rate, err := Fetch(SourceConfig{Code: "GBP", Source: "file", File: file}, dir); rate != nil || err == nil {
+		t.Errorf("Fetch EUR-based table = (%+v, %v), want (nil, error)", rate, err)
+	}
+}
+
+func TestFetch_URLFallsBackToCache(t *testing.T) {
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2026-10-15","rates":{"EUR":0.92}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	src := SourceConfig{Code: "EUR", Source: "url", URL: server.URL}
+	if rate, err := Fetch(src, dir); err != nil || rate.PerUSD != 0.92 {
+		t.Fatalf("Fetch url = (%+v, %v), want EUR at 0.92", rate, err)
+	}
+
+	fail = true
+	rate, err := Fetch(src, dir)
+	if err == nil || rate == nil || rate.PerUSD != 0.92 {
+		t.Errorf("Fetch after failure = (%+v, %v), want cached rate with fallback error", rate, err)
+	}
+}
+
+func TestFetch_Offline(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	if err := netclient.Configure(netclient.Settings{Offline: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer netclient.Configure(netclient.Settings{})
+
+	dir := t.TempDir()
+	src := SourceConfig{Code: "EUR", Source: "url", URL: server.URL}
+	if rate, err := Fetch(src, dir); rate != nil || err == nil {
+		t.Errorf("Fetch offline without cache = (%+v, %v), want (nil, error)", rate, err)
+	}
+	writeCache(dir, &Table{Rates: map[string]float64{"EUR": 0.9}})
+	if rate, err := Fetch(src, dir); err != nil || rate.PerUSD != 0.9 {
+		t.Errorf("Fetch offline with cache = (%+v, %v), want cached rate", rate, err)
+	}
+	if rate, err := Refresh(src, dir); rate != nil || err != nil {
+		t.Errorf("Refresh offline = (%+v, %v), want (nil, nil)", rate, err)
+	}
+	if called {
+		t.Error("rate URL fetched in offline mode")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Refresh Tests
+// ///////////////////////////////////////////////
+
+func TestRefresh_URLConditional(t *testing.T) {
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"r1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"r1"`)
+		w.Write([]byte(`{"base":"USD","rates":{"EUR":0.92}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	src := SourceConfig{Code: "EUR", Source: "url", URL: server.URL}
+	if _, err := Fetch(src, dir); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	rate, err := Refresh(src, dir)
+	if err != nil || rate != nil {
+		t.Errorf("Refresh unchanged = (%+v, %v), want (nil, nil)", rate, err)
+	}
+	if conditional != 1 {
+		t.Errorf("conditional requests = %d, want 1", conditional)
+	}
+}
+
+func TestRefresh_FileOnlyWhenModified(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rates.json")
+	os.WriteFile(file, []byte(`{"rates":{"EUR":0.92}}`), 0o644)
+	src := SourceConfig{Code: "EUR", Source: "file", File: file}
+
+	if _, err := Fetch(src, dir); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if rate, err := Refresh(src, dir); rate != nil || err != nil {
+		t.Errorf("Refresh unmodified = (%+v, %v), want (nil, nil)", rate, err)
+	}
+
+	os.WriteFile(file, []byte(`{"rates":{"EUR":0.95}}`), 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(file, future, future)
+
+	rate, err := Refresh(src, dir)
+	if err != nil || rate == nil || rate.PerUSD != 0.95 {
+		t.Errorf("Refresh modified = (%+v, %v), want EUR at 0.95", rate, err)
+	}
+}
+
+func TestRefresh_StaticAndUSDAreNoops(t *testing.T) {
+	for _, src := range []SourceConfig{
+		{Code: "EUR", Source: "static", Rate: 0.9},
+		{Code: "USD", Source: "url", URL: "http://127.0.0.1:0/"},
+	} {
+		if rate, err := Refresh(src, t.TempDir()); rate != nil || err != nil {
+			t.Errorf("Refresh(%+v) = (%+v, %v), want (nil, nil)", src, rate, err)
+		}
+	}
+}
+
+// ///////////////////////////////////////////////
+// Symbol Tests
+// ///////////////////////////////////////////////
+
+func TestSymbol(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"USD", "$"},
+		{"eur", "€"},
+		{"GBP", "£"},
+		{"XYZ", "XYZ"},
+	}
+	for _, tt := range tests {
+		if got := Symbol(tt.code); got != tt.want {
+			t.Errorf("Symbol(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 // Package discord provides a client for Discord's local IPC socket,
 // enabling Rich Presence updates via the SET_ACTIVITY command.
 //
-// The [Client] type manages connection lifecycle and command framing.
+// The [Client] type manages connection lifecycle and command framing; a
+// reader goroutine matches Discord's responses to commands by nonce.
 // Platform-specific socket discovery is handled by conn_unix.go and
 // conn_windows.go.
 package discord
@@ -33,28 +34,113 @@ type Button struct {
 	URL   string `json:"url"`
 }
 
-// Timestamps holds the start timestamp for an activity.
+// Timestamps holds the start and end timestamps for an activity, in Unix
+// seconds. With an end, Discord counts down (or shows a progress bar for
+// [ActivityListening]) instead of counting up from start.
 type Timestamps struct {
 	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
 }
 
-// Assets holds image keys and tooltip text for an activity.
+// Assets holds image keys, tooltip text and links for an activity. The URLs
+// are opened when the matching image is clicked.
 type Assets struct {
 	LargeImage string `json:"large_image,omitempty"`
 	LargeText  string `json:"large_text,omitempty"`
+	LargeURL   string `json:"large_url,omitempty"`
 	SmallImage string `json:"small_image,omitempty"`
 	SmallText  string `json:"small_text,omitempty"`
+	SmallURL   string `json:"small_url,omitempty"`
 }
 
+// Party holds the party of an activity. Size is [current, max] and is shown
+// as "(current of max)" after the state line.
+type Party struct {
+	ID   string `json:"id,omitempty"`
+	Size []int  `json:"size,omitempty"`
+}
+
+// ActivityType is the verb Discord shows before the application name
+// (e.g. "Playing", "Watching"). Streaming and Custom cannot be set over IPC.
+type ActivityType int
+
+const (
+	// ActivityPlaying shows "Playing {name}". The zero value.
+	ActivityPlaying ActivityType = 0
+	// ActivityListening shows "Listening to {name}".
+	ActivityListening ActivityType = 2
+	// ActivityWatching shows "Watching {name}".
+	ActivityWatching ActivityType = 3
+	// ActivityCompeting shows "Competing in {name}".
+	ActivityCompeting ActivityType = 5
+)
+
+// StatusDisplayType selects which activity field Discord shows in the
+// member list and profile status line.
+type StatusDisplayType int
+
+const (
+	// StatusDisplayName shows the application name. The zero value.
+	StatusDisplayName StatusDisplayType = 0
+	// StatusDisplayState shows the state line.
+	StatusDisplayState StatusDisplayType = 1
+	// StatusDisplayDetails shows the details line.
+	StatusDisplayDetails StatusDisplayType = 2
+)
+
 // Activity represents a Discord Rich Presence activity.
 type Activity struct {
-	Details    string      `json:"details,omitempty"`
-	State      string      `json:"state,omitempty"`
-	Timestamps *Timestamps `json:"timestamps,omitempty"`
-	Assets     *Assets     `json:"assets,omitempty"`
-	Buttons    []Button    `json:"buttons,omitempty"`
+	Type              ActivityType      `json:"type,omitempty"`
+	StatusDisplayType StatusDisplayType `json:"status_display_type,omitempty"`
+	Details           string            `json:"details,omitempty"`
+	DetailsURL        string            `json:"details_url,omitempty"`
+	State             string            `json:"state,omitempty"`
+	StateURL          string            `json:"state_url,omitempty"`
+	Timestamps        *Timestamps       `json:"timestamps,omitempty"`
+	Assets            *Assets           `json:"assets,omitempty"`
+	Buttons           []Button          `json:"buttons,omitempty"`
+	Party             *Party            `json:"party,omitempty"`
+	Instance          bool              `json:"instance,omitempty"`
 }
 
+// ///////////////////////////////////////////////
+// Connection Events
+// ///////////////////////////////////////////////
+
+// ConnState is the state of a [Client]'s connection.
+type ConnState int
+
+const (
+	// StateDisconnected means the connection was lost: Discord closed it,
+	// quit or restarted. Reconnect with [Client.Connect].
+	StateDisconnected ConnState = iota
+	// StateConnected means a connection was established and the handshake
+	// completed.
+	StateConnected
+)
+
+// String returns the state's name.
+func (s ConnState) String() string {
+	if s == StateConnected {
+		return "connected"
+	}
+	return "disconnected"
+}
+
+// ConnEvent reports a change of a [Client]'s connection state.
+type ConnEvent struct {
+	// State is the new connection state.
+	State ConnState
+	// Err is why the connection was lost, for [StateDisconnected]: io.EOF
+	// when Discord went away, or an [ErrClosedByDiscord] error.
+	Err error
+}
+
+// eventBuffer is the number of connection events buffered for a receiver
+// that is not keeping up. Further events are dropped; [Client.Connected]
+// always reports the current state.
+const eventBuffer = 8
+
 // ///////////////////////////////////////////////
 // Client
 // ///////////////////////////////////////////////
@@ -64,17 +150,31 @@ type Client struct {
 	// appID is the Discord application (OAuth2 client) identifier.
 	appID string
 
-	// mu protects conn and nonce from concurrent access.
+	// mu protects conn, reader and nonce from concurrent access.
 	mu sync.Mutex
 	// conn is the active IPC socket connection, or nil when disconnected.
 	conn net.Conn
-	// nonce is a monotonically increasing counter used to tag each command frame.
+	// reader reads the responses on conn, or is nil when disconnected.
+	reader *reader
+	// nonce is a monotonically increasing counter used to tag each command
+	// frame and match it to its response.
 	nonce uint64
+
+	// events delivers connection state changes. See [Client.Events].
+	events chan ConnEvent
 }
 
 // NewClient creates a new Discord IPC client for the given application ID.
 func NewClient(appID string) *Client {
-	return &Client{appID: appID}
+	return &Client{appID: appID, events: make(chan ConnEvent, eventBuffer)}
+}
+
+// Events returns the channel connection state changes are delivered on: a
+// [StateConnected] event for each successful [Client.Connect] and a
+// [StateDisconnected] event when the connection is lost. Closing the client
+// with [Client.Close] sends no event.
+func (c *Client) Events() <-chan ConnEvent {
+	return c.events
 }
 
 // Connect establishes a connection to Discord via IPC and sends the handshake.
@@ -86,6 +186,7 @@ func (c *Client) Connect() error {
 	if c.conn != nil {
 		c.conn.Close()
 		c.conn = nil
+		c.reader = nil
 	}
 
 	conn, err := connectToDiscord()
@@ -99,26 +200,24 @@ func (c *Client) Connect() error {
 		c.conn = nil
 		return err
 	}
+	c.startReader()
+	c.emit(ConnEvent{State: StateConnected})
 	return nil
 }
 
-// SetActivity sends a SET_ACTIVITY command to Discord.
+// SetActivity sends a SET_ACTIVITY command to Discord and waits for the
+// response. Returns a [*CommandError] when Discord rejects the activity.
 func (c *Client) SetActivity(activity *Activity) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.sendCommand("SET_ACTIVITY", map[string]any{
+	return c.command("SET_ACTIVITY", map[string]any{
 		"pid":      os.Getpid(),
 		"activity": activity,
 	})
 }
 
-// ClearActivity sends a SET_ACTIVITY command with a nil activity.
+// ClearActivity sends a SET_ACTIVITY command with a nil activity and waits
+// for the response.
 func (c *Client) ClearActivity() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	return c.sendCommand("SET_ACTIVITY", map[string]any{
+	return c.command("SET_ACTIVITY", map[string]any{
 		"pid":      os.Getpid(),
 		"activity": nil,
 	})
@@ -133,18 +232,21 @@ func (c *Client) Close() error {
 		return nil
 	}
 
-	// Best-effort clear before closing.
-	_ = c.sendCommand("SET_ACTIVITY", map[string]any{
+	// Best-effort clear before closing, without waiting for the response.
+	_, _ = c.sendCommand("SET_ACTIVITY", map[string]any{
 		"pid":      os.Getpid(),
 		"activity": nil,
 	})
 
 	err := c.conn.Close()
 	c.conn = nil
+	c.reader = nil
 	return err
 }
 
-// Connected reports whether the client has an active connection.
+// Connected reports whether the client has an active connection. It turns
+// false as soon as the connection is lost, without waiting for a write to
+// fail.
 func (c *Client) Connected() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -190,11 +292,83 @@ func (c *Client) handshake() error {
 	return nil
 }
 
-// sendCommand writes a command frame to the IPC connection.
-// The caller must hold c.mu.
-func (c *Client) sendCommand(cmd string, args map[string]any) error {
-	if c.conn == nil {
-		return ErrNotConnected
+// startReader starts the goroutine reading responses on c.conn. Called once
+// the handshake has completed. The caller must hold c.mu.
+func (c *Client) startReader() {
+	r := newReader()
+	r.ping = func(payload []byte) { c.pong(r, payload) }
+	r.stopped = func(err error) { c.lost(r, err) }
+	c.reader = r
+	go r.run(c.conn)
+}
+
+// pong answers a PING received by r, unless r's connection has since been
+// replaced or closed.
+func (c *Client) pong(r *reader, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reader != r {
+		return
+	}
+	frame, err := EncodeFrame(OpPong, payload)
+	if err != nil {
+		return
+	}
+	// A failed write surfaces as a read error on the same connection.
+	_, _ = c.conn.Write(frame)
+}
+
+// lost handles the connection of r ending with err: the connection is
+// dropped and a [StateDisconnected] event sent. Nothing happens when the
+// connection was already replaced or closed by the client.
+func (c *Client) lost(r *reader, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reader != r {
+		return
+	}
+	c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+	c.emit(ConnEvent{State: StateDisconnected, Err: err})
+}
+
+// emit delivers ev on the events channel, dropping it when the buffer is full.
+func (c *Client) emit(ev ConnEvent) {
+	select {
+	case c.events <- ev:
+	default:
+	}
+}
+
+// command sends a command and waits up to [responseTimeout] for Discord's
+// response. c.mu is only held while writing, so a slow response does not
+// block other callers.
+func (c *Client) command(cmd string, args map[string]any) error {
+	c.mu.Lock()
+	r := c.reader
+	sent, err := c.sendCommand(cmd, args)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return r.await(sent.response, sent.nonce, responseTimeout)
+}
+
+// sentCommand is a command written to the connection and awaiting its
+// response.
+type sentCommand struct {
+	// nonce tags the command and its response.
+	nonce string
+	// response receives Discord's response.
+	response <-chan *response
+}
+
+// sendCommand writes a command frame to the IPC connection and registers it
+// with the reader so its response can be awaited. The caller must hold c.mu.
+func (c *Client) sendCommand(cmd string, args map[string]any) (sentCommand, error) {
+	if c.conn == nil || c.reader == nil {
+		return sentCommand{}, ErrNotConnected
 	}
 
 	c.nonce++
@@ -206,15 +380,20 @@ func (c *Client) sendCommand(cmd string, args map[string]any) error {
 		"nonce": nonce,
 	})
 	if err != nil {
-		return fmt.Errorf("marshaling command: %w", err)
+		return sentCommand{}, fmt.Errorf("marshaling command: %w", err)
 	}
 
 	frame, err := EncodeFrame(OpFrame, payload)
 	if err != nil {
-		return fmt.Errorf("encoding command: %w", err)
+		return sentCommand{}, fmt.Errorf("encoding command: %w", err)
+	}
+	ch, err := c.reader.expect(nonce)
+	if err != nil {
+		return sentCommand{}, err
 	}
 	if _, err = c.conn.Write(frame); err != nil {
-		return fmt.Errorf("writing command: %w", err)
+		c.reader.forget(nonce)
+		return sentCommand{}, fmt.Errorf("writing command: %w", err)
 	}
-	return nil
+	return sentCommand{nonce: nonce, response: ch}, nil
 }
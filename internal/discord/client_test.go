@@ -1,13 +1,16 @@
 // Tests for the [Client] type covering handshake, activity commands,
-// nonce uniqueness, and connection lifecycle.
+// nonce uniqueness, response correlation, and connection lifecycle.
 package discord
 
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 // ///////////////////////////////////////////////
@@ -52,6 +55,59 @@ func writeReadyResponse(t *testing.T, conn net.Conn) {
 	}
 }
 
+// writeResponse writes the response to the command with nonce. An evt of
+// "ERROR" rejects the command with data as the error.
+func writeResponse(t *testing.T, conn net.Conn, nonce, evt string, data map[string]any) {
+	t.Helper()
+	resp := map[string]any{"cmd": "SET_ACTIVITY", "nonce": nonce, "data": data}
+	if evt != "" {
+		resp["evt"] = evt
+	}
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("failed to marshal response: %v", err)
+	}
+	frame, err := EncodeFrame(OpFrame, payload)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("failed to write response: %v", err)
+	}
+}
+
+// connectedClient returns a client connected to the client end of a pipe,
+// with its response reader running, and the server end of the pipe.
+func connectedClient(t *testing.T) (*Client, net.Conn) {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() {
+		serverConn.Close()
+		clientConn.Close()
+	})
+	c := NewClient("test-app-id")
+	// Inject the mock connection directly, bypassing connectToDiscord.
+	c.conn = clientConn
+	c.startReader()
+	return c, serverConn
+}
+
+// waitEvent waits for the next connection event of c and fails unless it
+// reports state.
+func waitEvent(t *testing.T, c *Client, state ConnState) ConnEvent {
+	t.Helper()
+	select {
+	case ev := <-c.Events():
+		if ev.State != state {
+			t.Fatalf("event state = %v, want %v", ev.State, state)
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("no %v event", state)
+		return ConnEvent{}
+	}
+}
+
 // ///////////////////////////////////////////////
 // Client.handshake
 // ///////////////////////////////////////////////
@@ -98,12 +154,7 @@ func TestClient_Handshake(t *testing.T) {
 // ///////////////////////////////////////////////
 
 func TestClient_SetActivity(t *testing.T) {
-	serverConn, clientConn := net.Pipe()
-	defer serverConn.Close()
-	defer clientConn.Close()
-
-	c := NewClient("test-app-id")
-	c.conn = clientConn
+	c, serverConn := connectedClient(t)
 
 	activity := &Activity{
 		Details: "Testing",
@@ -159,18 +210,14 @@ func TestClient_SetActivity(t *testing.T) {
 		t.Fatalf("expected state=Running tests, got %v", act["state"])
 	}
 
+	writeResponse(t, serverConn, nonce, "", act)
 	if err := <-done; err != nil {
 		t.Fatalf("SetActivity returned error: %v", err)
 	}
 }
 
 func TestClient_SetActivity_WithButtons(t *testing.T) {
-	serverConn, clientConn := net.Pipe()
-	defer serverConn.Close()
-	defer clientConn.Close()
-
-	c := NewClient("test-app-id")
-	c.conn = clientConn
+	c, serverConn := connectedClient(t)
 
 	activity := &Activity{
 		Details: "With buttons",
@@ -208,22 +255,70 @@ func TestClient_SetActivity_WithButtons(t *testing.T) {
 		t.Fatalf("button 1 mismatch: %v", b1)
 	}
 
+	writeResponse(t, serverConn, m["nonce"].(string), "", act)
 	if err := <-done; err != nil {
 		t.Fatalf("SetActivity returned error: %v", err)
 	}
 }
 
+func TestActivity_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		activity Activity
+		want     string
+	}{
+		{
+			name:     "zero values omitted",
+			activity: Activity{Details: "Testing"},
+			want:     `{"details":"Testing"}`,
+		},
+		{
+			name: "full payload",
+			activity: Activity{
+				Type:              ActivityWatching,
+				StatusDisplayType: StatusDisplayDetails,
+				Details:           "Working on agentcord",
+				DetailsURL:        "https://github.com/zachthedev/agentcord",
+				State:             "Opus 4.6",
+				StateURL:          "https://example.com/state",
+				Timestamps:        &Timestamps{Start: 1000, End: 4600},
+				Assets: &Assets{
+					LargeImage: "app_icon",
+					LargeURL:   "https://example.com/large",
+					SmallImage: "opus",
+					SmallURL:   "https://example.com/small",
+				},
+				Party:    &Party{ID: "p", Size: []int{1, 4}},
+				Instance: true,
+			},
+			want: `{"type":3,"status_display_type":2,"details":"Working on agentcord",` +
+				`"details_url":"https://github.com/zachthedev/agentcord","state":"Opus 4.6",` +
+				`"state_url":"https://example.com/state","timestamps":{"start":1000,"end":4600},` +
+				`"assets":{"large_image":"app_icon","large_url":"https://example.com/large",` +
+				`"small_image":"opus","small_url":"https://example.com/small"},` +
+				`"party":{"id":"p","size":[1,4]},"instance":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.activity)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
 // ///////////////////////////////////////////////
 // Client.ClearActivity
 // ///////////////////////////////////////////////
 
 func TestClient_ClearActivity(t *testing.T) {
-	serverConn, clientConn := net.Pipe()
-	defer serverConn.Close()
-	defer clientConn.Close()
-
-	c := NewClient("test-app-id")
-	c.conn = clientConn
+	c, serverConn := connectedClient(t)
 
 	done := make(chan error, 1)
 	go func() {
@@ -251,6 +346,7 @@ func TestClient_ClearActivity(t *testing.T) {
 		t.Fatalf("expected pid=%d, got %v", os.Getpid(), args["pid"])
 	}
 
+	writeResponse(t, serverConn, m["nonce"].(string), "", nil)
 	if err := <-done; err != nil {
 		t.Fatalf("ClearActivity returned error: %v", err)
 	}
@@ -261,12 +357,7 @@ func TestClient_ClearActivity(t *testing.T) {
 // ///////////////////////////////////////////////
 
 func TestClient_NonceUniqueness(t *testing.T) {
-	serverConn, clientConn := net.Pipe()
-	defer serverConn.Close()
-	defer clientConn.Close()
-
-	c := NewClient("test-app-id")
-	c.conn = clientConn
+	c, serverConn := connectedClient(t)
 
 	nonces := make(map[string]bool)
 
@@ -284,6 +375,7 @@ func TestClient_NonceUniqueness(t *testing.T) {
 		}
 		nonces[nonce] = true
 
+		writeResponse(t, serverConn, nonce, "", nil)
 		if err := <-done; err != nil {
 			t.Fatalf("SetActivity call %d returned error: %v", i, err)
 		}
@@ -319,7 +411,7 @@ func TestClient_Connected_ReturnsFalseInitially(t *testing.T) {
 
 func TestClient_SendCommand_NotConnected(t *testing.T) {
 	c := NewClient("test-app-id")
-	err := c.sendCommand("SET_ACTIVITY", map[string]any{"pid": 1})
+	_, err := c.sendCommand("SET_ACTIVITY", map[string]any{"pid": 1})
 	if err == nil {
 		t.Fatal("expected error from sendCommand when not connected")
 	}
@@ -411,3 +503,174 @@ func TestClient_Handshake_ErrorResponse(t *testing.T) {
 		t.Fatal("expected handshake to fail with ERROR response")
 	}
 }
+
+// ///////////////////////////////////////////////
+// Response Correlation
+// ///////////////////////////////////////////////
+
+func TestClient_SetActivity_Rejected(t *testing.T) {
+	c, serverConn := connectedClient(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.SetActivity(&Activity{Buttons: []Button{{Label: "Repo", URL: "not a url"}}})
+	}()
+
+	_, m := readFrame(t, serverConn)
+	writeResponse(t, serverConn, m["nonce"].(string), "ERROR", map[string]any{
+		"code":    4000,
+		"message": `child "activity" fails because [child "buttons" fails because ["buttons" at position 0 fails because [child "url" fails because ["url" must be a valid uri]]]]`,
+	})
+
+	err := <-done
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("SetActivity error = %v, want a *CommandError", err)
+	}
+	if cmdErr.Cmd != "SET_ACTIVITY" || cmdErr.Code != 4000 {
+		t.Errorf("CommandError = %+v, want SET_ACTIVITY code 4000", cmdErr)
+	}
+	if got := cmdErr.Field(); got != "activity.buttons.url" {
+		t.Errorf("Field() = %q, want %q", got, "activity.buttons.url")
+	}
+}
+
+func TestClient_ResponsesMatchedByNonce(t *testing.T) {
+	c, serverConn := connectedClient(t)
+
+	first := make(chan error, 1)
+	go func() { first <- c.SetActivity(&Activity{Details: "first"}) }()
+	_, m1 := readFrame(t, serverConn)
+
+	second := make(chan error, 1)
+	go func() { second <- c.SetActivity(&Activity{Details: "second"}) }()
+	_, m2 := readFrame(t, serverConn)
+
+	// Answer out of order, with an event in between that no command awaits.
+	writeResponse(t, serverConn, m2["nonce"].(string), "ERROR", map[string]any{"code": 4000, "message": "bad"})
+	writeResponse(t, serverConn, "", "ACTIVITY_JOIN", nil)
+	writeResponse(t, serverConn, m1["nonce"].(string), "", nil)
+
+	if err := <-first; err != nil {
+		t.Errorf("first SetActivity = %v, want success", err)
+	}
+	var cmdErr *CommandError
+	if err := <-second; !errors.As(err, &cmdErr) || cmdErr.Message != "bad" {
+		t.Errorf("second SetActivity = %v, want its own rejection", err)
+	}
+}
+
+func TestClient_SetActivity_ConnectionClosed(t *testing.T) {
+	c, serverConn := connectedClient(t)
+
+	done := make(chan error, 1)
+	go func() { done <- c.SetActivity(&Activity{Details: "test"}) }()
+	readFrame(t, serverConn)
+	serverConn.Close()
+
+	if err := <-done; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("SetActivity = %v, want ErrConnectionClosed", err)
+	}
+	waitEvent(t, c, StateDisconnected)
+	if err := c.SetActivity(&Activity{Details: "test"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("SetActivity after close = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestClient_SetActivity_NoResponse(t *testing.T) {
+	old := responseTimeout
+	responseTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { responseTimeout = old })
+
+	c, serverConn := connectedClient(t)
+
+	done := make(chan error, 1)
+	go func() { done <- c.SetActivity(&Activity{Details: "test"}) }()
+	readFrame(t, serverConn)
+
+	if err := <-done; !errors.Is(err, ErrNoResponse) {
+		t.Errorf("SetActivity = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestCommandError_Field(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{`child "activity" fails because [child "details" fails because ["details" length must be less than or equal to 128 characters long]]`, "activity.details"},
+		{"activity.buttons.0.url: Invalid URL", "activity.buttons.0.url"},
+		{"Invalid Payload", ""},
+	}
+	for _, tt := range tests {
+		e := &CommandError{Cmd: "SET_ACTIVITY", Code: 4000, Message: tt.message}
+		if got := e.Field(); got != tt.want {
+			t.Errorf("Field() of %q = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+// ///////////////////////////////////////////////
+// Disconnect Detection
+// ///////////////////////////////////////////////
+
+func TestClient_Ping(t *testing.T) {
+	c, serverConn := connectedClient(t)
+
+	frame, _ := EncodeFrame(OpPing, []byte(`{"ping":1}`))
+	serverConn.Write(frame)
+
+	opcode, payload, err := DecodeFrame(serverConn)
+	if err != nil {
+		t.Fatalf("reading pong: %v", err)
+	}
+	if opcode != OpPong || string(payload) != `{"ping":1}` {
+		t.Errorf("reply = %d %s, want PONG echoing the ping payload", opcode, payload)
+	}
+	if !c.Connected() {
+		t.Error("a ping should not disconnect the client")
+	}
+}
+
+func TestClient_DisconnectOnEOF(t *testing.T) {
+	c, serverConn := connectedClient(t)
+	serverConn.Close()
+
+	ev := waitEvent(t, c, StateDisconnected)
+	if !errors.Is(ev.Err, io.EOF) {
+		t.Errorf("event error = %v, want io.EOF", ev.Err)
+	}
+	if c.Connected() {
+		t.Error("Connected() = true after the connection was lost")
+	}
+}
+
+func TestClient_DisconnectOnClose(t *testing.T) {
+	c, serverConn := connectedClient(t)
+
+	frame, _ := EncodeFrame(OpClose, []byte(`{"code":4000,"message":"Invalid Client ID"}`))
+	serverConn.Write(frame)
+
+	ev := waitEvent(t, c, StateDisconnected)
+	if !errors.Is(ev.Err, ErrClosedByDiscord) || !strings.Contains(ev.Err.Error(), "Invalid Client ID") {
+		t.Errorf("event error = %v, want ErrClosedByDiscord with the close message", ev.Err)
+	}
+	if c.Connected() {
+		t.Error("Connected() = true after Discord closed the connection")
+	}
+}
+
+func TestClient_CloseSendsNoEvent(t *testing.T) {
+	c, serverConn := connectedClient(t)
+	// Drain the best-effort clear so Close can write it.
+	go DecodeFrame(serverConn)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	select {
+	case ev := <-c.Events():
+		t.Errorf("Close sent event %+v, want none", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
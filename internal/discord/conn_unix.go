@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 )
 
@@ -17,10 +19,10 @@ import (
 // Connection
 // ///////////////////////////////////////////////
 
-// connectToDiscord tries each known IPC socket path and returns the first
-// successful connection. It checks XDG_RUNTIME_DIR, /tmp, Snap, and
-// Flatpak socket locations.
-func connectToDiscord() (net.Conn, error) {
+// socketPaths returns every IPC socket path Discord may listen on, in the
+// order they are tried: XDG_RUNTIME_DIR, /tmp, Snap, Flatpak, and on WSL the
+// paths a relay bridge creates.
+func socketPaths() []string {
 	var paths []string
 
 	// Socket name prefixes for Discord variants (stable, Canary, PTB).
@@ -67,9 +69,27 @@ func connectToDiscord() (net.Conn, error) {
 	// may have created the socket. Many of these overlap with the standard paths
 	// above, but deduplication is not necessary since Dial on a missing path is
 	// cheap and fast.
-	paths = append(paths, wslSocketPaths()...)
+	return append(paths, wslSocketPaths()...)
+}
 
-	for _, path := range paths {
+// SocketDirs returns the directories Discord's IPC sockets are created in,
+// without duplicates, for watching until Discord starts. Directories that do
+// not exist yet are included.
+func SocketDirs() []string {
+	var dirs []string
+	for _, p := range socketPaths() {
+		if dir := filepath.Dir(p); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// connectToDiscord tries each known IPC socket path and returns the first
+// successful connection. It checks XDG_RUNTIME_DIR, /tmp, Snap, and
+// Flatpak socket locations.
+func connectToDiscord() (net.Conn, error) {
+	for _, path := range socketPaths() {
 		conn, err := net.Dial("unix", path)
 		if err == nil {
 			return conn, nil
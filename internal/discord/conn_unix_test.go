@@ -0,0 +1,30 @@
+//go:build !windows
+
+package discord
+
+import (
+	"slices"
+	"testing"
+)
+
+// ///////////////////////////////////////////////
+// SocketDirs
+// ///////////////////////////////////////////////
+
+func TestSocketDirs(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/test")
+	dirs := SocketDirs()
+
+	for _, want := range []string{"/run/user/test", "/tmp"} {
+		if !slices.Contains(dirs, want) {
+			t.Errorf("SocketDirs() = %v, missing %s", dirs, want)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, d := range dirs {
+		if seen[d] {
+			t.Errorf("SocketDirs() lists %s twice", d)
+		}
+		seen[d] = true
+	}
+}
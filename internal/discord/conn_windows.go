@@ -28,3 +28,9 @@ func connectToDiscord() (net.Conn, error) {
 	}
 	return nil, ErrIPCNotAvailable
 }
+
+// SocketDirs returns nil: Discord's named pipes live in no directory that can
+// be watched, so waiting for Discord relies on retries alone.
+func SocketDirs() []string {
+	return nil
+}
@@ -19,8 +19,14 @@ const (
 	OpHandshake Opcode = 0
 	// OpFrame is the opcode for a standard IPC data frame.
 	OpFrame Opcode = 1
-	// OpClose is the opcode for closing the IPC connection.
+	// OpClose is the opcode for closing the IPC connection. Discord sends it
+	// with a JSON payload holding the close code and message.
 	OpClose Opcode = 2
+	// OpPing is the opcode of a keepalive ping. The receiver echoes the
+	// payload back in a PONG frame.
+	OpPing Opcode = 3
+	// OpPong is the opcode of the reply to a PING.
+	OpPong Opcode = 4
 
 	// frameHeaderSize is the byte length of the IPC frame header
 	// consisting of a 4-byte little-endian opcode followed by a
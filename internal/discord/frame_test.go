@@ -250,6 +250,8 @@ func TestFrameRoundTrip(t *testing.T) {
 		{"handshake", OpHandshake, []byte(`{"v":1,"client_id":"12345"}`)},
 		{"frame_json", OpFrame, []byte(`{"cmd":"SET_ACTIVITY","args":{"pid":1234}}`)},
 		{"close", OpClose, []byte(`{"code":1000,"reason":"goodbye"}`)},
+		{"ping", OpPing, []byte(`{"nonce":"1"}`)},
+		{"pong", OpPong, []byte(`{"nonce":"1"}`)},
 		{"empty_payload", OpFrame, []byte{}},
 		{"binary_payload", OpHandshake, []byte{0x00, 0xFF, 0xFE, 0x01, 0x80}},
 	}
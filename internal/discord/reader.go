@@ -0,0 +1,239 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Command Errors
+// ///////////////////////////////////////////////
+
+// ErrNoResponse is returned when Discord does not answer a command within
+// [responseTimeout].
+var ErrNoResponse = errors.New("no response from Discord")
+
+// ErrConnectionClosed is returned when the connection closes while a command
+// waits for its response.
+var ErrConnectionClosed = errors.New("connection closed")
+
+// ErrClosedByDiscord is the cause of a disconnect Discord initiated with a
+// CLOSE frame, e.g. when the application ID is invalid or Discord quits.
+var ErrClosedByDiscord = errors.New("closed by Discord")
+
+// closeError returns the error for a CLOSE frame with payload, wrapping
+// [ErrClosedByDiscord] with Discord's close code and message.
+func closeError(payload []byte) error {
+	var data struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(payload, &data) != nil {
+		return ErrClosedByDiscord
+	}
+	return fmt.Errorf("%w (code %d): %s", ErrClosedByDiscord, data.Code, data.Message)
+}
+
+// CommandError is a command Discord rejected with an ERROR event, e.g. an
+// activity with an invalid button URL or an over-long field.
+type CommandError struct {
+	// Cmd is the rejected command (e.g. "SET_ACTIVITY").
+	Cmd string
+	// Code is Discord's RPC error code (e.g. 4000 for an invalid payload).
+	Code int
+	// Message is Discord's description of the error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s rejected (code %d): %s", e.Cmd, e.Code, e.Message)
+}
+
+// childFieldRegex matches one level of a validation message's field path:
+// `child "activity" fails because [child "buttons" fails because ...]`.
+var childFieldRegex = regexp.MustCompile(`child "([^"]+)"`)
+
+// pathFieldRegex matches a validation message that starts with the field
+// path: `activity.buttons.0.url: Invalid URL`.
+var pathFieldRegex = regexp.MustCompile(`^([\w.\[\]]+):\s`)
+
+// Field returns the dotted path of the payload field the error names (e.g.
+// "activity.buttons.url"), or "" when the message names no field.
+func (e *CommandError) Field() string {
+	if m := pathFieldRegex.FindStringSubmatch(e.Message); m != nil {
+		return m[1]
+	}
+	var fields []string
+	for _, m := range childFieldRegex.FindAllStringSubmatch(e.Message, -1) {
+		fields = append(fields, m[1])
+	}
+	return strings.Join(fields, ".")
+}
+
+// ///////////////////////////////////////////////
+// Response Reader
+// ///////////////////////////////////////////////
+
+// responseTimeout is how long a command waits for Discord's response.
+// A variable so tests can shorten it.
+var responseTimeout = 5 * time.Second
+
+// response is a frame Discord sends in reply to a command, or an event.
+type response struct {
+	Cmd   string          `json:"cmd"`
+	Evt   string          `json:"evt"`
+	Nonce string          `json:"nonce"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// err returns the [CommandError] of an ERROR response, or nil.
+func (r *response) err() error {
+	if r.Evt != "ERROR" {
+		return nil
+	}
+	var data struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(r.Data, &data); err != nil {
+		return fmt.Errorf("parsing %s error: %w", r.Cmd, err)
+	}
+	return &CommandError{Cmd: r.Cmd, Code: data.Code, Message: data.Message}
+}
+
+// reader decodes the frames Discord sends on one connection and hands each
+// response to the command with the same nonce. One reader serves one
+// connection; a reconnect starts a new one.
+type reader struct {
+	// mu protects pending.
+	mu sync.Mutex
+	// pending maps the nonce of each command awaiting a response to the
+	// channel the response is delivered on. Nil once the reader has stopped.
+	pending map[string]chan *response
+
+	// done is closed when the reader stops.
+	done chan struct{}
+	// err is the read error that stopped the reader. Set before done is closed.
+	err error
+
+	// ping answers a PING frame with its payload. Nil ignores pings.
+	ping func(payload []byte)
+	// stopped is called with the error that stopped the reader, after
+	// pending commands have been woken. Nil when nothing needs to know.
+	stopped func(err error)
+}
+
+// newReader returns a reader with no pending commands. Start it with run.
+func newReader() *reader {
+	return &reader{
+		pending: make(map[string]chan *response),
+		done:    make(chan struct{}),
+	}
+}
+
+// run reads frames from conn until a read fails or Discord closes the
+// connection, delivering responses to their commands and answering pings.
+// Frames without a pending nonce (events) are dropped.
+func (r *reader) run(conn io.Reader) {
+	err := r.read(conn)
+	r.stop(err)
+	if r.stopped != nil {
+		r.stopped(err)
+	}
+}
+
+// read handles frames from conn and returns the error that ends the
+// connection: the read error (io.EOF when Discord went away) or the
+// [ErrClosedByDiscord] error of a CLOSE frame.
+func (r *reader) read(conn io.Reader) error {
+	for {
+		opcode, payload, err := DecodeFrame(conn)
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case OpFrame:
+			var resp response
+			if json.Unmarshal(payload, &resp) != nil || resp.Nonce == "" {
+				continue
+			}
+			r.deliver(&resp)
+		case OpPing:
+			if r.ping != nil {
+				r.ping(payload)
+			}
+		case OpClose:
+			return closeError(payload)
+		}
+	}
+}
+
+// expect registers a command with nonce and returns the channel its response
+// will be delivered on. Must be called before the command is written, so a
+// fast response is not missed.
+func (r *reader) expect(nonce string) (<-chan *response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnectionClosed, r.err)
+	}
+	ch := make(chan *response, 1)
+	r.pending[nonce] = ch
+	return ch, nil
+}
+
+// forget drops the command with nonce, e.g. after its write failed or it
+// timed out.
+func (r *reader) forget(nonce string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, nonce)
+}
+
+// deliver hands resp to the command with the same nonce, if one is pending.
+func (r *reader) deliver(resp *response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ch, ok := r.pending[resp.Nonce]; ok {
+		delete(r.pending, resp.Nonce)
+		ch <- resp
+	}
+}
+
+// stop records the read error and wakes every pending command.
+func (r *reader) stop(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+	r.pending = nil
+	close(r.done)
+}
+
+// await waits up to timeout for the response on ch to the command with nonce
+// and returns the command's error: a [CommandError] when Discord rejected it.
+func (r *reader) await(ch <-chan *response, nonce string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		return resp.err()
+	case <-r.done:
+		// The response may have arrived just before the connection closed.
+		select {
+		case resp := <-ch:
+			return resp.err()
+		default:
+		}
+		return fmt.Errorf("%w: %w", ErrConnectionClosed, r.err)
+	case <-timer.C:
+		r.forget(nonce)
+		return ErrNoResponse
+	}
+}
@@ -0,0 +1,128 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ///////////////////////////////////////////////
+// Socket Watcher
+// ///////////////////////////////////////////////
+
+// ErrNoSocketDirs is returned by [WatchSockets] when none of the directories
+// can be watched.
+var ErrNoSocketDirs = errors.New("no socket directory to watch")
+
+// SocketWatcher reports when a Discord IPC socket may have appeared, so a
+// client waiting for Discord can connect as soon as it starts instead of on
+// its next retry.
+type SocketWatcher struct {
+	// fsw watches the socket directories that exist and the parents of
+	// those that do not.
+	fsw *fsnotify.Watcher
+	// dirs are the socket directories, watched once they are created.
+	dirs []string
+	// events delivers a signal when a socket or socket directory is created.
+	// Buffered to 1 so bursts coalesce.
+	events chan struct{}
+	// done is closed by [SocketWatcher.Close] to stop the watch goroutine.
+	done chan struct{}
+	// once ensures [SocketWatcher.Close] is idempotent.
+	once sync.Once
+}
+
+// WatchSockets watches dirs (see [SocketDirs]) for Discord IPC sockets.
+// A directory that does not exist yet is watched through its parent and
+// added once created. Returns [ErrNoSocketDirs] when nothing can be watched.
+func WatchSockets(dirs []string) (*SocketWatcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating socket watcher: %w", err)
+	}
+	w := &SocketWatcher{
+		fsw:    fsw,
+		dirs:   dirs,
+		events: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	watched := 0
+	for _, dir := range dirs {
+		if fsw.Add(dir) == nil || fsw.Add(filepath.Dir(dir)) == nil {
+			watched++
+		}
+	}
+	if watched == 0 {
+		fsw.Close()
+		return nil, ErrNoSocketDirs
+	}
+
+	go w.watch()
+	return w, nil
+}
+
+// Events returns the channel signalled when a socket may have appeared.
+func (w *SocketWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *SocketWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// isSocketName reports whether name is an IPC socket name such as
+// "discord-ipc-0" or "discordcanary-ipc-3".
+func isSocketName(name string) bool {
+	return strings.Contains(filepath.Base(name), "-ipc-")
+}
+
+// watch forwards the creation of sockets and socket directories to the
+// events channel until the watcher is closed.
+func (w *SocketWatcher) watch() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) {
+				continue
+			}
+			if slices.Contains(w.dirs, event.Name) {
+				// The socket may be created before the watch is added.
+				_ = w.fsw.Add(event.Name)
+				w.notify()
+				continue
+			}
+			if isSocketName(event.Name) {
+				w.notify()
+			}
+		case _, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// notify sends a non-blocking signal on the events channel.
+func (w *SocketWatcher) notify() {
+	select {
+	case w.events <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,83 @@
+package discord
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// SocketWatcher
+// ///////////////////////////////////////////////
+
+// waitSocketEvent fails unless w signals within a second.
+func waitSocketEvent(t *testing.T, w *SocketWatcher) {
+	t.Helper()
+	select {
+	case <-w.Events():
+	case <-time.After(time.Second):
+		t.Fatal("no socket event")
+	}
+}
+
+func TestSocketWatcher_SocketCreated(t *testing.T) {
+	dir := t.TempDir()
+	w, err := WatchSockets([]string{dir})
+	if err != nil {
+		t.Fatalf("WatchSockets: %v", err)
+	}
+	defer w.Close()
+
+	os.WriteFile(filepath.Join(dir, "unrelated.txt"), nil, 0o600)
+	select {
+	case <-w.Events():
+		t.Fatal("event for a file that is not a socket")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	os.WriteFile(filepath.Join(dir, "discordcanary-ipc-1"), nil, 0o600)
+	waitSocketEvent(t, w)
+}
+
+func TestSocketWatcher_DirectoryCreatedLater(t *testing.T) {
+	// Flatpak and Snap socket directories only exist once Discord ran.
+	dir := filepath.Join(t.TempDir(), "com.discordapp.Discord")
+	w, err := WatchSockets([]string{dir})
+	if err != nil {
+		t.Fatalf("WatchSockets: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	waitSocketEvent(t, w)
+
+	os.WriteFile(filepath.Join(dir, "discord-ipc-0"), nil, 0o600)
+	waitSocketEvent(t, w)
+}
+
+func TestWatchSockets_NothingToWatch(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "a", "b")
+	if _, err := WatchSockets([]string{missing}); !errors.Is(err, ErrNoSocketDirs) {
+		t.Errorf("WatchSockets = %v, want ErrNoSocketDirs", err)
+	}
+	if _, err := WatchSockets(nil); !errors.Is(err, ErrNoSocketDirs) {
+		t.Errorf("WatchSockets(nil) = %v, want ErrNoSocketDirs", err)
+	}
+}
+
+func TestSocketWatcher_CloseTwice(t *testing.T) {
+	w, err := WatchSockets([]string{t.TempDir()})
+	if err != nil {
+		t.Fatalf("WatchSockets: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package httpfetch performs conditional HTTP GETs for cached remote data.
+//
+// Each cached download (pricing, tiers) has a small metadata file stored next
+// to the cache recording when it was fetched, where from, and the ETag and
+// Last-Modified validators the server returned. [Get] replays those validators
+// so an unchanged source answers with 304 Not Modified instead of a full body.
+package httpfetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/atomicfile"
+)
+
+// ///////////////////////////////////////////////
+// Metadata
+// ///////////////////////////////////////////////
+
+// Meta records how a cached resource was last fetched.
+type Meta struct {
+	// FetchedAt is when the resource was last fetched or revalidated.
+	FetchedAt time.Time `json:"fetched_at"`
+	// SourceURL is the URL (or file path) the resource was fetched from.
+	SourceURL string `json:"source_url"`
+	// ETag is the entity tag returned by the server, if any.
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the Last-Modified header returned by the server, if any.
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ReadMeta reads fetch metadata from path.
+func ReadMeta(path string) (Meta, error) {
+	var m Meta
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("reading fetch metadata: %w", err)
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("parsing fetch metadata: %w", err)
+	}
+	return m, nil
+}
+
+// WriteMeta writes fetch metadata to path atomically.
+func WriteMeta(path string, m Meta) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling fetch metadata: %w", err)
+	}
+	return atomicfile.Write(path, b, 0o644)
+}
+
+// ///////////////////////////////////////////////
+// Conditional GET
+// ///////////////////////////////////////////////
+
+// Result is the outcome of a [Get].
+type Result struct {
+	// Body is the response body. Nil when NotModified is true.
+	Body []byte
+	// NotModified reports that the server answered 304 and the cached copy is current.
+	NotModified bool
+	// Meta is the updated metadata to persist alongside the cache.
+	Meta Meta
+}
+
+// Get fetches url with client, sending If-None-Match and If-Modified-Since
+// from prev when prev was recorded for the same URL. A body larger than
+// maxBytes is an error, as is any status other than 200 or 304.
+func Get(client *http.Client, url string, prev Meta, maxBytes int64) (*Result, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s: %w", url, err)
+	}
+	if prev.SourceURL == url {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		m := prev
+		m.FetchedAt = time.Now()
+		return &Result{NotModified: true, Meta: m}, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading response from %s: %w", url, err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("response from %s exceeds %d bytes", url, maxBytes)
+	}
+	return &Result{
+		Body: body,
+		Meta: Meta{
+			FetchedAt:    time.Now(),
+			SourceURL:    url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
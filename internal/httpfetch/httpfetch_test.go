@@ -0,0 +1,140 @@
+package httpfetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Get Tests
+// ///////////////////////////////////////////////
+
+func TestGet_ConditionalETag(t *testing.T) {
+	var gotIfNoneMatch string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfNoneMatch = r.Header.Get("If-None-Match")
+		if gotIfNoneMatch == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	first, err := Get(server.Client(), server.URL, Meta{}, 1<<10)
+	if err != nil {
+		t.Fatalf("first Get: %v", err)
+	}
+	if first.NotModified || string(first.Body) != `{"ok":true}` {
+		t.Fatalf("first Get = %+v, want full body", first)
+	}
+	if first.Meta.ETag != `"v1"` || first.Meta.SourceURL != server.URL || first.Meta.LastModified == "" {
+		t.Errorf("first Meta = %+v, want etag, url, and last-modified recorded", first.Meta)
+	}
+
+	second, err := Get(server.Client(), server.URL, first.Meta, 1<<10)
+	if err != nil {
+		t.Fatalf("second Get: %v", err)
+	}
+	if gotIfNoneMatch != `"v1"` {
+		t.Errorf("If-None-Match = %q, want %q", gotIfNoneMatch, `"v1"`)
+	}
+	if !second.NotModified || second.Body != nil {
+		t.Errorf("second Get = %+v, want NotModified with no body", second)
+	}
+	if second.Meta.ETag != `"v1"` || !second.Meta.FetchedAt.After(first.Meta.FetchedAt.Add(-time.Second)) {
+		t.Errorf("second Meta = %+v, want etag kept and fetched_at refreshed", second.Meta)
+	}
+}
+
+func TestGet_IfModifiedSince(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("If-Modified-Since")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	prev := Meta{SourceURL: server.URL, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}
+	if _, err := Get(server.Client(), server.URL, prev, 1<<10); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != prev.LastModified {
+		t.Errorf("If-Modified-Since = %q, want %q", got, prev.LastModified)
+	}
+}
+
+func TestGet_ValidatorsIgnoredForDifferentURL(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("If-None-Match")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	prev := Meta{SourceURL: "https://elsewhere.example/prices", ETag: `"v1"`}
+	if _, err := Get(server.Client(), server.URL, prev, 1<<10); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != "" {
+		t.Errorf("If-None-Match = %q, want empty for a different source URL", got)
+	}
+}
+
+func TestGet_Non200(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := Get(server.Client(), server.URL, Meta{}, 1<<10); err == nil {
+		t.Fatal("expected error for 500 response")
+	}
+}
+
+func TestGet_BodyTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 32)))
+	}))
+	defer server.Close()
+
+	if _, err := Get(server.Client(), server.URL, Meta{}, 16); err == nil {
+		t.Fatal("expected error for oversized body")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Meta Tests
+// ///////////////////////////////////////////////
+
+func TestMetaRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.meta.json")
+	in := Meta{
+		FetchedAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		SourceURL:    "https://example.com/prices",
+		ETag:         `"abc"`,
+		LastModified: "Fri, 02 Jan 2026 03:04:05 GMT",
+	}
+	if err := WriteMeta(path, in); err != nil {
+		t.Fatalf("WriteMeta: %v", err)
+	}
+	out, err := ReadMeta(path)
+	if err != nil {
+		t.Fatalf("ReadMeta: %v", err)
+	}
+	if !out.FetchedAt.Equal(in.FetchedAt) || out.SourceURL != in.SourceURL || out.ETag != in.ETag || out.LastModified != in.LastModified {
+		t.Errorf("round-trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestReadMeta_Missing(t *testing.T) {
+	if _, err := ReadMeta(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected error for missing metadata file")
+	}
+}
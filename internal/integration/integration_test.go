@@ -130,7 +130,7 @@ func TestStateCreation(t *testing.T) {
 	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "activity.sh"), dataDir, input)
 
 	// Verify per-client state file exists
-	stateFile := paths.StateFileForClient(session.DefaultClient)
+	stateFile := paths.StateFileForSession(session.DefaultClient, "test-session-001")
 	statePath := filepath.Join(dataDir, stateFile)
 	data, err := os.ReadFile(statePath)
 	if err != nil {
@@ -192,7 +192,7 @@ func TestSessionLifecycle(t *testing.T) {
 	sessionID := "lifecycle-session"
 	input := `{"session_id": "` + sessionID + `"}`
 
-	stateFile := paths.StateFileForClient(session.DefaultClient)
+	stateFile := paths.StateFileForSession(session.DefaultClient, sessionID)
 
 	// Activity hook creates state with stopped=false
 	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "activity.sh"), dataDir, input)
@@ -236,6 +236,43 @@ func TestMultiSession(t *testing.T) {
 	}
 }
 
+func TestParallelSessionStates(t *testing.T) {
+	bash := findBash()
+	if bash == "" {
+		t.Skip("bash not available")
+	}
+
+	dataDir := t.TempDir()
+	scripts := scriptDir(t)
+	placeFakeDaemon(t, dataDir)
+	activity := filepath.Join(scripts, "hooks", "unix", "activity.sh")
+
+	runHook(t, bash, activity, dataDir, `{"session_id": "session-a"}`)
+	runHook(t, bash, activity, dataDir, `{"session_id": "session-b"}`)
+
+	// Each session keeps its own state file instead of overwriting the other's.
+	for _, sid := range []string{"session-a", "session-b"} {
+		data, err := os.ReadFile(filepath.Join(dataDir, paths.StateFileForSession(session.DefaultClient, sid)))
+		if err != nil {
+			t.Fatalf("state for %s not created: %v", sid, err)
+		}
+		var state map[string]any
+		json.Unmarshal(data, &state)
+		if state["sessionId"] != sid {
+			t.Errorf("sessionId = %v, want %s", state["sessionId"], sid)
+		}
+	}
+
+	// Ending one of them drops its state; the other session's remains.
+	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "sessionend.sh"), dataDir, `{"session_id": "session-a"}`)
+	if _, err := os.Stat(filepath.Join(dataDir, paths.StateFileForSession(session.DefaultClient, "session-a"))); !os.IsNotExist(err) {
+		t.Error("expected the ended session's state file to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, paths.StateFileForSession(session.DefaultClient, "session-b"))); err != nil {
+		t.Errorf("expected the remaining session's state file to exist: %v", err)
+	}
+}
+
 func TestStopCleanup(t *testing.T) {
 	bash := findBash()
 	if bash == "" {
@@ -293,7 +330,7 @@ func TestSessionStartPreserved(t *testing.T) {
 	input := `{"session_id": "preserve-start"}`
 	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "activity.sh"), dataDir, input)
 
-	stateFile := paths.StateFileForClient(session.DefaultClient)
+	stateFile := paths.StateFileForSession(session.DefaultClient, "preserve-start")
 
 	data1, _ := os.ReadFile(filepath.Join(dataDir, stateFile))
 	var s1 map[string]any
@@ -327,9 +364,8 @@ func TestEmptySessionIDIgnored(t *testing.T) {
 	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "activity.sh"), dataDir, `{"no_session": "here"}`)
 
 	// No state file should be created
-	stateFile := paths.StateFileForClient(session.DefaultClient)
-	if _, err := os.Stat(filepath.Join(dataDir, stateFile)); !os.IsNotExist(err) {
-		t.Errorf("expected no %s for empty session_id", stateFile)
+	if matches, _ := filepath.Glob(filepath.Join(dataDir, paths.StateFilePattern)); len(matches) != 0 {
+		t.Errorf("expected no state file for empty session_id, got %v", matches)
 	}
 }
 
@@ -394,7 +430,7 @@ func TestClientFieldInState(t *testing.T) {
 
 	runHook(t, bash, filepath.Join(scripts, "hooks", "unix", "activity.sh"), dataDir, `{"session_id": "client-test"}`)
 
-	stateFile := paths.StateFileForClient(session.DefaultClient)
+	stateFile := paths.StateFileForSession(session.DefaultClient, "client-test")
 	data, err := os.ReadFile(filepath.Join(dataDir, stateFile))
 	if err != nil {
 		t.Fatalf("%s not created: %v", stateFile, err)
@@ -511,7 +547,7 @@ func TestStopIdempotent(t *testing.T) {
 	runHook(t, bash, stopScript, dataDir, input)
 
 	// Verify state still has stopped=true
-	stateFile := paths.StateFileForClient(session.DefaultClient)
+	stateFile := paths.StateFileForSession(session.DefaultClient, sessionID)
 	data, err := os.ReadFile(filepath.Join(dataDir, stateFile))
 	if err != nil {
 		t.Fatalf("%s not found: %v", stateFile, err)
@@ -0,0 +1,251 @@
+// Package ledger records token usage and API-value spending in an
+// append-only JSONL file in the data directory, so usage totals survive
+// daemon restarts and can be reported per day, week, project and model.
+//
+// The daemon reports each session's running totals per model, and the ledger
+// appends only the change since the previous report. The records of a session
+// therefore always sum to its latest totals, and the usage in any time range
+// is the sum of the records whose timestamps fall inside it.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// minDelta is the smallest cost change worth recording. Smaller changes are
+// floating-point noise from re-pricing the same usage.
+const minDelta = 1e-9
+
+// ///////////////////////////////////////////////
+// Types
+// ///////////////////////////////////////////////
+
+// Usage holds token counts, activity counts and USD cost, either as a
+// session's running totals or as the change recorded in one ledger line.
+type Usage struct {
+	// InputTokens is the number of uncached input tokens.
+	InputTokens int64 `json:"input,omitempty"`
+	// OutputTokens is the number of output tokens.
+	OutputTokens int64 `json:"output,omitempty"`
+	// CacheWriteTokens is the number of tokens written to the prompt cache.
+	CacheWriteTokens int64 `json:"cache_write,omitempty"`
+	// CacheReadTokens is the number of tokens read from the prompt cache.
+	CacheReadTokens int64 `json:"cache_read,omitempty"`
+	// Turns is the number of assistant turns.
+	Turns int64 `json:"turns,omitempty"`
+	// ToolUses is the number of tool calls.
+	ToolUses int64 `json:"tool_uses,omitempty"`
+	// Cost is the USD cost. In a ledger line it is negative when a session's
+	// cost was revised down, e.g. after a pricing refresh.
+	Cost float64 `json:"cost"`
+}
+
+// Tokens returns the total of all token classes.
+func (u Usage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheWriteTokens + u.CacheReadTokens
+}
+
+// Add returns the field-wise sum of u and other.
+func (u Usage) Add(other Usage) Usage {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.Turns += other.Turns
+	u.ToolUses += other.ToolUses
+	u.Cost += other.Cost
+	return u
+}
+
+// sub returns the field-wise difference of u and other.
+func (u Usage) sub(other Usage) Usage {
+	u.InputTokens -= other.InputTokens
+	u.OutputTokens -= other.OutputTokens
+	u.CacheWriteTokens -= other.CacheWriteTokens
+	u.CacheReadTokens -= other.CacheReadTokens
+	u.Turns -= other.Turns
+	u.ToolUses -= other.ToolUses
+	u.Cost -= other.Cost
+	return u
+}
+
+// isZero reports whether u records no change.
+func (u Usage) isZero() bool {
+	c := u
+	c.Cost = 0
+	return c == Usage{} && math.Abs(u.Cost) < minDelta
+}
+
+// Record is a single ledger line: a change in the usage of one model in one
+// session.
+type Record struct {
+	// Time is the Unix timestamp at which the change was observed.
+	Time int64 `json:"ts"`
+	// Session is the session the usage belongs to.
+	Session string `json:"session"`
+	// Project is the project the session ran in. Empty when unknown.
+	Project string `json:"project,omitempty"`
+	// Model is the model that consumed the usage. Empty when unknown.
+	Model string `json:"model,omitempty"`
+	// Usage holds the change in the session's totals for Model.
+	Usage
+}
+
+// Ledger is an append-only usage ledger backed by a JSONL file.
+// It is safe for concurrent use.
+type Ledger struct {
+	// mu protects records and seen.
+	mu sync.Mutex
+	// path is the JSONL file the ledger appends to.
+	path string
+	// records holds every record in file order.
+	records []Record
+	// seen maps session IDs to the sum of their records per model.
+	seen map[string]map[string]Usage
+}
+
+// ///////////////////////////////////////////////
+// Public API
+// ///////////////////////////////////////////////
+
+// Open loads the ledger at path. A missing file yields an empty ledger that
+// is created on the first write. Malformed lines, such as a line cut short
+// by a crash, are skipped.
+func Open(path string) (*Ledger, error) {
+	l := &Ledger{path: path, seen: make(map[string]map[string]Usage)}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening ledger: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.Session == "" {
+			continue
+		}
+		l.add(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading ledger: %w", err)
+	}
+	return l, nil
+}
+
+// Record reports the current running totals of a session, keyed by model.
+// For each model, the change since the session's last report is appended to
+// the ledger with timestamp now. Models whose totals are unchanged write
+// nothing.
+func (l *Ledger) Record(session, project string, models map[string]Usage, now time.Time) error {
+	if session == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var pending []Record
+	for _, model := range slices.Sorted(maps.Keys(models)) {
+		delta := models[model].sub(l.seen[session][model])
+		if delta.isZero() {
+			continue
+		}
+		pending = append(pending, Record{
+			Time:    now.Unix(),
+			Session: session,
+			Project: project,
+			Model:   model,
+			Usage:   delta,
+		})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := l.append(pending); err != nil {
+		return err
+	}
+	for _, r := range pending {
+		l.add(r)
+	}
+	return nil
+}
+
+// Has reports whether the ledger holds any records for session.
+func (l *Ledger) Has(session string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.seen[session]
+	return ok
+}
+
+// Spent returns the USD cost recorded in [from, to).
+func (l *Ledger) Spent(from, to time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lo, hi := from.Unix(), to.Unix()
+	var total float64
+	for _, r := range l.records {
+		if r.Time >= lo && r.Time < hi {
+			total += r.Cost
+		}
+	}
+	return total
+}
+
+// Records returns a copy of every record in file order.
+func (l *Ledger) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.records)
+}
+
+// ///////////////////////////////////////////////
+// Internal Helpers
+// ///////////////////////////////////////////////
+
+// add folds r into the in-memory state. The caller must hold l.mu or have
+// exclusive access.
+func (l *Ledger) add(r Record) {
+	l.records = append(l.records, r)
+	models := l.seen[r.Session]
+	if models == nil {
+		models = make(map[string]Usage)
+		l.seen[r.Session] = models
+	}
+	models[r.Model] = models[r.Model].Add(r.Usage)
+}
+
+// append writes records as lines at the end of the ledger file. A single
+// write of whole lines keeps the file readable even if the daemon is killed.
+func (l *Ledger) append(records []Record) error {
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshalling ledger record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening ledger: %w", err)
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf("writing ledger: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,174 @@
+package ledger
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cost returns running totals holding only a USD cost for a single model.
+func cost(c float64) map[string]Usage { return map[string]Usage{"claude-opus-4-6": {Cost: c}} }
+
+// ///////////////////////////////////////////////
+// Record Tests
+// ///////////////////////////////////////////////
+
+func TestRecord_AppendsDeltas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	day1 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	l.Record("s1", "", cost(1.5), day1)
+	l.Record("s1", "", cost(1.5), day1) // unchanged, not recorded
+	l.Record("s1", "", cost(4.0), day2)
+	l.Record("s2", "", cost(2.0), day2)
+
+	if got := l.Spent(day1, day2); math.Abs(got-1.5) > 1e-12 {
+		t.Errorf("Spent(day1) = %v, want 1.5", got)
+	}
+	if got := l.Spent(day2, day2.AddDate(0, 0, 1)); math.Abs(got-4.5) > 1e-12 {
+		t.Errorf("Spent(day2) = %v, want 4.5 (2.5 from s1, 2.0 from s2)", got)
+	}
+	if n := len(l.records); n != 3 {
+		t.Errorf("records = %d, want 3", n)
+	}
+}
+
+func TestRecord_NegativeDeltaKeepsSessionTotal(t *testing.T) {
+	l, _ := Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	now := time.Now()
+	l.Record("s1", "", cost(3.0), now)
+	l.Record("s1", "", cost(2.0), now) // e.g. re-priced after a pricing refresh
+
+	if got := l.Spent(now.Add(-time.Hour), now.Add(time.Hour)); math.Abs(got-2.0) > 1e-12 {
+		t.Errorf("Spent = %v, want 2.0", got)
+	}
+}
+
+func TestRecord_IgnoresEmptySession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, _ := Open(path)
+	if err := l.Record("", "", cost(1), time.Now()); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("ledger file written for a session without an ID")
+	}
+}
+
+func TestRecord_PerModelDeltas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, _ := Open(path)
+	now := time.Unix(1000, 0)
+
+	l.Record("s1", "web", map[string]Usage{
+		"opus":  {InputTokens: 100, OutputTokens: 20, Turns: 1, ToolUses: 2, Cost: 1},
+		"haiku": {InputTokens: 10, Cost: 0.1},
+	}, now)
+	l.Record("s1", "web", map[string]Usage{
+		"opus":  {InputTokens: 150, OutputTokens: 30, Turns: 2, ToolUses: 2, Cost: 1.5},
+		"haiku": {InputTokens: 10, Cost: 0.1}, // unchanged, not recorded
+	}, now.Add(time.Minute))
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	records := reopened.Records()
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	want := Record{
+		Time: 1060, Session: "s1", Project: "web", Model: "opus",
+		Usage: Usage{InputTokens: 50, OutputTokens: 10, Turns: 1, Cost: 0.5},
+	}
+	if records[2] != want {
+		t.Errorf("last record = %+v, want %+v", records[2], want)
+	}
+	if !reopened.Has("s1") || reopened.Has("s2") {
+		t.Error("Has() does not reflect recorded sessions")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Report Tests
+// ///////////////////////////////////////////////
+
+func TestGroup(t *testing.T) {
+	records := []Record{
+		{Time: 100, Session: "s1", Project: "web", Model: "opus", Usage: Usage{InputTokens: 10, Cost: 1}},
+		{Time: 200, Session: "s2", Project: "api", Model: "opus", Usage: Usage{InputTokens: 5, Cost: 3}},
+		{Time: 300, Session: "s1", Project: "web", Model: "haiku", Usage: Usage{OutputTokens: 7, Cost: 0.5}},
+	}
+
+	rows := Group(records, func(r Record) string { return r.Project })
+	if len(rows) != 2 || rows[0].Key != "api" || rows[1].Key != "web" {
+		t.Fatalf("rows = %+v, want api then web", rows)
+	}
+	if rows[1].Sessions != 1 || rows[1].Cost != 1.5 || rows[1].Tokens() != 17 {
+		t.Errorf("web row = %+v, want 1 session, $1.50, 17 tokens", rows[1])
+	}
+
+	rows = Group(records, func(r Record) string { return r.Model })
+	SortByCost(rows)
+	if rows[0].Key != "opus" || rows[0].Sessions != 2 {
+		t.Errorf("first row by cost = %+v, want opus with 2 sessions", rows[0])
+	}
+
+	if got := Since(records, time.Unix(200, 0)); len(got) != 2 {
+		t.Errorf("Since = %d records, want 2", len(got))
+	}
+}
+
+// ///////////////////////////////////////////////
+// Open Tests
+// ///////////////////////////////////////////////
+
+func TestOpen_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	now := time.Now()
+	l, _ := Open(path)
+	l.Record("s1", "", cost(2.0), now)
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	// The restarted daemon resumes from the recorded total, so only the 0.5 increase is new.
+	reopened.Record("s1", "", cost(2.5), now)
+	if got := reopened.Spent(now.Add(-time.Hour), now.Add(time.Hour)); math.Abs(got-2.5) > 1e-12 {
+		t.Errorf("Spent after restart = %v, want 2.5", got)
+	}
+}
+
+func TestOpen_SkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	os.WriteFile(path, []byte(`{"ts":100,"session":"s1","cost":1}
+{"ts":200,"sess
+{"ts":300,"session":"s1","cost":0.5}
+`), 0o600)
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := l.Spent(time.Unix(0, 0), time.Unix(1000, 0)); got != 1.5 {
+		t.Errorf("Spent = %v, want 1.5", got)
+	}
+}
+
+func TestOpen_MissingFile(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := l.Spent(time.Unix(0, 0), time.Now()); got != 0 {
+		t.Errorf("Spent = %v, want 0", got)
+	}
+}
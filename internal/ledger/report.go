@@ -0,0 +1,65 @@
+package ledger
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Reports
+// ///////////////////////////////////////////////
+
+// Row is the usage aggregated under one key of a report table.
+type Row struct {
+	// Key is the group the row covers, e.g. a date, project or model.
+	Key string `json:"key"`
+	// Sessions is the number of distinct sessions with usage in the group.
+	Sessions int `json:"sessions"`
+	// Usage holds the summed usage of the group.
+	Usage
+}
+
+// Group sums records by the key returned for each record and returns one row
+// per key, sorted by key.
+func Group(records []Record, key func(Record) string) []Row {
+	rows := make(map[string]*Row)
+	sessions := make(map[string]map[string]bool)
+	for _, r := range records {
+		k := key(r)
+		row := rows[k]
+		if row == nil {
+			row = &Row{Key: k}
+			rows[k] = row
+			sessions[k] = make(map[string]bool)
+		}
+		row.Usage = row.Usage.Add(r.Usage)
+		sessions[k][r.Session] = true
+	}
+
+	out := make([]Row, 0, len(rows))
+	for _, k := range slices.Sorted(maps.Keys(rows)) {
+		row := *rows[k]
+		row.Sessions = len(sessions[k])
+		out = append(out, row)
+	}
+	return out
+}
+
+// SortByCost orders rows by descending cost, keeping key order for ties.
+func SortByCost(rows []Row) {
+	slices.SortStableFunc(rows, func(a, b Row) int { return cmp.Compare(b.Cost, a.Cost) })
+}
+
+// Since returns the records observed at or after t.
+func Since(records []Record, t time.Time) []Record {
+	cutoff := t.Unix()
+	var out []Record
+	for _, r := range records {
+		if r.Time >= cutoff {
+			out = append(out, r)
+		}
+	}
+	return out
+}
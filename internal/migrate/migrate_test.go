@@ -162,8 +162,8 @@ func TestRunDevNoTransforms(t *testing.T) {
 
 func TestRegistryExportedForOverride(t *testing.T) {
 	// Verify Config and State registries exist with expected defaults
-	if Config.CurrentVersion != 1 {
-		t.Fatalf("expected Config.CurrentVersion=1, got %d", Config.CurrentVersion)
+	if Config.CurrentVersion != 2 {
+		t.Fatalf("expected Config.CurrentVersion=2, got %d", Config.CurrentVersion)
 	}
 	if State.CurrentVersion != 1 {
 		t.Fatalf("expected State.CurrentVersion=1, got %d", State.CurrentVersion)
@@ -70,7 +70,7 @@ func (r *Registry) HasDev() bool {
 }
 
 // Config is the migration registry for config.toml files.
-var Config = &Registry{CurrentVersion: 1}
+var Config = &Registry{CurrentVersion: 2}
 
 // State is the migration registry for state.json files.
 var State = &Registry{CurrentVersion: 1}
@@ -0,0 +1,185 @@
+// Package netclient provides the HTTP client shared by every package that
+// reaches the network: pricing, tier data and the update check.
+//
+// [Configure] applies the [network] config section once at startup: proxy,
+// extra CA certificates, User-Agent and timeouts. In offline mode every
+// request fails immediately with [ErrOffline], and callers check [Offline] to
+// skip network work entirely and run from local data.
+package netclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Settings
+// ///////////////////////////////////////////////
+
+// Default values applied when the corresponding [Settings] field is zero.
+const (
+	// DefaultUserAgent is sent when no User-Agent is configured.
+	DefaultUserAgent = "agentcord"
+	// DefaultTimeout bounds a whole request, including reading the body.
+	DefaultTimeout = 10 * time.Second
+	// DefaultConnectTimeout bounds dialing and the TLS handshake.
+	DefaultConnectTimeout = 5 * time.Second
+)
+
+// ErrOffline is returned for every request while offline mode is enabled.
+var ErrOffline = errors.New("network access disabled (offline mode)")
+
+// Settings configures the shared client.
+type Settings struct {
+	// Offline disables all network access.
+	Offline bool
+	// ProxyURL routes requests through this proxy. Empty uses the standard
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	ProxyURL string
+	// CABundle is a PEM file whose certificates are trusted in addition to
+	// the system roots.
+	CABundle string
+	// UserAgent is sent with every request that does not set its own.
+	UserAgent string
+	// Timeout bounds a whole request. Zero uses [DefaultTimeout].
+	Timeout time.Duration
+	// ConnectTimeout bounds dialing and the TLS handshake. Zero uses
+	// [DefaultConnectTimeout].
+	ConnectTimeout time.Duration
+}
+
+// state pairs the active settings with the client built from them.
+type state struct {
+	settings Settings
+	client   *http.Client
+}
+
+// current holds the active state. Swapped atomically by [Configure].
+var current atomic.Pointer[state]
+
+func init() {
+	c, _ := newClient(Settings{})
+	current.Store(&state{client: c})
+}
+
+// ///////////////////////////////////////////////
+// Public API
+// ///////////////////////////////////////////////
+
+// Configure builds a client from s and makes it the shared client. Returns an
+// error, leaving the previous client in place, when the proxy URL is invalid
+// or the CA bundle cannot be loaded.
+func Configure(s Settings) error {
+	c, err := newClient(s)
+	if err != nil {
+		return err
+	}
+	current.Store(&state{settings: s, client: c})
+	return nil
+}
+
+// Client returns the shared HTTP client. Callers should fetch it per request
+// rather than caching it, so a later [Configure] takes effect.
+func Client() *http.Client {
+	return current.Load().client
+}
+
+// Offline reports whether network access is disabled.
+func Offline() bool {
+	return current.Load().settings.Offline
+}
+
+// ///////////////////////////////////////////////
+// Internal helpers
+// ///////////////////////////////////////////////
+
+// newClient builds an HTTP client enforcing s.
+func newClient(s Settings) (*http.Client, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	connectTimeout := s.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultConnectTimeout
+	}
+	ua := s.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	tr.TLSHandshakeTimeout = connectTimeout
+
+	if s.ProxyURL != "" {
+		u, err := url.Parse(s.ProxyURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", s.ProxyURL)
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+
+	if s.CABundle != "" {
+		pool, err := loadCABundle(s.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &transport{base: tr, userAgent: ua, offline: s.Offline},
+	}, nil
+}
+
+// loadCABundle returns the system root pool extended with the PEM
+// certificates in path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// transport applies the User-Agent and offline settings on top of base.
+type transport struct {
+	// base performs the actual round trip.
+	base http.RoundTripper
+	// userAgent is set on requests that have no User-Agent header.
+	userAgent string
+	// offline rejects every request with [ErrOffline].
+	offline bool
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.offline {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrOffline
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,157 @@
+package netclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// configure applies s for the duration of the test and restores defaults after.
+func configure(t *testing.T, s Settings) {
+	t.Helper()
+	if err := Configure(s); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	t.Cleanup(func() { _ = Configure(Settings{}) })
+}
+
+// ///////////////////////////////////////////////
+// Client Tests
+// ///////////////////////////////////////////////
+
+func TestClient_UserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		ua   string
+		want string
+	}{
+		{"", DefaultUserAgent},
+		{"agentcord/1.2.3 (+ci)", "agentcord/1.2.3 (+ci)"},
+	} {
+		configure(t, Settings{UserAgent: tt.ua})
+		resp, err := Client().Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+		if got != tt.want {
+			t.Errorf("User-Agent = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestClient_Offline(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	configure(t, Settings{Offline: true})
+	if !Offline() {
+		t.Fatal("Offline() = false, want true")
+	}
+	_, err := Client().Get(server.URL)
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("Get error = %v, want ErrOffline", err)
+	}
+	if called {
+		t.Error("server was contacted in offline mode")
+	}
+}
+
+func TestClient_Proxy(t *testing.T) {
+	var gotURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	configure(t, Settings{ProxyURL: proxy.URL})
+	resp, err := Client().Get("http://pricing.example.invalid/models")
+	if err != nil {
+		t.Fatalf("Get via proxy: %v", err)
+	}
+	resp.Body.Close()
+	if gotURL != "http://pricing.example.invalid/models" {
+		t.Errorf("proxy saw %q, want the absolute target URL", gotURL)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	configure(t, Settings{Timeout: 50 * time.Millisecond})
+	if _, err := Client().Get(server.URL); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestClient_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's self-signed certificate is untrusted by default.
+	if _, err := Client().Get(server.URL); err == nil {
+		t.Fatal("expected certificate error without CA bundle")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(block), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configure(t, Settings{CABundle: bundle})
+	resp, err := Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get with CA bundle: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// ///////////////////////////////////////////////
+// Configure Tests
+// ///////////////////////////////////////////////
+
+func TestConfigure_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		s    Settings
+	}{
+		{"proxy without host", Settings{ProxyURL: "not a url"}},
+		{"missing CA bundle", Settings{CABundle: filepath.Join(dir, "missing.pem")}},
+		{"CA bundle without certificates", Settings{CABundle: notPEM}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := Client()
+			if err := Configure(tt.s); err == nil {
+				t.Error("expected error")
+			}
+			if Client() != before {
+				t.Error("failed Configure replaced the shared client")
+			}
+		})
+	}
+}
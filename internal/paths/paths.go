@@ -5,7 +5,10 @@ package paths
 // Generate constants.sh and constants.ps1 for hook scripts.
 //go:generate go run ../../cmd/genhooks
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+)
 
 // ///////////////////////////////////////////////
 // Constants
@@ -20,14 +23,87 @@ const (
 	ConversationsDir = "conversations"
 	PricingCacheFile = "pricing-cache.json"
 	TiersCacheFile   = "tiers-cache.json"
+
+	PricingDiagnosticsFile = "pricing-diagnostics.json"
+	PricingCacheMetaFile   = "pricing-cache.meta.json"
+	TiersCacheMetaFile     = "tiers-cache.meta.json"
+	CurrencyCacheFile      = "currency-cache.json"
+	CurrencyCacheMetaFile  = "currency-cache.meta.json"
+	UsageLedgerFile        = "usage-ledger.jsonl"
+	BudgetAlertsFile       = "budget-alerts.json"
+	StatuslineFile         = "statusline.json"
+	StatuslineCacheFile    = "statusline-cache.json"
+	StatuslinePricesFile   = "statusline-prices.json"
+	StatuslineBackupFile   = "statusline-original.json"
 )
 
-// StateFileForClient returns the per-client state file name.
+// StateFilePattern is the [filepath.Match] pattern matching per-session
+// state files and the legacy per-client ones.
+const StateFilePattern = "state.*.json"
+
+// StateFileForSession returns the per-session state file name, with the
+// session ID passed through [SanitizeSessionID].
+// For example, StateFileForSession("claude-code", "abc") returns
+// "state.claude-code.abc.json".
+func StateFileForSession(client, sessionID string) string {
+	return "state." + client + "." + SanitizeSessionID(sessionID) + ".json"
+}
+
+// StateFileForClient returns the legacy per-client state file name, written
+// by hooks before state files were kept per session.
 // For example, StateFileForClient("claude-code") returns "state.claude-code.json".
 func StateFileForClient(client string) string {
 	return "state." + client + ".json"
 }
 
+// SanitizeSessionID returns sessionID with every character outside
+// [A-Za-z0-9_-] replaced by "_", so it can be used in a file name without
+// escaping the data directory. The hooks apply the same mapping.
+func SanitizeSessionID(sessionID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, sessionID)
+}
+
+// IsStateFile reports whether the base name of path is a state file: a
+// per-session or legacy per-client file, or the legacy state.json.
+func IsStateFile(path string) bool {
+	base := filepath.Base(path)
+	if base == StateFile {
+		return true
+	}
+	matched, _ := filepath.Match(StateFilePattern, base)
+	return matched
+}
+
+// IsSessionStateFile reports whether the base name of path is a per-session
+// state file, i.e. state.<client>.<session>.json. Session IDs written by
+// older hooks may contain dots, so everything after the client counts as
+// the session ID.
+func IsSessionStateFile(path string) bool {
+	base := filepath.Base(path)
+	name, ok := strings.CutPrefix(base, "state.")
+	if !ok {
+		return false
+	}
+	name, ok = strings.CutSuffix(name, ".json")
+	if !ok {
+		return false
+	}
+	client, session, ok := strings.Cut(name, ".")
+	return ok && client != "" && session != ""
+}
+
+// IsLegacyClientStateFile reports whether the base name of path is a legacy
+// per-client state file, i.e. state.<client>.json.
+func IsLegacyClientStateFile(path string) bool {
+	return IsStateFile(path) && filepath.Base(path) != StateFile && !IsSessionStateFile(path)
+}
+
 // Hook script constants — consumed by cmd/genhooks to generate
 // _constants.sh and _constants.ps1 for the shell/PowerShell hooks.
 const (
@@ -73,10 +149,51 @@ func (d DataDir) PricingCache() string { return filepath.Join(d.Root, PricingCac
 // TiersCache returns the full path to the tiers cache file.
 func (d DataDir) TiersCache() string { return filepath.Join(d.Root, TiersCacheFile) }
 
+// PricingCacheMeta returns the full path to the pricing cache fetch metadata file.
+func (d DataDir) PricingCacheMeta() string { return filepath.Join(d.Root, PricingCacheMetaFile) }
+
+// TiersCacheMeta returns the full path to the tiers cache fetch metadata file.
+func (d DataDir) TiersCacheMeta() string { return filepath.Join(d.Root, TiersCacheMetaFile) }
+
+// CurrencyCache returns the full path to the exchange rate cache file.
+func (d DataDir) CurrencyCache() string { return filepath.Join(d.Root, CurrencyCacheFile) }
+
+// CurrencyCacheMeta returns the full path to the exchange rate cache fetch metadata file.
+func (d DataDir) CurrencyCacheMeta() string { return filepath.Join(d.Root, CurrencyCacheMetaFile) }
+
+// UsageLedger returns the full path to the append-only usage ledger.
+func (d DataDir) UsageLedger() string { return filepath.Join(d.Root, UsageLedgerFile) }
+
+// BudgetAlerts returns the full path to the fired budget alerts file.
+func (d DataDir) BudgetAlerts() string { return filepath.Join(d.Root, BudgetAlertsFile) }
+
+// Statusline returns the full path to the saved Claude Code statusline payload.
+func (d DataDir) Statusline() string { return filepath.Join(d.Root, StatuslineFile) }
+
+// StatuslineCache returns the full path to the statusline command's saved
+// transcript parse state.
+func (d DataDir) StatuslineCache() string { return filepath.Join(d.Root, StatuslineCacheFile) }
+
+// StatuslinePrices returns the full path to the statusline command's snapshot
+// of the model prices it uses.
+func (d DataDir) StatuslinePrices() string { return filepath.Join(d.Root, StatuslinePricesFile) }
+
+// StatuslineBackup returns the full path to the statusLine setting the
+// statusline setup script replaced.
+func (d DataDir) StatuslineBackup() string { return filepath.Join(d.Root, StatuslineBackupFile) }
+
+// PricingDiagnostics returns the full path to the pricing diagnostics file.
+func (d DataDir) PricingDiagnostics() string { return filepath.Join(d.Root, PricingDiagnosticsFile) }
+
 // Sessions returns the full path to the sessions directory.
 func (d DataDir) Sessions() string { return filepath.Join(d.Root, SessionsDir) }
 
-// StateForClient returns the full path to the per-client state file.
+// StateForSession returns the full path to the per-session state file.
+func (d DataDir) StateForSession(client, sessionID string) string {
+	return filepath.Join(d.Root, StateFileForSession(client, sessionID))
+}
+
+// StateForClient returns the full path to the legacy per-client state file.
 func (d DataDir) StateForClient(client string) string {
 	return filepath.Join(d.Root, StateFileForClient(client))
 }
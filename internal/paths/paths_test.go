@@ -27,6 +27,17 @@ func TestConstantValues(t *testing.T) {
 		{"ConversationsDir", ConversationsDir, "conversations"},
 		{"PricingCacheFile", PricingCacheFile, "pricing-cache.json"},
 		{"TiersCacheFile", TiersCacheFile, "tiers-cache.json"},
+		{"PricingDiagnosticsFile", PricingDiagnosticsFile, "pricing-diagnostics.json"},
+		{"PricingCacheMetaFile", PricingCacheMetaFile, "pricing-cache.meta.json"},
+		{"TiersCacheMetaFile", TiersCacheMetaFile, "tiers-cache.meta.json"},
+		{"CurrencyCacheFile", CurrencyCacheFile, "currency-cache.json"},
+		{"CurrencyCacheMetaFile", CurrencyCacheMetaFile, "currency-cache.meta.json"},
+		{"UsageLedgerFile", UsageLedgerFile, "usage-ledger.jsonl"},
+		{"BudgetAlertsFile", BudgetAlertsFile, "budget-alerts.json"},
+		{"StatuslineFile", StatuslineFile, "statusline.json"},
+		{"StatuslineCacheFile", StatuslineCacheFile, "statusline-cache.json"},
+		{"StatuslinePricesFile", StatuslinePricesFile, "statusline-prices.json"},
+		{"StatuslineBackupFile", StatuslineBackupFile, "statusline-original.json"},
 		{"SessionsDir", SessionsDir, "sessions"},
 		{"SessionExt", SessionExt, ".session"},
 		{"BinaryName", BinaryName, "agentcord"},
@@ -63,6 +74,19 @@ func TestDataDirMethods(t *testing.T) {
 		{"Conversations", d.Conversations(), filepath.Join(root, "conversations")},
 		{"PricingCache", d.PricingCache(), filepath.Join(root, "pricing-cache.json")},
 		{"TiersCache", d.TiersCache(), filepath.Join(root, "tiers-cache.json")},
+		{"PricingDiagnostics", d.PricingDiagnostics(), filepath.Join(root, "pricing-diagnostics.json")},
+		{"PricingCacheMeta", d.PricingCacheMeta(), filepath.Join(root, "pricing-cache.meta.json")},
+		{"TiersCacheMeta", d.TiersCacheMeta(), filepath.Join(root, "tiers-cache.meta.json")},
+		{"CurrencyCache", d.CurrencyCache(), filepath.Join(root, "currency-cache.json")},
+		{"CurrencyCacheMeta", d.CurrencyCacheMeta(), filepath.Join(root, "currency-cache.meta.json")},
+		{"UsageLedger", d.UsageLedger(), filepath.Join(root, "usage-ledger.jsonl")},
+		{"BudgetAlerts", d.BudgetAlerts(), filepath.Join(root, "budget-alerts.json")},
+		{"Statusline", d.Statusline(), filepath.Join(root, "statusline.json")},
+		{"StatuslineCache", d.StatuslineCache(), filepath.Join(root, "statusline-cache.json")},
+		{"StatuslinePrices", d.StatuslinePrices(), filepath.Join(root, "statusline-prices.json")},
+		{"StatuslineBackup", d.StatuslineBackup(), filepath.Join(root, "statusline-original.json")},
+		{"StateForSession", d.StateForSession("claude-code", "abc"), filepath.Join(root, "state.claude-code.abc.json")},
+		{"StateForClient", d.StateForClient("claude-code"), filepath.Join(root, "state.claude-code.json")},
 	}
 
 	for _, tt := range tests {
@@ -86,6 +110,56 @@ func TestDataDirEmptyRoot(t *testing.T) {
 	}
 }
 
+func TestIsStateFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       bool
+		sessionFile bool
+		legacy      bool
+	}{
+		{"state.json", true, false, false},
+		{"state.claude-code.json", true, false, true},
+		{"state.claude-code.abc-123.json", true, true, false},
+		{"state.claude-code.a.b.json", true, true, false},
+		{filepath.Join("dir", "state.cursor.s1.json"), true, true, false},
+		{"state.claude-code.json.tmp.42", false, false, false},
+		{"state.json.corrupted", false, false, false},
+		{"statusline.json", false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStateFile(tt.name); got != tt.state {
+				t.Errorf("IsStateFile(%q) = %v, want %v", tt.name, got, tt.state)
+			}
+			if got := IsSessionStateFile(tt.name); got != tt.sessionFile {
+				t.Errorf("IsSessionStateFile(%q) = %v, want %v", tt.name, got, tt.sessionFile)
+			}
+			if got := IsLegacyClientStateFile(tt.name); got != tt.legacy {
+				t.Errorf("IsLegacyClientStateFile(%q) = %v, want %v", tt.name, got, tt.legacy)
+			}
+		})
+	}
+}
+
+func TestSanitizeSessionID(t *testing.T) {
+	tests := []struct {
+		id, want string
+	}{
+		{"0f9e1c2a-7b3d-4e5f-8a9b-0c1d2e3f4a5b", "0f9e1c2a-7b3d-4e5f-8a9b-0c1d2e3f4a5b"},
+		{"abc_DEF-123", "abc_DEF-123"},
+		{"../a.b/c", "___a_b_c"},
+		{`a\b c`, "a_b_c"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeSessionID(tt.id); got != tt.want {
+			t.Errorf("SanitizeSessionID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+	if got, want := StateFileForSession("claude-code", "../x"), "state.claude-code.___x.json"; got != want {
+		t.Errorf("StateFileForSession = %q, want %q", got, want)
+	}
+}
+
 // ///////////////////////////////////////////////
 // Constants.sh Sync Tests
 // ///////////////////////////////////////////////
@@ -156,10 +230,10 @@ func TestConstantsShMatchesGoConstants(t *testing.T) {
 			t.Fatalf("STATE_VERSION is not an integer: %v", err)
 		}
 		// CurrentVersion is in the session package, but we can verify the
-		// shell file declares it as 1 (which is the known current version).
+		// shell file declares it as 2 (which is the known current version).
 		// We avoid importing the session package to prevent circular deps.
-		if n != 1 {
-			t.Errorf("constants.sh STATE_VERSION = %d, expected 1", n)
+		if n != 2 {
+			t.Errorf("constants.sh STATE_VERSION = %d, expected 2", n)
 		}
 	})
 }
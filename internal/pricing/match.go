@@ -0,0 +1,177 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/paths"
+)
+
+// ///////////////////////////////////////////////
+// Model ID Normalization
+// ///////////////////////////////////////////////
+
+// dateSuffixRegex matches a trailing release date such as "-20250929" or the
+// Vertex-style "@20250929".
+var dateSuffixRegex = regexp.MustCompile(`[-@]\d{8}$`)
+
+// NormalizeModelID reduces a model identifier to a canonical form so IDs from
+// different sources can be compared. It lowercases the ID, strips any
+// provider prefix ("anthropic/"), any bracketed variant suffix ("[1m]"), and
+// any trailing release date ("-20250929"), and replaces dots with dashes
+// ("claude-opus-4.6" -> "claude-opus-4-6").
+func NormalizeModelID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if idx := strings.LastIndexByte(id, '/'); idx >= 0 {
+		id = id[idx+1:]
+	}
+	if idx := strings.IndexByte(id, '['); idx >= 0 {
+		id = id[:idx]
+	}
+	id = dateSuffixRegex.ReplaceAllString(id, "")
+	return strings.ReplaceAll(id, ".", "-")
+}
+
+// ///////////////////////////////////////////////
+// Model Matching
+// ///////////////////////////////////////////////
+
+// buildIndex populates the normalized model and alias lookup tables.
+// When several pricing keys normalize to the same ID, the shortest key wins
+// (ties broken lexically) so the result is deterministic.
+func (pd *PricingData) buildIndex() {
+	pd.normalized = make(map[string]string, len(pd.Models))
+	for key := range pd.Models {
+		norm := NormalizeModelID(key)
+		if prev, ok := pd.normalized[norm]; ok {
+			if len(prev) < len(key) || (len(prev) == len(key) && prev < key) {
+				continue
+			}
+		}
+		pd.normalized[norm] = key
+	}
+	pd.aliases = make(map[string]string, len(pd.Aliases))
+	for from, to := range pd.Aliases {
+		pd.aliases[NormalizeModelID(from)] = to
+	}
+}
+
+// lookupKey finds the pricing key for model by exact match, then by
+// normalized match.
+func (pd *PricingData) lookupKey(model string) (string, bool) {
+	if _, ok := pd.Models[model]; ok {
+		return model, true
+	}
+	key, ok := pd.normalized[NormalizeModelID(model)]
+	return key, ok
+}
+
+// Resolve returns the key in Models that prices the given model ID.
+//
+// Matching tries, in order: an exact key, a user-defined alias (compared after
+// normalization, with the target itself matched exactly or normalized), and a
+// normalized key. Misses are logged once per model and reported by
+// [PricingData.Diagnostics].
+func (pd *PricingData) Resolve(model string) (string, bool) {
+	if pd == nil || model == "" {
+		return "", false
+	}
+	pd.indexOnce.Do(pd.buildIndex)
+
+	if _, ok := pd.Models[model]; ok {
+		return model, true
+	}
+
+	key, ok := "", false
+	if target, isAlias := pd.aliases[NormalizeModelID(model)]; isAlias {
+		key, ok = pd.lookupKey(target)
+	}
+	if !ok {
+		key, ok = pd.lookupKey(model)
+	}
+
+	pd.diagMu.Lock()
+	defer pd.diagMu.Unlock()
+	if ok {
+		if pd.matched == nil {
+			pd.matched = make(map[string]string)
+		}
+		pd.matched[model] = key
+		return key, true
+	}
+	if pd.unmatched == nil {
+		pd.unmatched = make(map[string]bool)
+	}
+	if !pd.unmatched[model] {
+		pd.unmatched[model] = true
+		slog.Warn("no pricing found for model, cost will show as $0", "model", model)
+	}
+	return "", false
+}
+
+// ///////////////////////////////////////////////
+// Diagnostics
+// ///////////////////////////////////////////////
+
+// Diagnostics summarizes how model IDs seen at runtime were matched against
+// the loaded pricing data.
+type Diagnostics struct {
+	// ModelCount is the number of models in the loaded pricing data.
+	ModelCount int `json:"model_count"`
+	// Matched maps model IDs that needed normalization or an alias to the
+	// pricing key they resolved to. Exact matches are not listed.
+	Matched map[string]string `json:"matched,omitempty"`
+	// Unmatched lists model IDs that had no pricing, sorted.
+	Unmatched []string `json:"unmatched,omitempty"`
+}
+
+// Diagnostics returns a snapshot of the model matching results so far.
+func (pd *PricingData) Diagnostics() Diagnostics {
+	if pd == nil {
+		return Diagnostics{}
+	}
+	pd.diagMu.Lock()
+	defer pd.diagMu.Unlock()
+
+	d := Diagnostics{ModelCount: len(pd.Models)}
+	if len(pd.matched) > 0 {
+		d.Matched = make(map[string]string, len(pd.matched))
+		for k, v := range pd.matched {
+			d.Matched[k] = v
+		}
+	}
+	for m := range pd.unmatched {
+		d.Unmatched = append(d.Unmatched, m)
+	}
+	slices.Sort(d.Unmatched)
+	return d
+}
+
+// Equal reports whether d and other hold the same model count and the same
+// matched and unmatched model IDs.
+func (d Diagnostics) Equal(other Diagnostics) bool {
+	return d.ModelCount == other.ModelCount &&
+		maps.Equal(d.Matched, other.Matched) &&
+		slices.Equal(d.Unmatched, other.Unmatched)
+}
+
+// WriteDiagnostics writes matching diagnostics to a file in the given directory
+// so users can see which models are unpriced without digging through logs.
+func WriteDiagnostics(dir string, d Diagnostics) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating diagnostics directory: %w", err)
+	}
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling pricing diagnostics: %w", err)
+	}
+	return atomicfile.Write(filepath.Join(dir, paths.PricingDiagnosticsFile), b, 0o644)
+}
@@ -0,0 +1,176 @@
+package pricing
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tools.zach/dev/agentcord/internal/paths"
+)
+
+// ///////////////////////////////////////////////
+// NormalizeModelID Tests
+// ///////////////////////////////////////////////
+
+func TestNormalizeModelID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"claude-opus-4-6", "claude-opus-4-6"},
+		{"anthropic/claude-opus-4.6", "claude-opus-4-6"},
+		{"claude-sonnet-4-5-20250929", "claude-sonnet-4-5"},
+		{"claude-3-5-sonnet@20240620", "claude-3-5-sonnet"},
+		{"Claude-Opus-4-6", "claude-opus-4-6"},
+		{"claude-opus-4-6[1m]", "claude-opus-4-6"},
+		{"openrouter/anthropic/claude-haiku-4.5", "claude-haiku-4-5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := NormalizeModelID(tt.in); got != tt.want {
+				t.Errorf("NormalizeModelID(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// ///////////////////////////////////////////////
+// Resolve Tests
+// ///////////////////////////////////////////////
+
+func TestResolve(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4.6":            {InputPerToken: 1},
+			"claude-sonnet-4-5-20250929": {InputPerToken: 2},
+			"claude-haiku-4-5":           {InputPerToken: 3},
+		},
+		Aliases: map[string]string{
+			"my-proxy-opus": "claude-opus-4-6",
+		},
+	}
+
+	tests := []struct {
+		model  string
+		want   string
+		wantOK bool
+	}{
+		{"claude-haiku-4-5", "claude-haiku-4-5", true},
+		{"claude-opus-4-6", "claude-opus-4.6", true},
+		{"claude-sonnet-4-5", "claude-sonnet-4-5-20250929", true},
+		{"anthropic/claude-haiku-4.5", "claude-haiku-4-5", true},
+		{"my-proxy-opus", "claude-opus-4.6", true},
+		{"gpt-4", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := pd.Resolve(tt.model)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Resolve(%q) = (%q, %v), want (%q, %v)", tt.model, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolve_NilPricingData(t *testing.T) {
+	var pd *PricingData
+	if _, ok := pd.Resolve("claude-opus-4-6"); ok {
+		t.Error("Resolve on nil PricingData should not match")
+	}
+}
+
+func TestResolve_DuplicateNormalizedKeysDeterministic(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4-6-20250101": {InputPerToken: 1},
+			"claude-opus-4.6":          {InputPerToken: 2},
+		},
+	}
+	got, ok := pd.Resolve("anthropic/claude-opus-4-6")
+	if !ok || got != "claude-opus-4.6" {
+		t.Errorf("Resolve = (%q, %v), want (%q, true)", got, ok, "claude-opus-4.6")
+	}
+}
+
+func TestCalculate_NormalizedMatch(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4.6": {InputPerToken: 0.000015, OutputPerToken: 0.000075},
+		},
+	}
+	if cost := pd.Calculate("claude-opus-4-6-20260101", 1000, 0); math.Abs(cost-0.015) > 1e-12 {
+		t.Errorf("Calculate = %v, want 0.015", cost)
+	}
+}
+
+func TestSubset(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4.6":  {InputPerToken: 0.000015},
+			"claude-haiku-4.5": {InputPerToken: 0.000001},
+		},
+		Aliases: map[string]string{"my-model": "claude-haiku-4.5"},
+	}
+	got := pd.Subset([]string{"claude-opus-4-6-20260101", "my-model", "unknown"})
+	if len(got) != 2 || got["claude-opus-4-6-20260101"].InputPerToken != 0.000015 || got["my-model"].InputPerToken != 0.000001 {
+		t.Errorf("Subset = %+v, want opus and the aliased haiku keyed by the given IDs", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Diagnostics Tests
+// ///////////////////////////////////////////////
+
+func TestDiagnostics(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4-6": {InputPerToken: 1},
+		},
+	}
+	pd.Resolve("claude-opus-4-6")
+	pd.Resolve("anthropic/claude-opus-4.6")
+	pd.Resolve("gpt-4")
+	pd.Resolve("gpt-4")
+	pd.Resolve("custom-model")
+
+	d := pd.Diagnostics()
+	if d.ModelCount != 1 {
+		t.Errorf("ModelCount = %d, want 1", d.ModelCount)
+	}
+	if len(d.Matched) != 1 || d.Matched["anthropic/claude-opus-4.6"] != "claude-opus-4-6" {
+		t.Errorf("Matched = %v, want only anthropic/claude-opus-4.6 -> claude-opus-4-6", d.Matched)
+	}
+	if len(d.Unmatched) != 2 || d.Unmatched[0] != "custom-model" || d.Unmatched[1] != "gpt-4" {
+		t.Errorf("Unmatched = %v, want [custom-model gpt-4]", d.Unmatched)
+	}
+
+	// Equal compares the IDs, not just how many there are.
+	other := d
+	other.Unmatched = []string{"custom-model", "gpt-5"}
+	if !d.Equal(pd.Diagnostics()) || d.Equal(other) {
+		t.Errorf("Equal: same = %v, different unmatched = %v", d.Equal(pd.Diagnostics()), d.Equal(other))
+	}
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	in := Diagnostics{ModelCount: 3, Unmatched: []string{"gpt-4"}}
+
+	if err := WriteDiagnostics(dir, in); err != nil {
+		t.Fatalf("WriteDiagnostics: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, paths.PricingDiagnosticsFile))
+	if err != nil {
+		t.Fatalf("reading diagnostics: %v", err)
+	}
+	var out Diagnostics
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("parsing diagnostics: %v", err)
+	}
+	if out.ModelCount != 3 || len(out.Unmatched) != 1 || out.Unmatched[0] != "gpt-4" {
+		t.Errorf("round-trip = %+v, want %+v", out, in)
+	}
+}
@@ -3,8 +3,9 @@
 // Pricing data can come from three source types: a remote URL (OpenRouter, LiteLLM,
 // or Agentcord format), a local file, or static inline values defined in config.
 // For URL and file sources, a double-fallback strategy applies: primary source,
-// then on-disk cache. If both fail, no pricing data is available and costs show
-// as $0.
+// then on-disk cache. If both fail, no pricing data is available and the daemon
+// shows presence without cost until a later fetch succeeds. In offline mode
+// (see [netclient.Offline]) URL sources read only the cache.
 //
 // The [Fetch] function is the main entry point. It accepts a [SourceConfig] that
 // describes the pricing source and returns a [PricingData] value ready for use
@@ -12,9 +13,10 @@
 package pricing
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -26,26 +28,26 @@ import (
 
 	"github.com/hashicorp/go-retryablehttp"
 	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/httpfetch"
+	"tools.zach/dev/agentcord/internal/netclient"
 	"tools.zach/dev/agentcord/internal/paths"
 )
 
-// httpClient is a lazily-initialized retryablehttp client shared across all
-// pricing fetches. Initialized once via httpClientOnce.
-var (
-	httpClient     *retryablehttp.Client
-	httpClientOnce sync.Once
-)
-
-// getHTTPClient returns the shared retryable HTTP client, initializing it on
-// first call.
+// getHTTPClient returns a retryable client layered over the shared
+// [netclient.Client], so proxy, CA, User-Agent, timeout and offline settings
+// apply to pricing fetches. Offline errors are not retried.
 func getHTTPClient() *retryablehttp.Client {
-	httpClientOnce.Do(func() {
-		httpClient = retryablehttp.NewClient()
-		httpClient.RetryMax = 2
-		httpClient.HTTPClient.Timeout = 10 * time.Second
-		httpClient.Logger = nil // suppress retryablehttp's default logging
-	})
-	return httpClient
+	c := retryablehttp.NewClient()
+	c.HTTPClient = netclient.Client()
+	c.RetryMax = 2
+	c.Logger = nil // suppress retryablehttp's default logging
+	c.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if errors.Is(err, netclient.ErrOffline) {
+			return false, err
+		}
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+	return c
 }
 
 // formatDefaultURLs maps format names to their default pricing API endpoints.
@@ -68,30 +70,175 @@ type SourceConfig struct {
 
 	// Static model prices (for source = "static").
 	Models map[string]ModelPricing
+
+	// Aliases maps model IDs to pricing keys, consulted when a model ID
+	// has no exact match (e.g. "my-proxy-opus" -> "claude-opus-4-6").
+	Aliases map[string]string
 }
 
 // PricingData holds pricing information for Claude models.
+//
+// Model IDs are matched through [PricingData.Resolve], so a PricingData must
+// not be copied after first use.
 type PricingData struct {
 	Models map[string]ModelPricing `json:"models"`
+
+	// Aliases maps user-defined model IDs to pricing keys. Not cached.
+	Aliases map[string]string `json:"-"`
+
+	// indexOnce guards lazy construction of normalized and aliases.
+	indexOnce sync.Once
+	// normalized maps normalized model IDs to their key in Models.
+	normalized map[string]string
+	// aliases maps normalized alias IDs to their target model ID.
+	aliases map[string]string
+
+	// diagMu guards matched and unmatched.
+	diagMu sync.Mutex
+	// matched records non-exact matches for diagnostics.
+	matched map[string]string
+	// unmatched records model IDs with no pricing for diagnostics.
+	unmatched map[string]bool
 }
 
 // ModelPricing holds per-token pricing for a model.
+//
+// Cache prices are optional. A zero CacheWrite1hPerToken falls back to
+// CacheWrite5mPerToken, since most sources only publish a single cache write
+// price. Other zero cache prices leave that token class unpriced.
+//
+// Bands hold long-context rates that replace the base rates for a request
+// whose prompt exceeds the band's threshold.
+//
+// ContextWindow is the model's maximum prompt size in tokens, or 0 when the
+// source does not publish it.
 type ModelPricing struct {
-	InputPerToken  float64 `json:"input_per_token"`
-	OutputPerToken float64 `json:"output_per_token"`
+	InputPerToken        float64     `json:"input_per_token"`
+	OutputPerToken       float64     `json:"output_per_token"`
+	CacheWrite5mPerToken float64     `json:"cache_write_5m_per_token,omitempty"`
+	CacheWrite1hPerToken float64     `json:"cache_write_1h_per_token,omitempty"`
+	CacheReadPerToken    float64     `json:"cache_read_per_token,omitempty"`
+	Bands                []PriceBand `json:"bands,omitempty"`
+	ContextWindow        int64       `json:"context_window,omitempty"`
+}
+
+// PriceBand holds the per-token rates that apply once a request's prompt
+// exceeds AboveInputTokens. The prompt size is the sum of input, cache write,
+// and cache read tokens. Zero rates inherit the model's base rate.
+type PriceBand struct {
+	AboveInputTokens     int64   `json:"above_input_tokens"`
+	InputPerToken        float64 `json:"input_per_token,omitempty"`
+	OutputPerToken       float64 `json:"output_per_token,omitempty"`
+	CacheWrite5mPerToken float64 `json:"cache_write_5m_per_token,omitempty"`
+	CacheWrite1hPerToken float64 `json:"cache_write_1h_per_token,omitempty"`
+	CacheReadPerToken    float64 `json:"cache_read_per_token,omitempty"`
+}
+
+// forPrompt returns the rates for a request with the given prompt size: the
+// base rates overlaid with the highest band whose threshold the prompt exceeds.
+func (mp ModelPricing) forPrompt(promptTokens int64) ModelPricing {
+	var band *PriceBand
+	for i := range mp.Bands {
+		b := &mp.Bands[i]
+		if promptTokens > b.AboveInputTokens && (band == nil || b.AboveInputTokens > band.AboveInputTokens) {
+			band = b
+		}
+	}
+	if band == nil {
+		return mp
+	}
+	overlay := func(base *float64, v float64) {
+		if v != 0 {
+			*base = v
+		}
+	}
+	// A band without a 1-hour write rate scales the base 1-hour rate by the
+	// band's 5-minute increase, keeping the 1h/5m ratio.
+	if band.CacheWrite1hPerToken == 0 && band.CacheWrite5mPerToken != 0 && mp.CacheWrite5mPerToken != 0 {
+		mp.CacheWrite1hPerToken *= band.CacheWrite5mPerToken / mp.CacheWrite5mPerToken
+	}
+	overlay(&mp.InputPerToken, band.InputPerToken)
+	overlay(&mp.OutputPerToken, band.OutputPerToken)
+	overlay(&mp.CacheWrite5mPerToken, band.CacheWrite5mPerToken)
+	overlay(&mp.CacheWrite1hPerToken, band.CacheWrite1hPerToken)
+	overlay(&mp.CacheReadPerToken, band.CacheReadPerToken)
+	mp.Bands = nil
+	return mp
+}
+
+// Usage holds token counts for every billable token class of a model call.
+type Usage struct {
+	// InputTokens is the number of uncached input tokens.
+	InputTokens int64
+	// OutputTokens is the number of output tokens.
+	OutputTokens int64
+	// CacheWrite5mTokens is the number of input tokens written to the 5-minute cache.
+	CacheWrite5mTokens int64
+	// CacheWrite1hTokens is the number of input tokens written to the 1-hour cache.
+	CacheWrite1hTokens int64
+	// CacheReadTokens is the number of input tokens served from cache.
+	CacheReadTokens int64
+	// PromptTokens, when set, is the prompt size that selects the
+	// long-context band, for usage summed over requests of similar size.
+	// Zero means the prompt size of u itself.
+	PromptTokens int64
 }
 
 // Calculate computes the cost for a given model and token counts.
-// Returns 0 if the model is not found in pricing data.
+// The model is matched via [PricingData.Resolve]. Returns 0 if no match is found.
 func (pd *PricingData) Calculate(model string, inputTokens, outputTokens int64) float64 {
-	if pd == nil {
+	return pd.CalculateUsage(model, Usage{InputTokens: inputTokens, OutputTokens: outputTokens})
+}
+
+// CalculateUsage computes the cost for a given model across every token class
+// in u, including cache writes and reads. The model is matched via
+// [PricingData.Resolve]. Returns 0 if no match is found.
+//
+// Long-context bands are selected by the prompt size of u, so u should
+// describe a single request; summing usage across requests before pricing
+// would overstate the prompt size unless PromptTokens is set.
+func (pd *PricingData) CalculateUsage(model string, u Usage) float64 {
+	key, ok := pd.Resolve(model)
+	if !ok {
 		return 0
 	}
-	mp, ok := pd.Models[model]
+	prompt := u.PromptTokens
+	if prompt == 0 {
+		prompt = u.InputTokens + u.CacheWrite5mTokens + u.CacheWrite1hTokens + u.CacheReadTokens
+	}
+	mp := pd.Models[key].forPrompt(prompt)
+	write1h := mp.CacheWrite1hPerToken
+	if write1h == 0 {
+		write1h = mp.CacheWrite5mPerToken
+	}
+	return float64(u.InputTokens)*mp.InputPerToken +
+		float64(u.OutputTokens)*mp.OutputPerToken +
+		float64(u.CacheWrite5mTokens)*mp.CacheWrite5mPerToken +
+		float64(u.CacheWrite1hTokens)*write1h +
+		float64(u.CacheReadTokens)*mp.CacheReadPerToken
+}
+
+// ContextWindow returns the maximum prompt size of model in tokens. The model
+// is matched via [PricingData.Resolve]. Returns 0 if no match is found or the
+// source does not publish a context window.
+func (pd *PricingData) ContextWindow(model string) int64 {
+	key, ok := pd.Resolve(model)
 	if !ok {
 		return 0
 	}
-	return float64(inputTokens)*mp.InputPerToken + float64(outputTokens)*mp.OutputPerToken
+	return pd.Models[key].ContextWindow
+}
+
+// Subset returns the pricing of each model ID in ids that resolves, keyed by
+// the ID as given, so a small table can stand in for pd for those models.
+func (pd *PricingData) Subset(ids []string) map[string]ModelPricing {
+	models := make(map[string]ModelPricing, len(ids))
+	for _, id := range ids {
+		if key, ok := pd.Resolve(id); ok {
+			models[id] = pd.Models[key]
+		}
+	}
+	return models
 }
 
 // ///////////////////////////////////////////////
@@ -105,26 +252,127 @@ func (pd *PricingData) Calculate(model string, inputTokens, outputTokens int64)
 //
 // Returns nil with an error when both primary and cache sources fail.
 // The returned error is non-nil when the data came from a cache fallback.
+// In offline mode "url" sources are served from the cache without an error.
+// Aliases from src are attached to the returned data.
 func Fetch(src SourceConfig, cacheDir string) (*PricingData, error) {
+	pd, err := fetchSource(src, cacheDir)
+	if pd != nil {
+		pd.Aliases = src.Aliases
+	}
+	return pd, err
+}
+
+// fetchSource dispatches to the loader for src.Source.
+func fetchSource(src SourceConfig, cacheDir string) (*PricingData, error) {
 	switch src.Source {
 	case "static":
 		return fetchStatic(src.Models)
 	case "file":
-		return fetchWithFallback(cacheDir, func() (*PricingData, error) {
+		pd, err := fetchWithFallback(cacheDir, func() (*PricingData, error) {
 			return fetchFromFile(src.File, src.Format)
 		})
+		if err == nil {
+			writeMeta(cacheDir, httpfetch.Meta{FetchedAt: time.Now(), SourceURL: src.File})
+		}
+		return pd, err
 	default: // "url"
-		url := src.URL
-		if url == "" {
-			url = formatDefaultURLs[src.Format]
+		if netclient.Offline() {
+			pd, err := ReadPricingCache(cacheDir)
+			if err != nil {
+				return nil, fmt.Errorf("offline and no pricing cache: %w", err)
+			}
+			return pd, nil
 		}
-		if url == "" {
-			return nil, fmt.Errorf("no URL configured and format %q has no default URL", src.Format)
+		url, err := sourceURL(src)
+		if err != nil {
+			return nil, err
 		}
-		format := src.Format
-		return fetchWithFallback(cacheDir, func() (*PricingData, error) {
-			return fetchFromURL(url, format)
+		var meta httpfetch.Meta
+		pd, err := fetchWithFallback(cacheDir, func() (*PricingData, error) {
+			pd, m, err := fetchFromURLConditional(url, src.Format, httpfetch.Meta{})
+			meta = m
+			return pd, err
 		})
+		if err == nil {
+			writeMeta(cacheDir, meta)
+		}
+		return pd, err
+	}
+}
+
+// sourceURL returns the pricing URL for src, falling back to the format's
+// default endpoint.
+func sourceURL(src SourceConfig) (string, error) {
+	url := src.URL
+	if url == "" {
+		url = formatDefaultURLs[src.Format]
+	}
+	if url == "" {
+		return "", fmt.Errorf("no URL configured and format %q has no default URL", src.Format)
+	}
+	return url, nil
+}
+
+// Refresh re-fetches pricing data for a long-running daemon. URL sources are
+// revalidated with the ETag and Last-Modified values recorded by the previous
+// fetch; file sources are re-read only when the file changed since then.
+//
+// Returns nil data and a nil error when the source is unchanged or static, or
+// for "url" sources in offline mode.
+// On success the cache and its metadata are updated and aliases from src are
+// attached. On error the caller should keep its current data.
+func Refresh(src SourceConfig, cacheDir string) (*PricingData, error) {
+	prev, _ := httpfetch.ReadMeta(filepath.Join(cacheDir, paths.PricingCacheMetaFile))
+
+	var pd *PricingData
+	switch src.Source {
+	case "static":
+		return nil, nil
+	case "file":
+		info, err := os.Stat(src.File)
+		if err != nil {
+			return nil, fmt.Errorf("stat pricing file %s: %w", src.File, err)
+		}
+		if prev.SourceURL == src.File && !info.ModTime().After(prev.FetchedAt) {
+			return nil, nil
+		}
+		if pd, err = fetchFromFile(src.File, src.Format); err != nil {
+			return nil, err
+		}
+		prev = httpfetch.Meta{FetchedAt: time.Now(), SourceURL: src.File}
+	default: // "url"
+		if netclient.Offline() {
+			return nil, nil
+		}
+		url, err := sourceURL(src)
+		if err != nil {
+			return nil, err
+		}
+		if pd, prev, err = fetchFromURLConditional(url, src.Format, prev); err != nil {
+			return nil, err
+		}
+		if pd == nil {
+			writeMeta(cacheDir, prev)
+			return nil, nil
+		}
+	}
+
+	if len(pd.Models) == 0 {
+		return nil, fmt.Errorf("refreshed pricing data is empty")
+	}
+	if err := WritePricingCache(cacheDir, pd); err != nil {
+		slog.Warn("failed to write pricing cache", "error", err)
+	}
+	writeMeta(cacheDir, prev)
+	pd.Aliases = src.Aliases
+	return pd, nil
+}
+
+// writeMeta persists fetch metadata next to the pricing cache. Failures are
+// logged; they only cost a full download on the next refresh.
+func writeMeta(cacheDir string, m httpfetch.Meta) {
+	if err := httpfetch.WriteMeta(filepath.Join(cacheDir, paths.PricingCacheMetaFile), m); err != nil {
+		slog.Debug("failed to write pricing fetch metadata", "error", err)
 	}
 }
 
@@ -168,32 +416,22 @@ func fetchStatic(models map[string]ModelPricing) (*PricingData, error) {
 	return pd, nil
 }
 
-// fetchFromURL downloads pricing data from the given URL and parses it.
-func fetchFromURL(url, format string) (*PricingData, error) {
-	const maxResponseBytes = 10 << 20 // 10 MiB
-
-	client := getHTTPClient()
+// maxResponseBytes caps the size of a pricing download.
+const maxResponseBytes = 10 << 20 // 10 MiB
 
-	resp, err := client.Get(url)
+// fetchFromURLConditional downloads and parses pricing data, revalidating
+// against prev. Returns nil data and the refreshed metadata when the server
+// reports the cached copy is current.
+func fetchFromURLConditional(url, format string, prev httpfetch.Meta) (*PricingData, httpfetch.Meta, error) {
+	res, err := httpfetch.Get(getHTTPClient().StandardClient(), url, prev, maxResponseBytes)
 	if err != nil {
-		return nil, fmt.Errorf("GET %s: %w", url, err)
+		return nil, httpfetch.Meta{}, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	if res.NotModified {
+		return nil, res.Meta, nil
 	}
-
-	limited := io.LimitReader(resp.Body, maxResponseBytes+1)
-	body, err := io.ReadAll(limited)
-	if err != nil {
-		return nil, fmt.Errorf("reading response from %s: %w", url, err)
-	}
-	if int64(len(body)) > maxResponseBytes {
-		return nil, fmt.Errorf("response from %s exceeds %d bytes", url, maxResponseBytes)
-	}
-
-	return parseBody(body, format)
+	pd, err := parseBody(res.Body, format)
+	return pd, res.Meta, err
 }
 
 // fetchFromFile reads pricing data from a local file and parses it.
@@ -229,15 +467,19 @@ type openRouterResponse struct {
 
 // openRouterModel represents a single model entry in an OpenRouter response.
 type openRouterModel struct {
-	ID      string                 `json:"id"`
-	Pricing openRouterModelPricing `json:"pricing"`
+	ID            string                 `json:"id"`
+	ContextLength int64                  `json:"context_length"`
+	Pricing       openRouterModelPricing `json:"pricing"`
 }
 
 // openRouterModelPricing holds the per-token price strings from OpenRouter.
-// Prices are transmitted as string-encoded floats (e.g. "0.000015").
+// Prices are transmitted as string-encoded floats (e.g. "0.000015"). The cache
+// fields are omitted for models without prompt caching.
 type openRouterModelPricing struct {
-	Prompt     string `json:"prompt"`
-	Completion string `json:"completion"`
+	Prompt          string `json:"prompt"`
+	Completion      string `json:"completion"`
+	InputCacheRead  string `json:"input_cache_read"`
+	InputCacheWrite string `json:"input_cache_write"`
 }
 
 // parseOpenRouter parses OpenRouter's {"data": [...]} format.
@@ -262,18 +504,66 @@ func parseOpenRouter(body []byte) (*PricingData, error) {
 			id = id[idx+1:]
 		}
 		pd.Models[id] = ModelPricing{
-			InputPerToken:  input,
-			OutputPerToken: output,
+			InputPerToken:        input,
+			OutputPerToken:       output,
+			CacheWrite5mPerToken: parseOptionalPrice(m.Pricing.InputCacheWrite),
+			CacheReadPerToken:    parseOptionalPrice(m.Pricing.InputCacheRead),
+			ContextWindow:        m.ContextLength,
 		}
 	}
 	return pd, nil
 }
 
+// parseOptionalPrice parses a string-encoded OpenRouter price, returning 0
+// when the field is empty or unparseable.
+func parseOptionalPrice(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
 // liteLLMModel represents a single entry in LiteLLM's flat model pricing map.
 // The upstream format is {"model-id": {"input_cost_per_token": N, "output_cost_per_token": N, ...}}.
+// The *_above_200k_tokens fields are long-context rates, mapped to a [PriceBand].
 type liteLLMModel struct {
-	InputCostPerToken  float64 `json:"input_cost_per_token"`
-	OutputCostPerToken float64 `json:"output_cost_per_token"`
+	MaxInputTokens int64 `json:"max_input_tokens"`
+
+	InputCostPerToken                  float64 `json:"input_cost_per_token"`
+	OutputCostPerToken                 float64 `json:"output_cost_per_token"`
+	CacheCreationInputTokenCost        float64 `json:"cache_creation_input_token_cost"`
+	CacheCreationInputTokenCostAbove1h float64 `json:"cache_creation_input_token_cost_above_1hr"`
+	CacheReadInputTokenCost            float64 `json:"cache_read_input_token_cost"`
+
+	InputCostPerTokenAbove200k                  float64 `json:"input_cost_per_token_above_200k_tokens"`
+	OutputCostPerTokenAbove200k                 float64 `json:"output_cost_per_token_above_200k_tokens"`
+	CacheCreationInputTokenCostAbove200k        float64 `json:"cache_creation_input_token_cost_above_200k_tokens"`
+	CacheCreationInputTokenCostAbove1hAbove200k float64 `json:"cache_creation_input_token_cost_above_1hr_above_200k_tokens"`
+	CacheReadInputTokenCostAbove200k            float64 `json:"cache_read_input_token_cost_above_200k_tokens"`
+}
+
+// liteLLMLongContextThreshold is the prompt size above which LiteLLM's
+// *_above_200k_tokens rates apply.
+const liteLLMLongContextThreshold = 200_000
+
+// bands returns the long-context price bands for m, or nil if it has none.
+func (m liteLLMModel) bands() []PriceBand {
+	band := PriceBand{
+		AboveInputTokens:     liteLLMLongContextThreshold,
+		InputPerToken:        m.InputCostPerTokenAbove200k,
+		OutputPerToken:       m.OutputCostPerTokenAbove200k,
+		CacheWrite5mPerToken: m.CacheCreationInputTokenCostAbove200k,
+		CacheWrite1hPerToken: m.CacheCreationInputTokenCostAbove1hAbove200k,
+		CacheReadPerToken:    m.CacheReadInputTokenCostAbove200k,
+	}
+	if band == (PriceBand{AboveInputTokens: liteLLMLongContextThreshold}) {
+		return nil
+	}
+	return []PriceBand{band}
 }
 
 // parseLiteLLM parses LiteLLM's flat model pricing map.
@@ -290,8 +580,13 @@ func parseLiteLLM(body []byte) (*PricingData, error) {
 			continue
 		}
 		pd.Models[id] = ModelPricing{
-			InputPerToken:  m.InputCostPerToken,
-			OutputPerToken: m.OutputCostPerToken,
+			InputPerToken:        m.InputCostPerToken,
+			OutputPerToken:       m.OutputCostPerToken,
+			CacheWrite5mPerToken: m.CacheCreationInputTokenCost,
+			CacheWrite1hPerToken: m.CacheCreationInputTokenCostAbove1h,
+			CacheReadPerToken:    m.CacheReadInputTokenCost,
+			Bands:                m.bands(),
+			ContextWindow:        m.MaxInputTokens,
 		}
 	}
 	return pd, nil
@@ -1,6 +1,6 @@
 // Package pricing tests cover format parsing (OpenRouter, LiteLLM, Agentcord),
 // the parseBody dispatch, static/file/URL source fetching, cost calculation,
-// cache round-tripping, and embedded defaults loading.
+// cache round-tripping, offline mode, and embedded defaults loading.
 package pricing
 
 import (
@@ -11,6 +11,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"tools.zach/dev/agentcord/internal/httpfetch"
+	"tools.zach/dev/agentcord/internal/netclient"
+	"tools.zach/dev/agentcord/internal/paths"
 )
 
 // ///////////////////////////////////////////////
@@ -325,7 +330,7 @@ func TestReadPricingCacheMissing(t *testing.T) {
 // URL Fetch (via httptest)
 // ///////////////////////////////////////////////
 
-func TestFetchFromURLOpenRouter(t *testing.T) {
+func TestFetchURLOpenRouter(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"data":[
@@ -335,16 +340,16 @@ func TestFetchFromURLOpenRouter(t *testing.T) {
 	}))
 	defer server.Close()
 
-	pd, err := fetchFromURL(server.URL, "openrouter")
+	pd, err := Fetch(SourceConfig{Source: "url", Format: "openrouter", URL: server.URL}, t.TempDir())
 	if err != nil {
-		t.Fatalf("fetchFromURL: %v", err)
+		t.Fatalf("Fetch: %v", err)
 	}
 	if len(pd.Models) != 2 {
 		t.Errorf("Models count = %d, want 2", len(pd.Models))
 	}
 }
 
-func TestFetchFromURLLiteLLM(t *testing.T) {
+func TestFetchURLLiteLLM(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{
@@ -353,9 +358,9 @@ func TestFetchFromURLLiteLLM(t *testing.T) {
 	}))
 	defer server.Close()
 
-	pd, err := fetchFromURL(server.URL, "litellm")
+	pd, err := Fetch(SourceConfig{Source: "url", Format: "litellm", URL: server.URL}, t.TempDir())
 	if err != nil {
-		t.Fatalf("fetchFromURL: %v", err)
+		t.Fatalf("Fetch: %v", err)
 	}
 	if len(pd.Models) != 1 {
 		t.Errorf("Models count = %d, want 1", len(pd.Models))
@@ -470,28 +475,28 @@ func TestFetchWithFallback_PrimaryAndCacheFailReturnsNil(t *testing.T) {
 }
 
 // ///////////////////////////////////////////////
-// fetchFromURL Non-200
+// URL Fetch Non-200
 // ///////////////////////////////////////////////
 
-func TestFetchFromURL_Non200(t *testing.T) {
+func TestFetchURL_Non200(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusServiceUnavailable)
 	}))
 	defer server.Close()
 
-	_, err := fetchFromURL(server.URL, "openrouter")
+	_, err := Fetch(SourceConfig{Source: "url", Format: "openrouter", URL: server.URL}, t.TempDir())
 	if err == nil {
 		t.Fatal("expected error for non-200 response")
 	}
 }
 
-func TestFetchFromURL_NotFound(t *testing.T) {
+func TestFetchURL_NotFound(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 	defer server.Close()
 
-	_, err := fetchFromURL(server.URL, "agentcord")
+	_, err := Fetch(SourceConfig{Source: "url", Format: "agentcord", URL: server.URL}, t.TempDir())
 	if err == nil {
 		t.Fatal("expected error for 404 response")
 	}
@@ -559,3 +564,435 @@ func TestCalculate_OnlyOutputTokens(t *testing.T) {
 		t.Errorf("Calculate = %v, want %v", cost, want)
 	}
 }
+
+// ///////////////////////////////////////////////
+// Cache-Aware Pricing
+// ///////////////////////////////////////////////
+
+func TestParseOpenRouterCachePrices(t *testing.T) {
+	body := []byte(`{"data":[
+		{"id":"anthropic/claude-opus-4-6","pricing":{"prompt":"0.000015","completion":"0.000075","input_cache_read":"0.0000015","input_cache_write":"0.00001875"}},
+		{"id":"openai/gpt-4","pricing":{"prompt":"0.00003","completion":"0.00006"}}
+	]}`)
+
+	pd, err := parseOpenRouter(body)
+	if err != nil {
+		t.Fatalf("parseOpenRouter: %v", err)
+	}
+	opus := pd.Models["claude-opus-4-6"]
+	if opus.CacheReadPerToken != 0.0000015 {
+		t.Errorf("opus CacheReadPerToken = %v, want 0.0000015", opus.CacheReadPerToken)
+	}
+	if opus.CacheWrite5mPerToken != 0.00001875 {
+		t.Errorf("opus CacheWrite5mPerToken = %v, want 0.00001875", opus.CacheWrite5mPerToken)
+	}
+	gpt := pd.Models["gpt-4"]
+	if gpt.CacheReadPerToken != 0 || gpt.CacheWrite5mPerToken != 0 {
+		t.Errorf("gpt-4 cache prices = %v/%v, want 0/0", gpt.CacheReadPerToken, gpt.CacheWrite5mPerToken)
+	}
+}
+
+func TestParseLiteLLMCachePrices(t *testing.T) {
+	body := []byte(`{
+		"claude-opus-4-6": {
+			"input_cost_per_token": 0.000015,
+			"output_cost_per_token": 0.000075,
+			"cache_creation_input_token_cost": 0.00001875,
+			"cache_creation_input_token_cost_above_1hr": 0.00003,
+			"cache_read_input_token_cost": 0.0000015
+		}
+	}`)
+
+	pd, err := parseLiteLLM(body)
+	if err != nil {
+		t.Fatalf("parseLiteLLM: %v", err)
+	}
+	opus := pd.Models["claude-opus-4-6"]
+	if opus.CacheWrite5mPerToken != 0.00001875 {
+		t.Errorf("CacheWrite5mPerToken = %v, want 0.00001875", opus.CacheWrite5mPerToken)
+	}
+	if opus.CacheWrite1hPerToken != 0.00003 {
+		t.Errorf("CacheWrite1hPerToken = %v, want 0.00003", opus.CacheWrite1hPerToken)
+	}
+	if opus.CacheReadPerToken != 0.0000015 {
+		t.Errorf("CacheReadPerToken = %v, want 0.0000015", opus.CacheReadPerToken)
+	}
+}
+
+func TestParseAgentcordCachePrices(t *testing.T) {
+	body := []byte(`{"models":{"claude-opus-4-6":{
+		"input_per_token":0.000015,
+		"output_per_token":0.000075,
+		"cache_write_5m_per_token":0.00001875,
+		"cache_write_1h_per_token":0.00003,
+		"cache_read_per_token":0.0000015
+	}}}`)
+
+	pd, err := parseAgentcord(body)
+	if err != nil {
+		t.Fatalf("parseAgentcord: %v", err)
+	}
+	opus := pd.Models["claude-opus-4-6"]
+	if opus.CacheWrite5mPerToken != 0.00001875 || opus.CacheWrite1hPerToken != 0.00003 || opus.CacheReadPerToken != 0.0000015 {
+		t.Errorf("cache prices = %+v, want 0.00001875/0.00003/0.0000015", opus)
+	}
+}
+
+func TestCalculateUsage_AllTokenClasses(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4-6": {
+				InputPerToken:        0.000015,
+				OutputPerToken:       0.000075,
+				CacheWrite5mPerToken: 0.00001875,
+				CacheWrite1hPerToken: 0.00003,
+				CacheReadPerToken:    0.0000015,
+			},
+		},
+	}
+	cost := pd.CalculateUsage("claude-opus-4-6", Usage{
+		InputTokens:        1000,
+		OutputTokens:       1000,
+		CacheWrite5mTokens: 1000,
+		CacheWrite1hTokens: 1000,
+		CacheReadTokens:    1000,
+	})
+	want := 0.015 + 0.075 + 0.01875 + 0.03 + 0.0015
+	if math.Abs(cost-want) > 1e-12 {
+		t.Errorf("CalculateUsage = %v, want %v", cost, want)
+	}
+}
+
+func TestCalculateUsage_1hFallsBackTo5m(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-opus-4-6": {
+				InputPerToken:        0.000015,
+				OutputPerToken:       0.000075,
+				CacheWrite5mPerToken: 0.00001875,
+			},
+		},
+	}
+	cost := pd.CalculateUsage("claude-opus-4-6", Usage{CacheWrite1hTokens: 1000})
+	want := 0.01875
+	if math.Abs(cost-want) > 1e-12 {
+		t.Errorf("CalculateUsage = %v, want %v", cost, want)
+	}
+}
+
+func TestCalculateUsage_UnknownModel(t *testing.T) {
+	pd := &PricingData{Models: map[string]ModelPricing{}}
+	cost := pd.CalculateUsage("claude-unknown-model", Usage{InputTokens: 1000, CacheReadTokens: 1000})
+	if cost != 0 {
+		t.Errorf("CalculateUsage for missing model = %v, want 0", cost)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Long-Context Bands
+// ///////////////////////////////////////////////
+
+func TestParseLiteLLMLongContextBand(t *testing.T) {
+	body := []byte(`{
+		"claude-sonnet-4-5": {
+			"input_cost_per_token": 0.000003,
+			"output_cost_per_token": 0.000015,
+			"cache_creation_input_token_cost": 0.00000375,
+			"cache_read_input_token_cost": 0.0000003,
+			"input_cost_per_token_above_200k_tokens": 0.000006,
+			"output_cost_per_token_above_200k_tokens": 0.0000225,
+			"cache_creation_input_token_cost_above_200k_tokens": 0.0000075,
+			"cache_read_input_token_cost_above_200k_tokens": 0.0000006
+		},
+		"claude-haiku-4-5": {
+			"input_cost_per_token": 0.000001,
+			"output_cost_per_token": 0.000005
+		}
+	}`)
+
+	pd, err := parseLiteLLM(body)
+	if err != nil {
+		t.Fatalf("parseLiteLLM: %v", err)
+	}
+	sonnet := pd.Models["claude-sonnet-4-5"]
+	if len(sonnet.Bands) != 1 {
+		t.Fatalf("sonnet bands = %d, want 1", len(sonnet.Bands))
+	}
+	want := PriceBand{
+		AboveInputTokens:     200_000,
+		InputPerToken:        0.000006,
+		OutputPerToken:       0.0000225,
+		CacheWrite5mPerToken: 0.0000075,
+		CacheReadPerToken:    0.0000006,
+	}
+	if sonnet.Bands[0] != want {
+		t.Errorf("sonnet band = %+v, want %+v", sonnet.Bands[0], want)
+	}
+	if bands := pd.Models["claude-haiku-4-5"].Bands; bands != nil {
+		t.Errorf("haiku bands = %+v, want nil", bands)
+	}
+}
+
+func TestParseAgentcordBands(t *testing.T) {
+	body := []byte(`{"models":{"claude-sonnet-4-5":{
+		"input_per_token":0.000003,
+		"output_per_token":0.000015,
+		"bands":[{"above_input_tokens":200000,"input_per_token":0.000006,"output_per_token":0.0000225}]
+	}}}`)
+
+	pd, err := parseAgentcord(body)
+	if err != nil {
+		t.Fatalf("parseAgentcord: %v", err)
+	}
+	bands := pd.Models["claude-sonnet-4-5"].Bands
+	if len(bands) != 1 || bands[0].AboveInputTokens != 200_000 || bands[0].InputPerToken != 0.000006 {
+		t.Errorf("bands = %+v, want one band above 200000 at 0.000006", bands)
+	}
+}
+
+func TestCalculateUsage_Bands(t *testing.T) {
+	pd := &PricingData{
+		Models: map[string]ModelPricing{
+			"claude-sonnet-4-5": {
+				InputPerToken:        0.000003,
+				OutputPerToken:       0.000015,
+				CacheWrite5mPerToken: 0.00000375,
+				CacheWrite1hPerToken: 0.000006,
+				CacheReadPerToken:    0.0000003,
+				Bands: []PriceBand{
+					{AboveInputTokens: 500_000, InputPerToken: 0.00001},
+					{AboveInputTokens: 200_000, InputPerToken: 0.000006, OutputPerToken: 0.0000225, CacheWrite5mPerToken: 0.0000075},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		u    Usage
+		want float64
+	}{
+		{
+			name: "below threshold uses base rates",
+			u:    Usage{InputTokens: 200_000, OutputTokens: 1000},
+			want: 200_000*0.000003 + 1000*0.000015,
+		},
+		{
+			name: "cache tokens count toward prompt size",
+			u:    Usage{InputTokens: 1000, CacheReadTokens: 250_000, OutputTokens: 1000},
+			want: 1000*0.000006 + 250_000*0.0000003 + 1000*0.0000225,
+		},
+		{
+			name: "1h write rate scales with band 5m rate",
+			u:    Usage{InputTokens: 200_000, CacheWrite1hTokens: 1000},
+			want: 200_000*0.000006 + 1000*0.000012,
+		},
+		{
+			name: "highest crossed band wins, zero rates inherit base",
+			u:    Usage{InputTokens: 600_000, OutputTokens: 1000},
+			want: 600_000*0.00001 + 1000*0.000015,
+		},
+		{
+			name: "summed usage is banded by its prompt tokens",
+			u:    Usage{InputTokens: 300_000, OutputTokens: 1000, PromptTokens: 150_000},
+			want: 300_000*0.000003 + 1000*0.000015,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pd.CalculateUsage("claude-sonnet-4-5", tt.u)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CalculateUsage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// ///////////////////////////////////////////////
+// Refresh
+// ///////////////////////////////////////////////
+
+func TestRefresh_URLConditional(t *testing.T) {
+	var hits, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"data":[{"id":"anthropic/claude-opus-4-6","pricing":{"prompt":"0.000015","completion":"0.000075"}}]}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	src := SourceConfig{Source: "url", Format: "openrouter", URL: server.URL, Aliases: map[string]string{"a": "b"}}
+
+	if _, err := Fetch(src, dir); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	meta, err := httpfetch.ReadMeta(filepath.Join(dir, paths.PricingCacheMetaFile))
+	if err != nil {
+		t.Fatalf("ReadMeta after Fetch: %v", err)
+	}
+	if meta.ETag != `"v1"` || meta.SourceURL != server.URL {
+		t.Errorf("meta = %+v, want etag and source url recorded", meta)
+	}
+
+	pd, err := Refresh(src, dir)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if pd != nil {
+		t.Errorf("Refresh returned data for unchanged source, want nil")
+	}
+	if notModified != 1 {
+		t.Errorf("conditional hits = %d, want 1", notModified)
+	}
+
+	// Changing the URL discards the stale validators and forces a full fetch.
+	src.URL = server.URL + "/v2"
+	pd, err = Refresh(src, dir)
+	if err != nil {
+		t.Fatalf("Refresh new URL: %v", err)
+	}
+	if pd == nil || len(pd.Models) != 1 {
+		t.Fatalf("Refresh new URL = %+v, want fresh data", pd)
+	}
+	if pd.Aliases["a"] != "b" {
+		t.Errorf("Aliases not attached to refreshed data")
+	}
+	if hits != 3 {
+		t.Errorf("server hits = %d, want 3", hits)
+	}
+}
+
+func TestRefresh_FileOnlyWhenModified(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.json")
+	os.WriteFile(path, []byte(`{"models":{"claude-opus-4-6":{"input_per_token":0.000015,"output_per_token":0.000075}}}`), 0o644)
+	src := SourceConfig{Source: "file", Format: "agentcord", File: path}
+
+	if _, err := Fetch(src, dir); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	pd, err := Refresh(src, dir)
+	if err != nil || pd != nil {
+		t.Fatalf("Refresh unchanged = (%v, %v), want (nil, nil)", pd, err)
+	}
+
+	os.WriteFile(path, []byte(`{"models":{"claude-haiku-4-5":{"input_per_token":0.000001,"output_per_token":0.000005}}}`), 0o644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	pd, err = Refresh(src, dir)
+	if err != nil {
+		t.Fatalf("Refresh modified: %v", err)
+	}
+	if pd == nil {
+		t.Fatal("Refresh modified returned nil, want new data")
+	}
+	if _, ok := pd.Models["claude-haiku-4-5"]; !ok {
+		t.Error("refreshed data missing claude-haiku-4-5")
+	}
+}
+
+func TestRefresh_StaticIsNoop(t *testing.T) {
+	pd, err := Refresh(SourceConfig{Source: "static"}, t.TempDir())
+	if err != nil || pd != nil {
+		t.Errorf("Refresh static = (%v, %v), want (nil, nil)", pd, err)
+	}
+}
+
+func TestRefresh_ErrorKeepsNothing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	pd, err := Refresh(SourceConfig{Source: "url", Format: "openrouter", URL: server.URL}, t.TempDir())
+	if err == nil || pd != nil {
+		t.Errorf("Refresh on 404 = (%v, %v), want (nil, error)", pd, err)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Offline Mode
+// ///////////////////////////////////////////////
+
+func TestFetch_OfflineUsesCacheOnly(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	if err := netclient.Configure(netclient.Settings{Offline: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer netclient.Configure(netclient.Settings{})
+
+	dir := t.TempDir()
+	src := SourceConfig{Source: "url", Format: "openrouter", URL: server.URL}
+	if pd, err := Fetch(src, dir); pd != nil || err == nil {
+		t.Errorf("Fetch offline without cache = (%v, %v), want (nil, error)", pd, err)
+	}
+
+	cached := &PricingData{Models: map[string]ModelPricing{"claude-opus-4-6": {InputPerToken: 1}}}
+	if err := WritePricingCache(dir, cached); err != nil {
+		t.Fatal(err)
+	}
+	pd, err := Fetch(src, dir)
+	if err != nil || pd == nil || len(pd.Models) != 1 {
+		t.Errorf("Fetch offline with cache = (%v, %v), want cached data without error", pd, err)
+	}
+	if pd, err := Refresh(src, dir); pd != nil || err != nil {
+		t.Errorf("Refresh offline = (%v, %v), want (nil, nil)", pd, err)
+	}
+	if called {
+		t.Error("pricing URL fetched in offline mode")
+	}
+}
+
+// ///////////////////////////////////////////////
+// Context Window
+// ///////////////////////////////////////////////
+
+func TestParseContextWindow(t *testing.T) {
+	litellm, err := parseLiteLLM([]byte(`{
+		"claude-opus-4-6": {"input_cost_per_token": 0.000015, "output_cost_per_token": 0.000075, "max_input_tokens": 200000}
+	}`))
+	if err != nil {
+		t.Fatalf("parseLiteLLM: %v", err)
+	}
+	if got := litellm.Models["claude-opus-4-6"].ContextWindow; got != 200_000 {
+		t.Errorf("litellm ContextWindow = %d, want 200000", got)
+	}
+
+	openrouter, err := parseOpenRouter([]byte(`{"data": [
+		{"id": "anthropic/claude-sonnet-4-5", "context_length": 1000000, "pricing": {"prompt": "0.000003", "completion": "0.000015"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("parseOpenRouter: %v", err)
+	}
+	if got := openrouter.Models["claude-sonnet-4-5"].ContextWindow; got != 1_000_000 {
+		t.Errorf("openrouter ContextWindow = %d, want 1000000", got)
+	}
+}
+
+func TestContextWindow_Resolve(t *testing.T) {
+	pd := &PricingData{Models: map[string]ModelPricing{
+		"claude-opus-4-6": {InputPerToken: 0.000015, ContextWindow: 200_000},
+	}}
+	if got := pd.ContextWindow("claude-opus-4-6-20260101"); got != 200_000 {
+		t.Errorf("ContextWindow(dated ID) = %d, want 200000", got)
+	}
+	if got := pd.ContextWindow("gpt-4"); got != 0 {
+		t.Errorf("ContextWindow(unknown) = %d, want 0", got)
+	}
+	var nilPD *PricingData
+	if got := nilPD.ContextWindow("claude-opus-4-6"); got != 0 {
+		t.Errorf("nil ContextWindow = %d, want 0", got)
+	}
+}
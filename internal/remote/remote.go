@@ -29,6 +29,11 @@ var (
 	repo     string
 )
 
+// gitTimeout bounds the git remote lookup. git reads the remote from the local
+// repository config without touching the network, so the [network] timeouts
+// and offline mode do not apply.
+const gitTimeout = 2 * time.Second
+
 // githubRemoteRe extracts owner and repo from GitHub remote URLs.
 // Matches both HTTPS (github.com/) and SSH (github.com:) formats.
 var githubRemoteRe = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/.]+)`)
@@ -42,7 +47,7 @@ func ensureInit() {
 			repo = ldRepo
 			return
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
 		defer cancel()
 		out, err := exec.CommandContext(ctx, "git", "remote", "get-url", "origin").Output()
 		if err != nil {
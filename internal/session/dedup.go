@@ -0,0 +1,102 @@
+package session
+
+// ///////////////////////////////////////////////
+// Entry Deduplication
+// ///////////////////////////////////////////////
+
+// Claude Code writes one transcript line per content block of an assistant
+// message, and every line repeats the message's usage. A resumed session's
+// transcript also starts with copies of the earlier session's entries, which
+// keep their original session ID. Both would inflate tokens, cost and turns if
+// every line were counted, so each API response is counted once: the first
+// line carrying its message ID and request ID counts in full, later lines add
+// only their tool calls, and lines from another session are skipped.
+
+// entryAction says how a log entry contributes to the totals.
+type entryAction int
+
+const (
+	// entrySkip ignores the entry: it was already counted.
+	entrySkip entryAction = iota
+	// entryFirst counts the entry's usage, turn and tool calls.
+	entryFirst
+	// entryContinuation counts only the tool calls of a later line of a
+	// streamed response.
+	entryContinuation
+)
+
+// recentKeys is how many of the latest entry UUIDs and response keys an
+// [entryFilter] remembers in order. Claude Code writes the lines of a response
+// together, so only recent keys can repeat further down the transcript.
+const recentKeys = 256
+
+// entryFilter classifies the entries of one transcript. Its zero value
+// deduplicates within the transcript only.
+type entryFilter struct {
+	// uuids holds the entry UUIDs seen in the transcript.
+	uuids map[string]struct{}
+	// responses holds the response keys counted in the transcript.
+	responses map[string]struct{}
+	// recentUUIDs and recentResponses hold the latest recentKeys entries of
+	// uuids and responses, oldest first. Only these are saved with a cache.
+	recentUUIDs     []string
+	recentResponses []string
+	// session, when set, is the ID of the session the transcript belongs to.
+	// Entries of another session were copied in by a resume and count only
+	// in the original transcript.
+	session string
+}
+
+// classify returns how entry contributes to the transcript's totals and
+// records it as seen.
+func (f *entryFilter) classify(entry *jsonlEntry) entryAction {
+	if f.session != "" && entry.SessionID != "" && entry.SessionID != f.session {
+		return entrySkip
+	}
+	if entry.UUID != "" {
+		if _, dup := f.uuids[entry.UUID]; dup {
+			return entrySkip
+		}
+		if f.uuids == nil {
+			f.uuids = make(map[string]struct{})
+		}
+		f.uuids[entry.UUID] = struct{}{}
+		f.recentUUIDs = appendRecent(f.recentUUIDs, entry.UUID)
+	}
+
+	key := responseKey(entry)
+	if key == "" {
+		return entryFirst
+	}
+	if _, seen := f.responses[key]; seen {
+		return entryContinuation
+	}
+	if f.responses == nil {
+		f.responses = make(map[string]struct{})
+	}
+	f.responses[key] = struct{}{}
+	f.recentResponses = appendRecent(f.recentResponses, key)
+	return entryFirst
+}
+
+// appendRecent appends key to keys, dropping the oldest keys beyond recentKeys.
+func appendRecent(keys []string, key string) []string {
+	keys = append(keys, key)
+	if len(keys) > recentKeys {
+		keys = keys[len(keys)-recentKeys:]
+	}
+	return keys
+}
+
+// responseKey identifies the API response an entry belongs to: its message ID
+// and request ID, or its UUID for entries without either. Returns "" when the
+// entry carries no identifier, in which case it is always counted.
+func responseKey(entry *jsonlEntry) string {
+	if entry.Message.ID != "" || entry.RequestID != "" {
+		return entry.Message.ID + ":" + entry.RequestID
+	}
+	if entry.UUID != "" {
+		return "uuid:" + entry.UUID
+	}
+	return ""
+}
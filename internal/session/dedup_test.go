@@ -0,0 +1,101 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Deduplication Tests
+// ///////////////////////////////////////////////
+
+// streamedResponse is one assistant message written as three lines, one per
+// content block, each repeating the message's usage.
+const streamedResponse = `{"type":"assistant","uuid":"u1","requestId":"req_1","message":{"id":"msg_1","model":"claude-opus-4-6","content":[{"type":"thinking"}],"usage":{"input_tokens":100,"output_tokens":40}}}
+{"type":"assistant","uuid":"u2","requestId":"req_1","message":{"id":"msg_1","model":"claude-opus-4-6","content":[{"type":"tool_use"}],"usage":{"input_tokens":100,"output_tokens":40}}}
+{"type":"assistant","uuid":"u3","requestId":"req_1","message":{"id":"msg_1","model":"claude-opus-4-6","content":[{"type":"tool_use"}],"usage":{"input_tokens":100,"output_tokens":40}}}
+`
+
+func TestParseJSONL_StreamedResponseCountedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	os.WriteFile(path, []byte(streamedResponse+
+		`{"type":"user","uuid":"u4","message":{"content":[{"type":"tool_result"}]}}
+{"type":"assistant","uuid":"u5","requestId":"req_2","message":{"id":"msg_2","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":10,"output_tokens":5}}}
+{"type":"assistant","uuid":"u5","requestId":"req_2","message":{"id":"msg_2","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":10,"output_tokens":5}}}
+`), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if data.InputTokens != 110 || data.OutputTokens != 45 {
+		t.Errorf("tokens = %d/%d, want 110/45", data.InputTokens, data.OutputTokens)
+	}
+	if data.TurnCount != 2 || data.ToolUseCount != 2 {
+		t.Errorf("turns = %d, tool uses = %d; want 2 and 2", data.TurnCount, data.ToolUseCount)
+	}
+	if len(data.Requests) != 2 {
+		t.Errorf("Requests = %d, want 2", len(data.Requests))
+	}
+	opus := data.ModelUsage["claude-opus-4-6"]
+	if opus.TurnCount != 2 || opus.ToolUseCount != 2 || opus.InputTokens != 110 {
+		t.Errorf("opus usage = %+v, want 2 turns, 2 tool uses, 110 input", opus)
+	}
+}
+
+func TestParseJSONLCached_StreamedAcrossReads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	lines := []byte(streamedResponse)
+	first := len(lines) / 2
+	os.WriteFile(path, lines[:first], 0o644)
+
+	cache := NewJSONLCache(path)
+	ParseJSONLCached(cache)
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.Write(lines[first:])
+	f.Close()
+
+	data, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("ParseJSONLCached: %v", err)
+	}
+	if data.InputTokens != 100 || data.TurnCount != 1 || data.ToolUseCount != 2 {
+		t.Errorf("input=%d turns=%d tools=%d, want 100/1/2", data.InputTokens, data.TurnCount, data.ToolUseCount)
+	}
+}
+
+func TestJSONLCaches_ResumedSessionSkipsCopiedEntries(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "a.jsonl")
+	resumed := filepath.Join(dir, "b.jsonl")
+	copied := strings.ReplaceAll(streamedResponse, `"type":"assistant",`, `"type":"assistant","sessionId":"a",`)
+	os.WriteFile(original, []byte(copied), 0o644)
+	os.WriteFile(resumed, []byte(copied+
+		`{"type":"assistant","sessionId":"b","uuid":"u9","requestId":"req_9","message":{"id":"msg_9","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":7,"output_tokens":3}}}
+`), 0o644)
+
+	// The resumed transcript is parsed first, as after a daemon restart:
+	// ownership comes from the entries, not from parse order.
+	caches := NewJSONLCaches()
+	now := time.Now()
+	b, err := caches.Parse("b", resumed, now)
+	if err != nil {
+		t.Fatalf("Parse(resumed): %v", err)
+	}
+	if b.InputTokens != 7 || b.TurnCount != 1 || b.ToolUseCount != 0 {
+		t.Errorf("resumed = input %d, turns %d, tools %d; want only its own response", b.InputTokens, b.TurnCount, b.ToolUseCount)
+	}
+	a, err := caches.Parse("a", original, now)
+	if err != nil || a.InputTokens != 100 || a.ToolUseCount != 2 {
+		t.Fatalf("original = %+v, %v; want 100 input tokens, 2 tool uses", a, err)
+	}
+
+	// A fresh registry gives the same result.
+	if b, _ := NewJSONLCaches().Parse("b", resumed, now); b.InputTokens != 7 {
+		t.Errorf("resumed in a new registry = %d input tokens, want 7", b.InputTokens)
+	}
+}
@@ -3,13 +3,18 @@ package session
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"tools.zach/dev/agentcord/internal/atomicfile"
 	"tools.zach/dev/agentcord/internal/config"
 )
 
@@ -23,36 +28,357 @@ type JSONLData struct {
 	InputTokens         int64
 	OutputTokens        int64
 	CacheCreationTokens int64
-	CacheReadTokens     int64
-	TurnCount           int64
-	ToolUseCount        int64
-	UniqueModels        []string
+	// CacheCreation1hTokens is the portion of CacheCreationTokens written to the
+	// 1-hour cache. The remainder was written to the default 5-minute cache.
+	CacheCreation1hTokens int64
+	CacheReadTokens       int64
+	TurnCount             int64
+	ToolUseCount          int64
+	UniqueModels          []string
+	// CWD is the working directory recorded on the latest entry that has one.
+	CWD string
+
+	// ModelUsage breaks the token totals down by the model that consumed them,
+	// so sessions that switch models can be priced per model.
+	ModelUsage map[string]ModelUsage
+	// Requests holds the usage of each individual API request in log order,
+	// so long-context rates can be applied only to requests over a threshold.
+	Requests []RequestUsage
+	// ModelCosts holds the USD cost per model. It is not populated by parsing;
+	// callers set it after pricing ModelUsage.
+	ModelCosts map[string]float64
+
+	// Subagent holds the part of the totals above that came from subagent
+	// (sidechain) traffic, in the main transcript or a subagent transcript.
+	Subagent ModelUsage
+	// SubagentCost is the USD cost of Subagent. Like ModelCosts, it is set
+	// by callers when pricing Requests.
+	SubagentCost float64
+	// RunningSubagents is the number of subagent tasks started in the main
+	// transcript whose results have not arrived yet.
+	RunningSubagents int
+	// runningTasks holds the tool use IDs of the running subagent tasks.
+	runningTasks map[string]struct{}
+
+	// ContextTokens is the prompt size (input plus cache tokens) of the
+	// latest main-thread assistant turn, i.e. how much of the context window
+	// the conversation fills. A compaction resets it to 0.
+	ContextTokens int64
+	// ContextModel is the model that served the turn behind ContextTokens.
+	ContextModel string
+	// ContextWindow is ContextModel's maximum prompt size. Like ModelCosts,
+	// it is set by callers from pricing data; 0 means unknown.
+	ContextWindow int64
+}
+
+// ModelUsage holds token totals attributed to a single model.
+type ModelUsage struct {
+	// InputTokens is the number of uncached input tokens.
+	InputTokens int64
+	// OutputTokens is the number of output tokens.
+	OutputTokens int64
+	// CacheCreationTokens is the number of tokens written to the prompt cache.
+	CacheCreationTokens int64
+	// CacheCreation1hTokens is the portion of CacheCreationTokens written to the 1-hour cache.
+	CacheCreation1hTokens int64
+	// CacheReadTokens is the number of tokens read from the prompt cache.
+	CacheReadTokens int64
+	// TurnCount is the number of assistant turns produced by the model.
+	TurnCount int64
+	// ToolUseCount is the number of tool calls made by the model.
+	ToolUseCount int64
+}
+
+// RequestUsage holds the token usage of a single API request.
+type RequestUsage struct {
+	// Model is the model that served the request.
+	Model string
+	// Sidechain is true for requests made by a subagent.
+	Sidechain bool
+	// Usage holds the request's token counts. TurnCount is 1 for assistant turns.
+	Usage ModelUsage
+	// Prompt is set when Usage sums several requests of similar prompt size
+	// (see [compactRequests]): it is their largest prompt, which selects the
+	// long-context rates. Zero means Usage is a single request.
+	Prompt int64 `json:",omitempty"`
+	// Time is the Unix time the log entry was written. Zero when the entry
+	// has no timestamp or the request was merged by [compactRequests].
+	Time int64 `json:",omitempty"`
+}
+
+// prompt returns the prompt size that selects the request's rates.
+func (r RequestUsage) prompt() int64 {
+	if r.Prompt != 0 {
+		return r.Prompt
+	}
+	return r.Usage.InputTokens + r.Usage.CacheCreationTokens + r.Usage.CacheReadTokens
 }
 
 // jsonlEntry represents a single line in a JSONL conversation log.
 // Only the fields needed for token aggregation and model detection are decoded.
+// Model and usage are read from the top level or, as in Claude Code
+// transcripts, from the nested message.
 type jsonlEntry struct {
 	// Type is the entry kind (e.g. "assistant", "user").
 	Type string `json:"type"`
+	// UUID uniquely identifies the log entry. Entries copied into a resumed
+	// session's transcript keep their UUID.
+	UUID string `json:"uuid"`
+	// SessionID is the session that wrote the entry. Entries copied into a
+	// resumed session's transcript keep the original session's ID.
+	SessionID string `json:"sessionId"`
+	// RequestID identifies the API request that produced the entry.
+	RequestID string `json:"requestId"`
+	// IsSidechain marks subagent traffic.
+	IsSidechain bool `json:"isSidechain"`
+	// Subtype refines system entries (e.g. "compact_boundary").
+	Subtype string `json:"subtype"`
 	// Model is the model identifier that produced this entry.
 	Model string `json:"model"`
-	// Message holds the content blocks for assistant messages (for tool use counting).
+	// CWD is the working directory the session ran in.
+	CWD string `json:"cwd"`
+	// Timestamp is the RFC 3339 time the entry was written. Kept as a string
+	// so a malformed value does not discard the entry's usage.
+	Timestamp string `json:"timestamp"`
+	// Message holds the API message: its content blocks (for tool use
+	// counting) and, in Claude Code transcripts, its model and usage.
 	Message struct {
-		Content []struct {
-			Type string `json:"type"`
-		} `json:"content"`
+		ID      string       `json:"id"`
+		Model   string       `json:"model"`
+		Content jsonlContent `json:"content"`
+		Usage   jsonlUsage   `json:"usage"`
 	} `json:"message"`
 	// Usage holds the token consumption for this entry.
-	Usage struct {
-		// InputTokens is the number of input tokens consumed.
-		InputTokens int64 `json:"input_tokens"`
-		// OutputTokens is the number of output tokens produced.
-		OutputTokens int64 `json:"output_tokens"`
-		// CacheCreationInputTokens is the number of tokens used to create cache entries.
-		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
-		// CacheReadInputTokens is the number of tokens read from cache.
-		CacheReadInputTokens int64 `json:"cache_read_input_tokens"`
-	} `json:"usage"`
+	Usage jsonlUsage `json:"usage"`
+}
+
+// jsonlUsage is the token usage reported for a single API response.
+type jsonlUsage struct {
+	// InputTokens is the number of input tokens consumed.
+	InputTokens int64 `json:"input_tokens"`
+	// OutputTokens is the number of output tokens produced.
+	OutputTokens int64 `json:"output_tokens"`
+	// CacheCreationInputTokens is the number of tokens used to create cache entries.
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	// CacheReadInputTokens is the number of tokens read from cache.
+	CacheReadInputTokens int64 `json:"cache_read_input_tokens"`
+	// CacheCreation breaks CacheCreationInputTokens down by cache lifetime.
+	// Absent in older logs, in which case all writes count as 5-minute writes.
+	CacheCreation struct {
+		Ephemeral5mInputTokens int64 `json:"ephemeral_5m_input_tokens"`
+		Ephemeral1hInputTokens int64 `json:"ephemeral_1h_input_tokens"`
+	} `json:"cache_creation"`
+}
+
+// jsonlContent holds a message's content blocks. Only the fields used for
+// tool use counting and subagent tracking are decoded. Plain-text message
+// content (a JSON string, as in user prompts) decodes to no blocks.
+type jsonlContent []struct {
+	// Type is the block kind (e.g. "text", "tool_use", "tool_result").
+	Type string `json:"type"`
+	// ID identifies a tool_use block.
+	ID string `json:"id"`
+	// Name is the tool a tool_use block calls.
+	Name string `json:"name"`
+	// ToolUseID is the tool_use block a tool_result block answers.
+	ToolUseID string `json:"tool_use_id"`
+}
+
+// UnmarshalJSON decodes an array of content blocks and ignores string content.
+func (c *jsonlContent) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*c = nil
+		return nil
+	}
+	type blocks jsonlContent
+	return json.Unmarshal(data, (*blocks)(c))
+}
+
+// syntheticModel is the placeholder model Claude Code records on messages it
+// generates locally, such as API error notices. They consume no tokens and
+// are not counted as turns.
+const syntheticModel = "<synthetic>"
+
+// compactBoundary is the subtype of the system entry written when a
+// conversation is compacted.
+const compactBoundary = "compact_boundary"
+
+// subagentTools are the tools that launch a subagent.
+var subagentTools = []string{"Task", "Agent"}
+
+// addEntry folds a single decoded log entry into the running totals.
+// Usage on an entry without a model is attributed to the latest model seen.
+// first is false for the later lines of a streamed API response, which repeat
+// the response's usage: only their tool calls are counted.
+func (d *JSONLData) addEntry(entry *jsonlEntry, first bool) {
+	usage := entry.Usage
+	if usage == (jsonlUsage{}) {
+		usage = entry.Message.Usage
+	}
+	model := entry.Model
+	if model == "" {
+		model = entry.Message.Model
+	}
+	if model == syntheticModel {
+		return
+	}
+
+	if entry.CWD != "" {
+		d.CWD = entry.CWD
+	}
+	if entry.Type == "system" && entry.Subtype == compactBoundary {
+		d.ContextTokens = 0
+	}
+	d.trackSubagents(entry)
+
+	isTurn := entry.Type == "assistant"
+	var toolUses int64
+	if isTurn {
+		for _, block := range entry.Message.Content {
+			if block.Type == "tool_use" {
+				toolUses++
+			}
+		}
+		d.ToolUseCount += toolUses
+	}
+
+	if !first {
+		if toolUses > 0 && d.Model != "" && d.ModelUsage != nil {
+			mu := d.ModelUsage[d.Model]
+			mu.ToolUseCount += toolUses
+			d.ModelUsage[d.Model] = mu
+		}
+		if entry.IsSidechain {
+			d.Subagent.ToolUseCount += toolUses
+		}
+		return
+	}
+
+	d.InputTokens += usage.InputTokens
+	d.OutputTokens += usage.OutputTokens
+	d.CacheCreationTokens += usage.CacheCreationInputTokens
+	d.CacheCreation1hTokens += usage.CacheCreation.Ephemeral1hInputTokens
+	d.CacheReadTokens += usage.CacheReadInputTokens
+
+	if model != "" {
+		d.Model = model
+		// Track unique models (simple linear scan — list is small)
+		if !slices.Contains(d.UniqueModels, model) {
+			d.UniqueModels = append(d.UniqueModels, model)
+		}
+	}
+	if isTurn {
+		d.TurnCount++
+		prompt := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+		if !entry.IsSidechain && prompt > 0 {
+			d.ContextTokens = prompt
+			d.ContextModel = d.Model
+		}
+	}
+
+	if d.Model == "" {
+		return
+	}
+	req := ModelUsage{
+		InputTokens:           usage.InputTokens,
+		OutputTokens:          usage.OutputTokens,
+		CacheCreationTokens:   usage.CacheCreationInputTokens,
+		CacheCreation1hTokens: usage.CacheCreation.Ephemeral1hInputTokens,
+		CacheReadTokens:       usage.CacheReadInputTokens,
+	}
+	if isTurn {
+		req.TurnCount = 1
+		req.ToolUseCount = toolUses
+	}
+	if req == (ModelUsage{}) {
+		return
+	}
+	r := RequestUsage{Model: d.Model, Sidechain: entry.IsSidechain, Usage: req}
+	if t, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+		r.Time = t.Unix()
+	}
+	d.Requests = append(d.Requests, r)
+
+	if d.ModelUsage == nil {
+		d.ModelUsage = make(map[string]ModelUsage)
+	}
+	d.ModelUsage[d.Model] = d.ModelUsage[d.Model].add(req)
+	if entry.IsSidechain {
+		d.Subagent = d.Subagent.add(req)
+	}
+}
+
+// trackSubagents updates the running subagent tasks: a Task tool call starts
+// one and its tool result ends it.
+func (d *JSONLData) trackSubagents(entry *jsonlEntry) {
+	for _, block := range entry.Message.Content {
+		switch {
+		case block.Type == "tool_use" && block.ID != "" && slices.Contains(subagentTools, block.Name):
+			if d.runningTasks == nil {
+				d.runningTasks = make(map[string]struct{})
+			}
+			d.runningTasks[block.ID] = struct{}{}
+		case block.Type == "tool_result" && block.ToolUseID != "":
+			delete(d.runningTasks, block.ToolUseID)
+		}
+	}
+	d.RunningSubagents = len(d.runningTasks)
+}
+
+// MergeSubagent adds the totals of a subagent transcript to d, counting all
+// of them as subagent traffic. The displayed model, running subagent count
+// and context size stay those of the main transcript.
+func (d *JSONLData) MergeSubagent(sub *JSONLData) {
+	d.InputTokens += sub.InputTokens
+	d.OutputTokens += sub.OutputTokens
+	d.CacheCreationTokens += sub.CacheCreationTokens
+	d.CacheCreation1hTokens += sub.CacheCreation1hTokens
+	d.CacheReadTokens += sub.CacheReadTokens
+	d.TurnCount += sub.TurnCount
+	d.ToolUseCount += sub.ToolUseCount
+	for _, m := range sub.UniqueModels {
+		if !slices.Contains(d.UniqueModels, m) {
+			d.UniqueModels = append(d.UniqueModels, m)
+		}
+	}
+
+	if len(sub.ModelUsage) > 0 && d.ModelUsage == nil {
+		d.ModelUsage = make(map[string]ModelUsage, len(sub.ModelUsage))
+	}
+	for m, u := range sub.ModelUsage {
+		d.ModelUsage[m] = d.ModelUsage[m].add(u)
+		d.Subagent = d.Subagent.add(u)
+	}
+
+	// Requests may share its backing array with a cache; clip it so the
+	// append copies instead of writing into the cache's spare capacity.
+	d.Requests = slices.Clip(d.Requests)
+	for _, r := range sub.Requests {
+		r.Sidechain = true
+		d.Requests = append(d.Requests, r)
+	}
+}
+
+// add returns the field-wise sum of u and other.
+func (u ModelUsage) add(other ModelUsage) ModelUsage {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+	u.CacheCreation1hTokens += other.CacheCreation1hTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.TurnCount += other.TurnCount
+	u.ToolUseCount += other.ToolUseCount
+	return u
+}
+
+// clone returns a copy of d whose maps and slices can be modified without
+// affecting d. Requests is append-only, so the copy shares its backing array.
+func (d JSONLData) clone() JSONLData {
+	d.UniqueModels = slices.Clone(d.UniqueModels)
+	d.ModelUsage = maps.Clone(d.ModelUsage)
+	d.ModelCosts = maps.Clone(d.ModelCosts)
+	d.runningTasks = maps.Clone(d.runningTasks)
+	return d
 }
 
 // ///////////////////////////////////////////////
@@ -60,6 +386,7 @@ type jsonlEntry struct {
 // ///////////////////////////////////////////////
 
 // ParseJSONL reads a JSONL file, aggregates token counts, and extracts the latest model.
+// Each API response is counted once, however many lines it spans.
 // Malformed lines are silently skipped.
 func ParseJSONL(path string) (*JSONLData, error) {
 	f, err := os.Open(path)
@@ -69,7 +396,7 @@ func ParseJSONL(path string) (*JSONLData, error) {
 	defer f.Close()
 
 	data := &JSONLData{}
-	seenModels := make(map[string]bool)
+	var filter entryFilter
 	scanner := bufio.NewScanner(f)
 	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 
@@ -83,27 +410,8 @@ func ParseJSONL(path string) (*JSONLData, error) {
 		if err := json.Unmarshal(line, &entry); err != nil {
 			continue
 		}
-
-		data.InputTokens += entry.Usage.InputTokens
-		data.OutputTokens += entry.Usage.OutputTokens
-		data.CacheCreationTokens += entry.Usage.CacheCreationInputTokens
-		data.CacheReadTokens += entry.Usage.CacheReadInputTokens
-
-		if entry.Model != "" {
-			data.Model = entry.Model
-			if !seenModels[entry.Model] {
-				seenModels[entry.Model] = true
-				data.UniqueModels = append(data.UniqueModels, entry.Model)
-			}
-		}
-
-		if entry.Type == "assistant" {
-			data.TurnCount++
-			for _, block := range entry.Message.Content {
-				if block.Type == "tool_use" {
-					data.ToolUseCount++
-				}
-			}
+		if action := filter.classify(&entry); action != entrySkip {
+			data.addEntry(&entry, action == entryFirst)
 		}
 	}
 
@@ -119,13 +427,27 @@ func ParseJSONL(path string) (*JSONLData, error) {
 // ///////////////////////////////////////////////
 
 // JSONLCache tracks parse state for incremental JSONL parsing.
-// It stores the last known file size and accumulated data so that
-// subsequent calls to ParseJSONLCached only scan new entries.
+// It stores the offset just past the last complete line and the accumulated
+// data so that subsequent calls to ParseJSONLCached only scan new entries.
+// A trailing line without a newline may still be being written, so it is
+// left unconsumed and re-read once it is complete.
 type JSONLCache struct {
-	mu       sync.Mutex
-	path     string
-	lastSize int64
+	mu   sync.Mutex
+	path string
+	// info identifies the file last read, so a replaced file (new inode or
+	// file ID at the same path) is detected and re-parsed from the start.
+	// It is nil for a cache restored by [LoadJSONLCaches].
+	info os.FileInfo
+	// size and modTime are the size and modification time of the file last
+	// read, so an unchanged file is not read again.
+	size    int64
+	modTime time.Time
+	// offset is the byte offset just past the last complete line parsed.
+	offset int64
+	// lastData holds the totals of every complete line before offset.
 	lastData JSONLData
+	// filter deduplicates the entries before offset.
+	filter entryFilter
 }
 
 // NewJSONLCache creates a cache for incremental parsing of the given JSONL file.
@@ -133,8 +455,9 @@ func NewJSONLCache(path string) *JSONLCache {
 	return &JSONLCache{path: path}
 }
 
-// ParseJSONLCached reads only the new portion of the JSONL file since the last
-// call. If the file has shrunk (truncation/rotation), it falls back to a full scan.
+// ParseJSONLCached reads only the complete lines appended to the JSONL file
+// since the last call. If the file was replaced or has shrunk (truncation or
+// rotation), it falls back to a full scan.
 func ParseJSONLCached(cache *JSONLCache) (*JSONLData, error) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
@@ -150,81 +473,296 @@ func ParseJSONLCached(cache *JSONLCache) (*JSONLData, error) {
 		return nil, fmt.Errorf("stat JSONL file: %w", err)
 	}
 
-	currentSize := info.Size()
-
-	// If the file shrunk, reset and do a full scan.
-	if currentSize < cache.lastSize {
-		cache.lastSize = 0
-		cache.lastData = JSONLData{}
-	}
-
-	// If unchanged, return cached data.
-	if currentSize == cache.lastSize {
-		result := cache.lastData
-		return &result, nil
+	if prev := cache.info; prev != nil || cache.offset > 0 {
+		switch {
+		case prev != nil && !os.SameFile(prev, info), info.Size() < cache.offset:
+			// Replaced or truncated: reset and do a full scan.
+			cache.reset()
+		case info.Size() == cache.size && info.ModTime().Equal(cache.modTime):
+			// Unchanged: return cached data.
+			result := cache.lastData.clone()
+			return &result, nil
+		}
 	}
-
 	// Seek to where we left off.
-	if cache.lastSize > 0 {
-		if _, err := f.Seek(cache.lastSize, io.SeekStart); err != nil {
+	offset := cache.offset
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
 			return nil, fmt.Errorf("seeking JSONL file: %w", err)
 		}
 	}
 
-	data := cache.lastData
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+	data := cache.lastData.clone()
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Any bytes read are a partial line; leave them for the next call.
+			break
 		}
+		if err != nil {
+			// The filter already saw part of this read; start over next time.
+			cache.reset()
+			return nil, fmt.Errorf("reading JSONL file: %w", err)
+		}
+		offset += int64(len(line))
 
 		var entry jsonlEntry
 		if err := json.Unmarshal(line, &entry); err != nil {
 			continue
 		}
+		if action := cache.filter.classify(&entry); action != entrySkip {
+			data.addEntry(&entry, action == entryFirst)
+		}
+	}
 
-		data.InputTokens += entry.Usage.InputTokens
-		data.OutputTokens += entry.Usage.OutputTokens
-		data.CacheCreationTokens += entry.Usage.CacheCreationInputTokens
-		data.CacheReadTokens += entry.Usage.CacheReadInputTokens
-
-		if entry.Model != "" {
-			data.Model = entry.Model
-			// Track unique models (simple linear scan — list is small)
-			found := false
-			for _, m := range data.UniqueModels {
-				if m == entry.Model {
-					found = true
-					break
-				}
-			}
-			if !found {
-				data.UniqueModels = append(data.UniqueModels, entry.Model)
-			}
+	cache.info = info
+	cache.size = info.Size()
+	cache.modTime = info.ModTime()
+	cache.offset = offset
+	cache.lastData = data
+
+	result := data.clone()
+	return &result, nil
+}
+
+// reset discards the parse state so the next call re-reads the whole file.
+// The caller must hold cache.mu.
+func (cache *JSONLCache) reset() {
+	cache.info = nil
+	cache.size = 0
+	cache.modTime = time.Time{}
+	cache.offset = 0
+	cache.lastData = JSONLData{}
+	cache.filter = entryFilter{session: cache.filter.session}
+}
+
+// ///////////////////////////////////////////////
+// JSONL Cache Registry
+// ///////////////////////////////////////////////
+
+// JSONLCaches holds one [JSONLCache] per transcript, so the daemon re-reads
+// only the lines appended since its last poll. Entries belong to a session
+// and are evicted when the session ends or goes unused. Each transcript
+// counts only its own session's entries, so entries a resumed session copied
+// from an earlier transcript count only toward the earlier one.
+// It is safe for concurrent use.
+type JSONLCaches struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a registered transcript cache.
+type cacheEntry struct {
+	// cache holds the incremental parse state.
+	cache *JSONLCache
+	// sessionID is the session the transcript belongs to.
+	sessionID string
+	// lastUsed is when the transcript was last parsed.
+	lastUsed time.Time
+}
+
+// NewJSONLCaches creates an empty cache registry.
+func NewJSONLCaches() *JSONLCaches {
+	return &JSONLCaches{entries: make(map[string]*cacheEntry)}
+}
+
+// Parse incrementally parses the transcript at path for sessionID, creating
+// its cache on first use. Entries written by another session are skipped.
+func (c *JSONLCaches) Parse(sessionID, path string, now time.Time) (*JSONLData, error) {
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	if !ok {
+		cache := NewJSONLCache(path)
+		cache.filter = entryFilter{session: sessionID}
+		e = &cacheEntry{cache: cache}
+		c.entries[path] = e
+	}
+	e.sessionID = sessionID
+	e.lastUsed = now
+	c.mu.Unlock()
+
+	return ParseJSONLCached(e.cache)
+}
+
+// Evict drops the caches of sessionID's transcripts.
+func (c *JSONLCaches) Evict(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path, e := range c.entries {
+		if e.sessionID == sessionID {
+			delete(c.entries, path)
 		}
+	}
+}
 
-		if entry.Type == "assistant" {
-			data.TurnCount++
-			for _, block := range entry.Message.Content {
-				if block.Type == "tool_use" {
-					data.ToolUseCount++
-				}
-			}
+// EvictIdle drops the caches not used within maxIdle of now, covering
+// sessions that ended without reporting it.
+func (c *JSONLCaches) EvictIdle(maxIdle time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path, e := range c.entries {
+		if now.Sub(e.lastUsed) > maxIdle {
+			delete(c.entries, path)
 		}
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning JSONL file: %w", err)
+// Len returns the number of registered caches.
+func (c *JSONLCaches) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// ///////////////////////////////////////////////
+// JSONL Cache Persistence
+// ///////////////////////////////////////////////
+
+// savedCache is the on-disk form of a registered [JSONLCache].
+type savedCache struct {
+	// Path is the transcript's path.
+	Path string `json:"path"`
+	// SessionID is the session the transcript belongs to.
+	SessionID string `json:"session_id"`
+	// LastUsed is when the transcript was last parsed.
+	LastUsed time.Time `json:"last_used"`
+	// Size and ModTime identify the transcript as last read.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Offset is the byte offset just past the last complete line parsed.
+	Offset int64 `json:"offset"`
+	// Data holds the totals of every complete line before Offset.
+	Data JSONLData `json:"data"`
+	// RunningTasks holds the tool use IDs of the running subagent tasks.
+	RunningTasks []string `json:"running_tasks,omitempty"`
+	// UUIDs and Responses hold the entry filter's most recent keys, the only
+	// ones that can still repeat after Offset.
+	UUIDs     []string `json:"uuids,omitempty"`
+	Responses []string `json:"responses,omitempty"`
+}
+
+// Save writes the parse state of every registered cache to path, so a later
+// process can resume each transcript where this one stopped instead of
+// parsing it from the start. Only aggregates are saved: requests are merged
+// by [compactRequests] and the dedup sets keep their recent keys, so the
+// file stays small however long the transcript grows.
+func (c *JSONLCaches) Save(path string) error {
+	c.mu.Lock()
+	saved := make([]savedCache, 0, len(c.entries))
+	for p, e := range c.entries {
+		e.cache.mu.Lock()
+		if e.cache.info != nil {
+			data := e.cache.lastData
+			data.Requests = compactRequests(data.Requests)
+			saved = append(saved, savedCache{
+				Path:         p,
+				SessionID:    e.sessionID,
+				LastUsed:     e.lastUsed,
+				Size:         e.cache.size,
+				ModTime:      e.cache.modTime,
+				Offset:       e.cache.offset,
+				Data:         data,
+				RunningTasks: slices.Collect(maps.Keys(data.runningTasks)),
+				UUIDs:        e.cache.filter.recentUUIDs,
+				Responses:    e.cache.filter.recentResponses,
+			})
+		}
+		e.cache.mu.Unlock()
 	}
+	c.mu.Unlock()
 
-	cache.lastSize = currentSize
-	cache.lastData = data
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("encoding JSONL caches: %w", err)
+	}
+	return atomicfile.Write(path, data, 0o600)
+}
 
-	result := data
-	return &result, nil
+// LoadJSONLCaches reads a registry written by [JSONLCaches.Save]. A missing
+// file yields an empty registry. A restored cache cannot tell a replaced
+// transcript from an appended one unless it shrank; transcripts are
+// append-only, so it only re-parses from the start after a truncation.
+func LoadJSONLCaches(path string) (*JSONLCaches, error) {
+	c := NewJSONLCaches()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading JSONL caches: %w", err)
+	}
+	var saved []savedCache
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("decoding JSONL caches: %w", err)
+	}
+
+	for _, s := range saved {
+		cache := NewJSONLCache(s.Path)
+		cache.size = s.Size
+		cache.modTime = s.ModTime
+		cache.offset = s.Offset
+		cache.lastData = s.Data
+		cache.lastData.runningTasks = keySet(s.RunningTasks)
+		cache.filter = entryFilter{
+			uuids:           keySet(s.UUIDs),
+			responses:       keySet(s.Responses),
+			recentUUIDs:     s.UUIDs,
+			recentResponses: s.Responses,
+			session:         s.SessionID,
+		}
+		c.entries[s.Path] = &cacheEntry{cache: cache, sessionID: s.SessionID, lastUsed: s.LastUsed}
+	}
+	return c, nil
+}
+
+// promptBucket is the prompt size range, in tokens, within which
+// [compactRequests] merges requests. Long-context thresholds are multiples of
+// it, so every request in a bucket is priced at the same rates.
+const promptBucket = 1000
+
+// compactRequests merges the requests of each model, subagent flag and
+// prompt bucket into one entry whose Prompt is their largest prompt. The
+// result prices the same as reqs and is bounded by the number of buckets a
+// model's context window spans rather than by the number of requests.
+// Request times are dropped.
+func compactRequests(reqs []RequestUsage) []RequestUsage {
+	type groupKey struct {
+		model     string
+		sidechain bool
+		bucket    int64
+	}
+	index := make(map[groupKey]int)
+	var out []RequestUsage
+	for _, r := range reqs {
+		prompt := r.prompt()
+		// Buckets are (n*promptBucket, (n+1)*promptBucket], matching bands
+		// that apply above a threshold.
+		k := groupKey{r.Model, r.Sidechain, (prompt + promptBucket - 1) / promptBucket}
+		r.Time = 0
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(out)
+			out = append(out, r)
+			continue
+		}
+		g := &out[i]
+		g.Prompt = max(g.prompt(), prompt)
+		g.Usage = g.Usage.add(r.Usage)
+	}
+	return out
+}
+
+// keySet returns the set of keys, or nil if keys is empty.
+func keySet(keys []string) map[string]struct{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return set
 }
 
 // ///////////////////////////////////////////////
@@ -1,10 +1,12 @@
 // Tests for JSONL parsing, discovery, and token formatting in the session package.
-// Covers [ParseJSONL], [FindLatestJSONL], and [FormatTokenCount].
+// Covers [ParseJSONL], [ParseJSONLCached], [JSONLCaches], [JSONLData.MergeSubagent], [FindLatestJSONL], and [FormatTokenCount].
 package session
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -345,6 +347,217 @@ func TestParseJSONLCached_MissingFile(t *testing.T) {
 	}
 }
 
+func TestParseJSONLCached_PartialTrailingLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.jsonl")
+	line := `{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50}}`
+	os.WriteFile(path, []byte(line+"\n"+line[:40]), 0o644)
+
+	cache := NewJSONLCache(path)
+	data, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("ParseJSONLCached: %v", err)
+	}
+	if data.InputTokens != 100 {
+		t.Errorf("with partial line: input=%d, want 100", data.InputTokens)
+	}
+
+	// The writer finishes the line; it must be counted once complete.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(line[40:] + "\n")
+	f.Close()
+
+	data, err = ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("ParseJSONLCached: %v", err)
+	}
+	if data.InputTokens != 200 || data.TurnCount != 2 {
+		t.Errorf("after completion: input=%d turns=%d, want 200/2", data.InputTokens, data.TurnCount)
+	}
+}
+
+func TestParseJSONLCached_Replacement(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50}}`+"\n"), 0o644)
+
+	cache := NewJSONLCache(path)
+	ParseJSONLCached(cache)
+
+	// Atomically replace the file with a larger one: a size check alone
+	// would treat the new file as an append and skip its first bytes.
+	replacement := filepath.Join(dir, "replacement.jsonl")
+	os.WriteFile(replacement, []byte(`{"type":"assistant","model":"claude-haiku-4-5","usage":{"input_tokens":7,"output_tokens":3}}
+{"type":"assistant","model":"claude-haiku-4-5","usage":{"input_tokens":7,"output_tokens":3}}
+`), 0o644)
+	if err := os.Rename(replacement, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("ParseJSONLCached: %v", err)
+	}
+	if data.InputTokens != 14 || data.Model != "claude-haiku-4-5" {
+		t.Errorf("after replacement: input=%d model=%q, want 14 haiku", data.InputTokens, data.Model)
+	}
+}
+
+// ///////////////////////////////////////////////
+// JSONLCaches Tests
+// ///////////////////////////////////////////////
+
+func TestJSONLCaches_Eviction(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.jsonl")
+	b := filepath.Join(dir, "b.jsonl")
+	os.WriteFile(a, []byte(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":1}}`+"\n"), 0o644)
+	os.WriteFile(b, []byte(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":2}}`+"\n"), 0o644)
+
+	caches := NewJSONLCaches()
+	now := time.Now()
+	if data, err := caches.Parse("s1", a, now); err != nil || data.InputTokens != 1 {
+		t.Fatalf("Parse(a) = %+v, %v", data, err)
+	}
+	caches.Parse("s2", b, now.Add(-2*time.Hour))
+	if caches.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", caches.Len())
+	}
+
+	caches.EvictIdle(time.Hour, now)
+	if caches.Len() != 1 {
+		t.Errorf("after EvictIdle: Len = %d, want 1", caches.Len())
+	}
+	caches.Evict("s1")
+	if caches.Len() != 0 {
+		t.Errorf("after Evict: Len = %d, want 0", caches.Len())
+	}
+}
+
+func TestJSONLCaches_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s1.jsonl")
+	lines := `{"type":"assistant","sessionId":"s1","uuid":"u1","requestId":"r1","message":{"id":"m1","model":"claude-opus-4-6","content":[{"type":"tool_use","id":"t1","name":"Task"}],"usage":{"input_tokens":10}}}
+{"type":"assistant","sessionId":"s1","uuid":"u2","requestId":"r2","message":{"id":"m2","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":20}}}
+`
+	os.WriteFile(path, []byte(lines), 0o644)
+
+	caches := NewJSONLCaches()
+	now := time.Now()
+	if _, err := caches.Parse("s1", path, now); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	savePath := filepath.Join(dir, "caches.json")
+	if err := caches.Save(savePath); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Blank the parsed lines: a restored cache must not read them again.
+	// The appended lines continue response m1 and repeat entry u2.
+	appended := `{"type":"assistant","sessionId":"s1","uuid":"u3","requestId":"r1","message":{"id":"m1","model":"claude-opus-4-6","content":[{"type":"tool_use","id":"t2","name":"Read"}],"usage":{"input_tokens":10}}}
+{"type":"assistant","sessionId":"s1","uuid":"u2","requestId":"r2","message":{"id":"m2","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":20}}}
+`
+	os.WriteFile(path, []byte(strings.Repeat(" ", len(lines)-1)+"\n"+appended), 0o644)
+
+	loaded, err := LoadJSONLCaches(savePath)
+	if err != nil {
+		t.Fatalf("LoadJSONLCaches: %v", err)
+	}
+	if loaded.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", loaded.Len())
+	}
+	data, err := loaded.Parse("s1", path, now)
+	if err != nil {
+		t.Fatalf("Parse after load: %v", err)
+	}
+	if data.InputTokens != 30 || data.TurnCount != 2 || data.ToolUseCount != 2 {
+		t.Errorf("tokens, turns, tools = %d, %d, %d; want 30, 2, 2", data.InputTokens, data.TurnCount, data.ToolUseCount)
+	}
+	if data.RunningSubagents != 1 {
+		t.Errorf("RunningSubagents = %d, want 1", data.RunningSubagents)
+	}
+}
+
+func TestJSONLCaches_SaveBounded(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "s1.jsonl")
+	savePath := filepath.Join(dir, "caches.json")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+
+	caches := NewJSONLCaches()
+	var sizes []int64
+	n := 0
+	for _, lines := range []int{500, 1000, 2000} {
+		for ; n < lines; n++ {
+			fmt.Fprintf(f, `{"type":"assistant","sessionId":"s1","uuid":"u%05d","requestId":"r%05d","message":{"id":"m%05d","model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":10,"cache_read_input_tokens":%d,"output_tokens":5}}}`+"\n",
+				n, n, n, 50_000+n%3*100)
+		}
+		data, err := caches.Parse("s1", path, time.Now())
+		if err != nil || data.TurnCount != int64(lines) {
+			t.Fatalf("Parse = %+v, %v; want %d turns", data, err, lines)
+		}
+		if err := caches.Save(savePath); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		info, _ := os.Stat(savePath)
+		sizes = append(sizes, info.Size())
+	}
+	// Only the digits of the totals grow.
+	if sizes[2] > sizes[0]+64 {
+		t.Errorf("saved sizes = %v, want them bounded as the transcript grows", sizes)
+	}
+
+	loaded, err := LoadJSONLCaches(savePath)
+	if err != nil {
+		t.Fatalf("LoadJSONLCaches: %v", err)
+	}
+	data, err := loaded.Parse("s1", path, time.Now())
+	if err != nil || data.TurnCount != 2000 || data.CacheReadTokens != caches.entries[path].cache.lastData.CacheReadTokens {
+		t.Errorf("restored totals = %+v, %v; want the saved aggregates", data, err)
+	}
+}
+
+func TestCompactRequests(t *testing.T) {
+	req := func(model string, sidechain bool, prompt int64) RequestUsage {
+		return RequestUsage{Model: model, Sidechain: sidechain, Usage: ModelUsage{InputTokens: prompt, OutputTokens: 1, TurnCount: 1}}
+	}
+	got := compactRequests([]RequestUsage{
+		req("opus", false, 150_100),
+		req("opus", false, 150_900),
+		req("opus", false, 200_000),
+		req("opus", false, 200_001),
+		req("opus", true, 150_500),
+		req("haiku", false, 150_500),
+	})
+	want := []RequestUsage{
+		{Model: "opus", Prompt: 150_900, Usage: ModelUsage{InputTokens: 301_000, OutputTokens: 2, TurnCount: 2}},
+		req("opus", false, 200_000),
+		req("opus", false, 200_001),
+		req("opus", true, 150_500),
+		req("haiku", false, 150_500),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("compactRequests = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadJSONLCaches_Missing(t *testing.T) {
+	dir := t.TempDir()
+	caches, err := LoadJSONLCaches(filepath.Join(dir, "missing.json"))
+	if err != nil || caches.Len() != 0 {
+		t.Errorf("LoadJSONLCaches(missing) = %d caches, %v; want 0, nil", caches.Len(), err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	os.WriteFile(corrupt, []byte("not json"), 0o644)
+	if _, err := LoadJSONLCaches(corrupt); err == nil {
+		t.Error("LoadJSONLCaches(corrupt) succeeded, want error")
+	}
+}
+
 // ///////////////////////////////////////////////
 // FormatTokenCount Tests
 // ///////////////////////////////////////////////
@@ -376,3 +589,233 @@ func TestFormatTokenCount(t *testing.T) {
 		})
 	}
 }
+
+// ///////////////////////////////////////////////
+// Cache Token Tests
+// ///////////////////////////////////////////////
+
+func TestParseJSONL_CacheTokens(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.jsonl")
+	content := `{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":300,"cache_read_input_tokens":2000,"cache_creation":{"ephemeral_5m_input_tokens":100,"ephemeral_1h_input_tokens":200}}}
+{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":50,"cache_read_input_tokens":3000}}
+`
+	os.WriteFile(path, []byte(content), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if data.CacheCreationTokens != 350 {
+		t.Errorf("CacheCreationTokens = %d, want 350", data.CacheCreationTokens)
+	}
+	if data.CacheCreation1hTokens != 200 {
+		t.Errorf("CacheCreation1hTokens = %d, want 200", data.CacheCreation1hTokens)
+	}
+	if data.CacheReadTokens != 5000 {
+		t.Errorf("CacheReadTokens = %d, want 5000", data.CacheReadTokens)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Per-Model Usage Tests
+// ///////////////////////////////////////////////
+
+func TestParseJSONL_ModelUsage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.jsonl")
+	content := `{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_read_input_tokens":1000}}
+{"type":"user"}
+{"type":"assistant","model":"claude-haiku-4-5","cwd":"/home/u/web","usage":{"input_tokens":10,"output_tokens":5}}
+{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":200,"output_tokens":75,"cache_creation_input_tokens":400,"cache_creation":{"ephemeral_1h_input_tokens":400}},"message":{"content":[{"type":"tool_use"},{"type":"tool_use"}]}}
+`
+	os.WriteFile(path, []byte(content), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if len(data.ModelUsage) != 2 {
+		t.Fatalf("ModelUsage has %d models, want 2", len(data.ModelUsage))
+	}
+	opus := data.ModelUsage["claude-opus-4-6"]
+	want := ModelUsage{InputTokens: 300, OutputTokens: 125, CacheCreationTokens: 400, CacheCreation1hTokens: 400, CacheReadTokens: 1000, TurnCount: 2, ToolUseCount: 2}
+	if opus != want {
+		t.Errorf("opus usage = %+v, want %+v", opus, want)
+	}
+	haiku := data.ModelUsage["claude-haiku-4-5"]
+	if haiku.InputTokens != 10 || haiku.OutputTokens != 5 || haiku.TurnCount != 1 {
+		t.Errorf("haiku usage = %+v, want 10 in / 5 out / 1 turn", haiku)
+	}
+	if data.CWD != "/home/u/web" {
+		t.Errorf("CWD = %q, want %q", data.CWD, "/home/u/web")
+	}
+}
+
+func TestParseJSONLCached_ModelUsageNotShared(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cached.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50}}`+"\n"), 0o644)
+
+	cache := NewJSONLCache(path)
+	first, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("first parse: %v", err)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":200,"output_tokens":75}}` + "\n")
+	f.Close()
+
+	second, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("second parse: %v", err)
+	}
+	if got := first.ModelUsage["claude-opus-4-6"].InputTokens; got != 100 {
+		t.Errorf("first result mutated: InputTokens = %d, want 100", got)
+	}
+	if got := second.ModelUsage["claude-opus-4-6"].InputTokens; got != 300 {
+		t.Errorf("second InputTokens = %d, want 300", got)
+	}
+}
+
+func TestParseJSONL_Requests(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "requests.jsonl")
+	content := `{"type":"assistant","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50}}
+{"type":"user"}
+{"type":"assistant","model":"claude-haiku-4-5","usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":250000}}
+`
+	os.WriteFile(path, []byte(content), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if len(data.Requests) != 2 {
+		t.Fatalf("Requests = %d, want 2", len(data.Requests))
+	}
+	if r := data.Requests[0]; r.Model != "claude-opus-4-6" || r.Usage.InputTokens != 100 || r.Usage.TurnCount != 1 {
+		t.Errorf("Requests[0] = %+v, want opus with 100 input tokens", r)
+	}
+	if r := data.Requests[1]; r.Model != "claude-haiku-4-5" || r.Usage.CacheReadTokens != 250000 {
+		t.Errorf("Requests[1] = %+v, want haiku with 250000 cache read tokens", r)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Subagent Tests
+// ///////////////////////////////////////////////
+
+func TestParseJSONL_SidechainUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","uuid":"u1","requestId":"r1","message":{"id":"m1","model":"claude-opus-4-6","content":[{"type":"tool_use","id":"toolu_1","name":"Task"}],"usage":{"input_tokens":100,"output_tokens":10}}}
+{"type":"assistant","uuid":"u2","requestId":"r2","isSidechain":true,"message":{"id":"m2","model":"claude-haiku-4-5","content":[{"type":"tool_use","id":"toolu_2","name":"Read"}],"usage":{"input_tokens":50,"output_tokens":5}}}
+`), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if data.InputTokens != 150 || data.TurnCount != 2 {
+		t.Errorf("totals = %d input, %d turns; want 150 and 2", data.InputTokens, data.TurnCount)
+	}
+	if data.Subagent.InputTokens != 50 || data.Subagent.TurnCount != 1 || data.Subagent.ToolUseCount != 1 {
+		t.Errorf("Subagent = %+v, want 50 input, 1 turn, 1 tool use", data.Subagent)
+	}
+	if len(data.Requests) != 2 || data.Requests[0].Sidechain || !data.Requests[1].Sidechain {
+		t.Errorf("Requests = %+v, want only the second marked as sidechain", data.Requests)
+	}
+	if data.RunningSubagents != 1 {
+		t.Errorf("RunningSubagents = %d, want 1", data.RunningSubagents)
+	}
+}
+
+func TestParseJSONLCached_SubagentFinishes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"tool_use","id":"toolu_1","name":"Task"},{"type":"tool_use","id":"toolu_2","name":"Task"}]}}
+`), 0o644)
+
+	cache := NewJSONLCache(path)
+	if data, _ := ParseJSONLCached(cache); data.RunningSubagents != 2 {
+		t.Fatalf("RunningSubagents = %d, want 2", data.RunningSubagents)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"type":"user","uuid":"u2","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1"}]}}
+`)
+	f.Close()
+
+	if data, _ := ParseJSONLCached(cache); data.RunningSubagents != 1 {
+		t.Errorf("RunningSubagents after one result = %d, want 1", data.RunningSubagents)
+	}
+}
+
+func TestMergeSubagent(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "s1.jsonl")
+	os.WriteFile(mainPath, []byte(`{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":100,"output_tokens":10}}}
+`), 0o644)
+	subDir := filepath.Join(dir, "s1", "subagents")
+	os.MkdirAll(subDir, 0o755)
+	os.WriteFile(filepath.Join(subDir, "agent-a.jsonl"), []byte(`{"type":"assistant","uuid":"a1","isSidechain":true,"message":{"model":"claude-haiku-4-5","content":[{"type":"text"}],"usage":{"input_tokens":30,"output_tokens":3}}}
+`), 0o644)
+
+	subs := SubagentTranscripts(mainPath)
+	if len(subs) != 1 {
+		t.Fatalf("SubagentTranscripts = %v, want one transcript", subs)
+	}
+
+	caches := NewJSONLCaches()
+	now := time.Now()
+	data, _ := caches.Parse("s1", mainPath, now)
+	sub, err := caches.Parse("s1", subs[0], now)
+	if err != nil {
+		t.Fatalf("Parse(subagent): %v", err)
+	}
+	data.MergeSubagent(sub)
+
+	if data.InputTokens != 130 || data.TurnCount != 2 || data.Model != "claude-opus-4-6" {
+		t.Errorf("merged = %d input, %d turns, model %q; want 130, 2, opus", data.InputTokens, data.TurnCount, data.Model)
+	}
+	if data.Subagent.InputTokens != 30 || data.ModelUsage["claude-haiku-4-5"].InputTokens != 30 {
+		t.Errorf("Subagent = %+v, haiku = %+v; want 30 input each", data.Subagent, data.ModelUsage["claude-haiku-4-5"])
+	}
+	if len(data.Requests) != 2 || !data.Requests[1].Sidechain {
+		t.Errorf("Requests = %+v, want the subagent request marked as sidechain", data.Requests)
+	}
+
+	// Merging must not leak into the cached main transcript.
+	if again, _ := caches.Parse("s1", mainPath, now); again.InputTokens != 100 || len(again.Requests) != 1 {
+		t.Errorf("cached main = %d input, %d requests; want 100 and 1", again.InputTokens, len(again.Requests))
+	}
+}
+
+// ///////////////////////////////////////////////
+// Context Window Tests
+// ///////////////////////////////////////////////
+
+func TestParseJSONL_ContextTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	os.WriteFile(path, []byte(`{"type":"assistant","uuid":"u1","message":{"model":"claude-opus-4-6","content":[{"type":"text"}],"usage":{"input_tokens":10,"cache_creation_input_tokens":2000,"cache_read_input_tokens":30000,"output_tokens":50}}}
+{"type":"assistant","uuid":"u2","isSidechain":true,"message":{"model":"claude-haiku-4-5","content":[{"type":"text"}],"usage":{"input_tokens":900000,"output_tokens":5}}}
+`), 0o644)
+
+	cache := NewJSONLCache(path)
+	data, err := ParseJSONLCached(cache)
+	if err != nil {
+		t.Fatalf("ParseJSONLCached: %v", err)
+	}
+	if data.ContextTokens != 32_010 || data.ContextModel != "claude-opus-4-6" {
+		t.Errorf("context = %d (%s), want 32010 from the main-thread opus turn", data.ContextTokens, data.ContextModel)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"type":"system","subtype":"compact_boundary","uuid":"u3","compactMetadata":{"trigger":"auto","preTokens":32010}}
+`)
+	f.Close()
+
+	if data, _ := ParseJSONLCached(cache); data.ContextTokens != 0 {
+		t.Errorf("ContextTokens after compaction = %d, want 0", data.ContextTokens)
+	}
+}
@@ -0,0 +1,37 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ///////////////////////////////////////////////
+// State Migrations
+// ///////////////////////////////////////////////
+
+// legacyClient is the client of state files written before state files
+// recorded one. Only the Claude Code hooks wrote them.
+const legacyClient = "claude-code"
+
+func init() {
+	StateMigrations = append(StateMigrations, MigrationEntry{
+		Version:     2,
+		Description: "state files are per session; fill in the client of legacy files",
+		Upgrade:     fillLegacyClient,
+	})
+}
+
+// fillLegacyClient sets the client of a v1 state file that has none. Version 2
+// files are named after their client and session, so every state carries
+// both; v1 files predating multi-client support only carried the session.
+func fillLegacyClient(data []byte) ([]byte, error) {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing state: %w", err)
+	}
+	if c, _ := m["client"].(string); c != "" {
+		return data, nil
+	}
+	m["client"] = legacyClient
+	return json.Marshal(m)
+}
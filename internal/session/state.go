@@ -15,6 +15,7 @@
 package session
 
 import (
+	"cmp"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -22,10 +23,15 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/budget"
 	"tools.zach/dev/agentcord/internal/config"
 	"tools.zach/dev/agentcord/internal/migrate"
 )
@@ -34,8 +40,9 @@ import (
 // State Types
 // ///////////////////////////////////////////////
 
-// CurrentVersion is the latest state file schema version.
-const CurrentVersion = 1
+// CurrentVersion is the latest state file schema version. Version 2 moved
+// from one state file per client to one per session.
+const CurrentVersion = 2
 
 // DefaultClient is the client identifier written to the state file by hooks.
 const DefaultClient = "unknown"
@@ -66,6 +73,9 @@ type State struct {
 	CWD string `json:"cwd"`
 	// GitRemoteURL is the HTTPS URL of the git remote origin, used for the repo button.
 	GitRemoteURL string `json:"gitRemoteUrl"`
+	// TranscriptPath is the conversation log path supplied by the hook input.
+	// Empty for clients that do not report one. See [TranscriptResolver].
+	TranscriptPath string `json:"transcriptPath,omitempty"`
 	// Client identifies which client wrote the state (e.g. "claude-code").
 	Client string `json:"client"`
 	// Stopped indicates whether the session has ended. When true, [BuildActivity] returns nil.
@@ -88,6 +98,11 @@ type State struct {
 	PermissionMode string `json:"permissionMode,omitempty"`
 	// HookEvent is the name of the last hook event that triggered this state update.
 	HookEvent string `json:"hookEvent,omitempty"`
+
+	// Statusline is Claude Code's statusline payload for the session, when
+	// use_statusline is set. It is merged in by the daemon and never persisted.
+	// See [State.MergeStatusline].
+	Statusline *Statusline `json:"-"`
 }
 
 // ///////////////////////////////////////////////
@@ -98,22 +113,39 @@ type State struct {
 // output of [BuildActivity] and [BuildActivityWithData], ready for transmission
 // over the Discord IPC socket.
 type Activity struct {
+	// Type is the activity type shown before the app name: "playing",
+	// "listening", "watching" or "competing". Empty means "playing".
+	Type string
+	// StatusDisplay selects what the profile status line shows: "name" (the
+	// app name), "state" or "details". Empty means "name".
+	StatusDisplay string
 	// Details is the top line of text displayed in the presence (e.g. "Working on agentcord").
 	Details string
+	// DetailsURL is the link opened when the details line is clicked.
+	DetailsURL string
 	// State is the second line of text (e.g. "Cost: $0.42").
 	State string
+	// StateURL is the link opened when the state line is clicked.
+	StateURL string
 	// Timestamps controls the elapsed time display.
 	Timestamps Timestamps
 	// Assets holds the image keys and hover text.
 	Assets Assets
 	// Buttons is the list of clickable buttons (max 2 per Discord API).
 	Buttons []Button
+	// Party shows how many sessions are running in aggregate mode, or the
+	// configured party otherwise. Its zero value is not sent.
+	Party Party
+	// Instance marks the activity as an instanced game session.
+	Instance bool
 }
 
-// Timestamps holds the start time for a Discord activity's elapsed timer.
+// Timestamps holds the start and end times for a Discord activity's timer.
 type Timestamps struct {
 	// Start is the Unix timestamp from which Discord calculates the "elapsed" display.
 	Start int64
+	// End is the Unix timestamp Discord counts down to. Zero shows elapsed time.
+	End int64
 }
 
 // Assets holds the image and text assets for a Discord activity.
@@ -122,10 +154,14 @@ type Assets struct {
 	LargeImage string
 	// LargeText is the tooltip shown when hovering over the large image.
 	LargeText string
+	// LargeURL is the link opened when the large image is clicked.
+	LargeURL string
 	// SmallImage is the Discord asset key for the small overlay image (model tier icon).
 	SmallImage string
 	// SmallText is the tooltip shown when hovering over the small image.
 	SmallText string
+	// SmallURL is the link opened when the small image is clicked.
+	SmallURL string
 }
 
 // Button represents a clickable button on a Discord Rich Presence activity.
@@ -136,6 +172,40 @@ type Button struct {
 	URL string
 }
 
+// Party is the "N of M" size display of a Discord activity.
+type Party struct {
+	// ID identifies the party the size belongs to.
+	ID string
+	// Size is the current party size (e.g. the number of live sessions).
+	Size int
+	// Max is the maximum party size. It is never below Size.
+	Max int
+}
+
+// Aggregate sums every live session for the aggregate mode card, which shows
+// the concurrent sessions together instead of only the most recent one.
+type Aggregate struct {
+	// Sessions is the number of live sessions.
+	Sessions int
+	// Cost is the combined cost of the live sessions in USD.
+	Cost float64
+	// Tokens is the combined token count (input + output).
+	Tokens int64
+	// Projects are the distinct project names of the live sessions, in the
+	// order they were added.
+	Projects []string
+}
+
+// Add counts one live session. An empty project name adds no project.
+func (a *Aggregate) Add(project string, cost float64, tokens int64) {
+	a.Sessions++
+	a.Cost += cost
+	a.Tokens += tokens
+	if project != "" && !slices.Contains(a.Projects, project) {
+		a.Projects = append(a.Projects, project)
+	}
+}
+
 // ActivityConfig captures the configuration fields needed for building a
 // Discord Rich Presence [Activity]. Fields are typically populated from the
 // user's TOML configuration via [config.Config].
@@ -151,6 +221,13 @@ type ActivityConfig struct {
 
 	// CostFormat is the fmt.Sprintf verb for formatting cost values (e.g. "%.2f").
 	CostFormat string
+	// CurrencySymbol is the symbol shown with converted costs (e.g. "€").
+	CurrencySymbol string
+	// CurrencySymbolAfter places the symbol after the amount (e.g. "12.34 €").
+	CurrencySymbolAfter bool
+	// CurrencyRate is the number of display currency units per USD. Zero shows
+	// costs in USD, e.g. while the exchange rate is still loading.
+	CurrencyRate float64
 	// TokenFormat controls token count display: "short" for abbreviated (e.g. "1.2k")
 	// or "full" for the exact number.
 	TokenFormat string
@@ -165,12 +242,31 @@ type ActivityConfig struct {
 	// matches any pattern, [BuildActivity] returns nil.
 	IgnoredPatterns []string
 
+	// ActivityType is the activity type: "playing", "listening", "watching"
+	// or "competing".
+	ActivityType string
+	// StatusDisplay selects what the profile status line shows: "name",
+	// "state" or "details".
+	StatusDisplay string
+	// DetailsURLFormat is the template for the link on the details line.
+	// Empty adds no link.
+	DetailsURLFormat string
+	// StateURLFormat is the template for the link on the state line.
+	// Empty adds no link.
+	StateURLFormat string
+	// Instance marks the activity as an instanced game session.
+	Instance bool
+
 	// LargeImage is the Discord asset key for the large activity image.
 	LargeImage string
 	// LargeText is the hover text for the large activity image.
 	LargeText string
+	// LargeURLFormat is the template for the link on the large image.
+	LargeURLFormat string
 	// ShowModelIcon enables the small image overlay showing the model tier icon.
 	ShowModelIcon bool
+	// SmallURLFormat is the template for the link on the small image.
+	SmallURLFormat string
 
 	// ShowRepoButton enables a clickable button linking to the git remote URL.
 	ShowRepoButton bool
@@ -191,6 +287,9 @@ type ActivityConfig struct {
 	// TimestampMode controls the elapsed timer origin: "session" uses the session
 	// start time, "daemon" uses the daemon start time.
 	TimestampMode string
+	// EndMinutes sets the end timestamp this many minutes after the start, so
+	// Discord counts down instead of up. Zero shows elapsed time.
+	EndMinutes int
 	// IdleMinutes is the number of minutes without activity before the session is
 	// considered idle. Zero disables idle detection.
 	IdleMinutes int
@@ -216,6 +315,36 @@ type ActivityConfig struct {
 	IdleDetails string
 	// IdleState is the state line shown when IdleMode is "idle_text".
 	IdleState string
+
+	// Budgets holds the current spending against each configured budget.
+	// Like CurrencyRate it is refreshed on every update, not loaded from config.
+	Budgets []budget.Status
+	// BudgetWarnPercent is the share of a budget, in percent, at which the
+	// BudgetWarn* overrides apply. Zero applies them only once a budget is exceeded.
+	BudgetWarnPercent float64
+	// BudgetWarnDetails replaces the details template while a budget is over
+	// the warning threshold. Empty keeps the normal template.
+	BudgetWarnDetails string
+	// BudgetWarnState replaces the state template while a budget is over the
+	// warning threshold. Empty keeps the normal template.
+	BudgetWarnState string
+	// BudgetWarnSmallImage replaces the small image while a budget is over the
+	// warning threshold. Empty keeps the model icon.
+	BudgetWarnSmallImage string
+	// BudgetWarnSmallText replaces the small image tooltip while a budget is
+	// over the warning threshold. It is rendered as a template.
+	BudgetWarnSmallText string
+
+	// Aggregate holds the totals of every live session in aggregate mode.
+	// Like Budgets it is refreshed on every update. Nil shows the displayed
+	// session alone.
+	Aggregate *Aggregate
+	// PartyMax is the party maximum shown in aggregate mode ("3 of N").
+	// Values below the session count show the session count.
+	PartyMax int
+	// Party is the configured party shown outside aggregate mode when its
+	// Size is set. A non-empty ID also names the aggregate mode party.
+	Party Party
 }
 
 // ///////////////////////////////////////////////
@@ -231,7 +360,8 @@ type templateVars struct {
 	Branch string
 	// Model is the raw model identifier (e.g. "claude-opus-4-6").
 	Model string
-	// Cost is the accumulated session cost in USD.
+	// Cost is the accumulated session cost in USD. Converted to the display
+	// currency at render time.
 	Cost float64
 	// Tokens is the total token count (input + output) for the session.
 	Tokens int64
@@ -250,10 +380,41 @@ type templateVars struct {
 	CacheTokens  int64
 	Turns        int64
 
+	// Per-model data
+	ModelCosts map[string]float64 // USD cost keyed by model ID
+	ModelTurns map[string]int64   // assistant turns keyed by model ID
+
+	// Subagent data
+	Subagents     int     // subagent tasks currently running
+	SubagentCost  float64 // USD cost of subagent traffic
+	SubagentTurns int64   // assistant turns made by subagents
+
+	// Context window
+	ContextTokens int64 // prompt size of the latest main-thread turn
+	ContextWindow int64 // model's maximum prompt size; 0 when unknown
+
+	// Concurrent sessions
+	SessionCount int      // live sessions; 1 outside aggregate mode
+	TotalCost    float64  // USD cost of all live sessions
+	Projects     []string // distinct projects of the live sessions
+
+	// Statusline data
+	ModelDisplayName string // model name shown by Claude Code
+	LinesAdded       int64  // lines added in the session
+	LinesRemoved     int64  // lines removed in the session
+
 	// Git extended
 	GitOwner string
 	GitRepo  string
 
+	// Budgets holds spending against each configured budget
+	Budgets []budget.Status
+
+	// Display currency
+	CurrencySymbol      string
+	CurrencySymbolAfter bool
+	CurrencyRate        float64 // display units per USD; 0 shows USD
+
 	// Defaults
 	DefaultModelFormat string
 	DefaultCostFormat  string
@@ -400,21 +561,39 @@ func BuildActivityWithData(s *State, cfg ActivityConfig, cost float64, totalToke
 	vars := buildTemplateVars(s, cfg, cost, totalTokens, model, jsonl)
 	details := resolveDetails(cfg, vars)
 	state := resolveState(cfg, vars)
+	warn := budgetWarning(cfg)
+	if warn && cfg.BudgetWarnDetails != "" {
+		details = applyTemplate(cfg.BudgetWarnDetails, vars)
+	}
+	if warn && cfg.BudgetWarnState != "" {
+		state = applyTemplate(cfg.BudgetWarnState, vars)
+	}
 
+	// URLs are rendered without truncation: a cut-off link is worse than
+	// Discord rejecting the update.
 	a := &Activity{
-		Details: details,
-		State:   state,
-		Timestamps: Timestamps{
-			Start: s.SessionStart,
-		},
+		Type:          cfg.ActivityType,
+		StatusDisplay: cfg.StatusDisplay,
+		Details:       details,
+		DetailsURL:    renderTemplate(cfg.DetailsURLFormat, vars),
+		State:         state,
+		StateURL:      renderTemplate(cfg.StateURLFormat, vars),
+		Timestamps:    buildTimestamps(cfg, s.SessionStart),
 		Assets: Assets{
 			LargeImage: cfg.LargeImage,
 			LargeText:  cfg.LargeText,
+			LargeURL:   renderTemplate(cfg.LargeURLFormat, vars),
+			SmallURL:   renderTemplate(cfg.SmallURLFormat, vars),
 		},
-		Buttons: buildButtons(cfg, s.GitRemoteURL),
+		Buttons:  buildButtons(cfg, s.GitRemoteURL),
+		Party:    buildParty(cfg),
+		Instance: cfg.Instance,
 	}
 
 	applyModelIcon(a, cfg, model)
+	if warn {
+		applyBudgetIcon(a, cfg, vars)
+	}
 	return a
 }
 
@@ -459,35 +638,85 @@ func buildTemplateVars(s *State, cfg ActivityConfig, cost float64, totalTokens i
 
 	gitOwner, gitRepo := parseGitRemote(s.GitRemoteURL)
 
-	var inputTokens, outputTokens, cacheTokens, turns int64
+	var modelDisplayName string
+	var linesAdded, linesRemoved int64
+	if sl := s.Statusline; sl != nil {
+		modelDisplayName = sl.Model.DisplayName
+		linesAdded = sl.Cost.TotalLinesAdded
+		linesRemoved = sl.Cost.TotalLinesRemoved
+	}
+
+	var inputTokens, outputTokens, cacheTokens, turns, subagentTurns int64
+	var contextTokens, contextWindow int64
+	var modelCosts map[string]float64
+	var modelTurns map[string]int64
+	var subagents int
+	var subagentCost float64
 	if jsonl != nil {
 		inputTokens = jsonl.InputTokens
 		outputTokens = jsonl.OutputTokens
 		cacheTokens = jsonl.CacheCreationTokens + jsonl.CacheReadTokens
 		turns = jsonl.TurnCount
+		modelTurns = make(map[string]int64, len(jsonl.ModelUsage))
+		for m, u := range jsonl.ModelUsage {
+			modelTurns[m] = u.TurnCount
+		}
+		subagents = jsonl.RunningSubagents
+		subagentTurns = jsonl.Subagent.TurnCount
+		contextTokens = jsonl.ContextTokens
+		contextWindow = jsonl.ContextWindow
+		if cfg.ShowCost {
+			modelCosts = jsonl.ModelCosts
+			subagentCost = jsonl.SubagentCost
+		}
+	}
+
+	sessionCount, totalCost, projects := 1, cost, []string{project}
+	if agg := cfg.Aggregate; agg != nil {
+		sessionCount, totalCost, projects = agg.Sessions, agg.Cost, agg.Projects
+		if !cfg.ShowCost {
+			totalCost = 0
+		}
 	}
 
 	return templateVars{
-		Project:            project,
-		Branch:             s.Branch,
-		Model:              model,
-		Cost:               cost,
-		Tokens:             totalTokens,
-		Tool:               s.ToolName,
-		ToolTarget:         s.ToolTarget,
-		File:               s.ActiveFile,
-		AgentState:         s.AgentState,
-		Permission:         s.PermissionMode,
-		Client:             config.ClientDisplayName(s.Client),
-		InputTokens:        inputTokens,
-		OutputTokens:       outputTokens,
-		CacheTokens:        cacheTokens,
-		Turns:              turns,
-		GitOwner:           gitOwner,
-		GitRepo:            gitRepo,
-		DefaultModelFormat: cfg.ModelFormat,
-		DefaultCostFormat:  cfg.CostFormat,
-		DefaultTokenFormat: cfg.TokenFormat,
+		Project:             project,
+		Branch:              s.Branch,
+		Model:               model,
+		Cost:                cost,
+		Tokens:              totalTokens,
+		Tool:                s.ToolName,
+		ToolTarget:          s.ToolTarget,
+		File:                s.ActiveFile,
+		AgentState:          s.AgentState,
+		Permission:          s.PermissionMode,
+		Client:              config.ClientDisplayName(s.Client),
+		InputTokens:         inputTokens,
+		OutputTokens:        outputTokens,
+		CacheTokens:         cacheTokens,
+		Turns:               turns,
+		ModelCosts:          modelCosts,
+		ModelTurns:          modelTurns,
+		Subagents:           subagents,
+		SubagentCost:        subagentCost,
+		SubagentTurns:       subagentTurns,
+		ContextTokens:       contextTokens,
+		ContextWindow:       contextWindow,
+		SessionCount:        sessionCount,
+		TotalCost:           totalCost,
+		Projects:            projects,
+		ModelDisplayName:    modelDisplayName,
+		LinesAdded:          linesAdded,
+		LinesRemoved:        linesRemoved,
+		GitOwner:            gitOwner,
+		GitRepo:             gitRepo,
+		Budgets:             cfg.Budgets,
+		CurrencySymbol:      cfg.CurrencySymbol,
+		CurrencySymbolAfter: cfg.CurrencySymbolAfter,
+		CurrencyRate:        cfg.CurrencyRate,
+		DefaultModelFormat:  cfg.ModelFormat,
+		DefaultCostFormat:   cfg.CostFormat,
+		DefaultTokenFormat:  cfg.TokenFormat,
 	}
 }
 
@@ -510,6 +739,39 @@ func resolveState(cfg ActivityConfig, vars templateVars) string {
 	return applyTemplate(cfg.StateFormat, vars)
 }
 
+// partyID identifies the aggregate mode party.
+const partyID = "agentcord-sessions"
+
+// buildParty returns the party showing the live session count in aggregate
+// mode, the configured party when it has a size, or the zero [Party].
+func buildParty(cfg ActivityConfig) Party {
+	if cfg.Aggregate == nil || cfg.Aggregate.Sessions == 0 {
+		if cfg.Party.Size <= 0 {
+			return Party{}
+		}
+		p := cfg.Party
+		p.Max = max(p.Size, p.Max)
+		return p
+	}
+	n := cfg.Aggregate.Sessions
+	return Party{ID: cmp.Or(cfg.Party.ID, partyID), Size: n, Max: max(n, cfg.PartyMax)}
+}
+
+// buildTimestamps returns the timestamps for a session started at start,
+// ending EndMinutes later when set.
+func buildTimestamps(cfg ActivityConfig, start int64) Timestamps {
+	ts := Timestamps{Start: start}
+	if cfg.EndMinutes > 0 && start != 0 {
+		ts.End = start + int64(cfg.EndMinutes)*60
+	}
+	return ts
+}
+
+// Ignores reports whether a session in cwd is hidden by the ignore patterns.
+func (cfg ActivityConfig) Ignores(cwd string) bool {
+	return matchesIgnorePattern(cfg.IgnoredPatterns, cwd)
+}
+
 // applyModelIcon sets the small image and hover text on the [Activity] assets
 // when ShowModelIcon is enabled and a model is known. The image key is derived
 // from the model tier via extractModelTier.
@@ -522,6 +784,28 @@ func applyModelIcon(a *Activity, cfg ActivityConfig, model string) {
 	a.Assets.SmallText = config.FormatModelName(model, cfg.ModelFormat)
 }
 
+// budgetWarning reports whether any budget is at or over the warning
+// threshold, in which case the BudgetWarn* overrides replace the normal card.
+func budgetWarning(cfg ActivityConfig) bool {
+	for _, b := range cfg.Budgets {
+		if b.Level(cfg.BudgetWarnPercent) != budget.LevelOK {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBudgetIcon replaces the small image and its tooltip with the budget
+// warning overrides, when configured.
+func applyBudgetIcon(a *Activity, cfg ActivityConfig, vars templateVars) {
+	if cfg.BudgetWarnSmallImage != "" {
+		a.Assets.SmallImage = cfg.BudgetWarnSmallImage
+	}
+	if cfg.BudgetWarnSmallText != "" {
+		a.Assets.SmallText = applyTemplate(cfg.BudgetWarnSmallText, vars)
+	}
+}
+
 // buildButtons constructs the [Activity] button list from config and remote URL.
 // Up to two buttons can be returned: the repo link button and a custom button.
 func buildButtons(cfg ActivityConfig, remoteURL string) []Button {
@@ -549,8 +833,10 @@ func buildIdleActivity(s *State, cfg ActivityConfig) *Activity {
 	switch cfg.IdleMode {
 	case "idle_text":
 		return &Activity{
-			Details: cfg.IdleDetails,
-			State:   cfg.IdleState,
+			Type:          cfg.ActivityType,
+			StatusDisplay: cfg.StatusDisplay,
+			Details:       cfg.IdleDetails,
+			State:         cfg.IdleState,
 			Timestamps: Timestamps{
 				Start: s.SessionStart,
 			},
@@ -558,6 +844,7 @@ func buildIdleActivity(s *State, cfg ActivityConfig) *Activity {
 				LargeImage: cfg.LargeImage,
 				LargeText:  cfg.LargeText,
 			},
+			Instance: cfg.Instance,
 		}
 	case "last_activity":
 		// Caller handles this: returns the last known non-nil activity.
@@ -598,11 +885,26 @@ var formatVarRegex = regexp.MustCompile(`\{(\w+):([^}]+)\}`)
 // discordMaxLen is the maximum character length for Discord activity Details and State fields.
 const discordMaxLen = 128
 
-// applyTemplate renders a template string by replacing variable placeholders
-// with formatted values from vars. It performs two passes: first replacing
-// {var:format} patterns (explicit format), then {var} patterns (default format).
-// The result is truncated to [discordMaxLen] characters.
+// applyTemplate renders a template string with [renderTemplate] and truncates
+// the result to [discordMaxLen] characters.
 func applyTemplate(tmpl string, vars templateVars) string {
+	s := renderTemplate(tmpl, vars)
+	if len(s) > discordMaxLen {
+		s = s[:discordMaxLen-1] + "…"
+	}
+	return s
+}
+
+// RenderTemplate renders tmpl with the same variables as the presence card,
+// without Discord's length limit. It is used for the terminal statusline.
+func RenderTemplate(tmpl string, s *State, cfg ActivityConfig, cost float64, totalTokens int64, model string, jsonl *JSONLData) string {
+	return renderTemplate(tmpl, buildTemplateVars(s, cfg, cost, totalTokens, model, jsonl))
+}
+
+// renderTemplate replaces variable placeholders in tmpl with formatted values
+// from vars. It performs two passes: first replacing {var:format} patterns
+// (explicit format), then {var} patterns (default format).
+func renderTemplate(tmpl string, vars templateVars) string {
 	s := tmpl
 
 	// First pass: replace {var:format} patterns with explicit format
@@ -631,14 +933,23 @@ func applyTemplate(tmpl string, vars templateVars) string {
 	s = strings.ReplaceAll(s, "{output_tokens}", resolveVar("output_tokens", vars.DefaultTokenFormat, vars))
 	s = strings.ReplaceAll(s, "{cache_tokens}", resolveVar("cache_tokens", vars.DefaultTokenFormat, vars))
 	s = strings.ReplaceAll(s, "{turns}", fmt.Sprintf("%d", vars.Turns))
+	s = strings.ReplaceAll(s, "{model_mix}", resolveVar("model_mix", vars.DefaultModelFormat, vars))
+	s = strings.ReplaceAll(s, "{subagents}", resolveVar("subagents", "", vars))
+	s = strings.ReplaceAll(s, "{subagent_cost}", resolveVar("subagent_cost", vars.DefaultCostFormat, vars))
+	s = strings.ReplaceAll(s, "{subagent_turns}", resolveVar("subagent_turns", "", vars))
+	s = strings.ReplaceAll(s, "{context_pct}", resolveVar("context_pct", "", vars))
+	s = strings.ReplaceAll(s, "{context_tokens}", resolveVar("context_tokens", vars.DefaultTokenFormat, vars))
+	s = strings.ReplaceAll(s, "{session_count}", resolveVar("session_count", "", vars))
+	s = strings.ReplaceAll(s, "{total_cost}", resolveVar("total_cost", vars.DefaultCostFormat, vars))
+	s = strings.ReplaceAll(s, "{projects}", resolveVar("projects", "", vars))
+	s = strings.ReplaceAll(s, "{lines_added}", resolveVar("lines_added", "", vars))
+	s = strings.ReplaceAll(s, "{lines_removed}", resolveVar("lines_removed", "", vars))
 	s = strings.ReplaceAll(s, "{git_owner}", vars.GitOwner)
 	s = strings.ReplaceAll(s, "{git_repo}", vars.GitRepo)
-
-	// Truncate to Discord's character limit
-	if len(s) > discordMaxLen {
-		s = s[:discordMaxLen-1] + "…"
-	}
-
+	s = strings.ReplaceAll(s, "{budget_used_pct}", resolveVar("budget_used_pct", "", vars))
+	s = strings.ReplaceAll(s, "{budget_remaining}", resolveVar("budget_remaining", "", vars))
+	s = strings.ReplaceAll(s, "{budget_spent}", resolveVar("budget_spent", "", vars))
+	s = strings.ReplaceAll(s, "{budget_period}", resolveVar("budget_period", "", vars))
 	return s
 }
 
@@ -647,15 +958,33 @@ func applyTemplate(tmpl string, vars templateVars) string {
 func resolveVar(name, format string, vars templateVars) string {
 	switch name {
 	case "model":
+		if format == "display" {
+			// The name Claude Code shows, from the statusline.
+			if vars.ModelDisplayName != "" {
+				return vars.ModelDisplayName
+			}
+			format = "short"
+		}
 		if vars.Model == "" {
 			return ""
 		}
 		return config.FormatModelName(vars.Model, format)
 	case "cost":
-		if format == "" {
-			format = "%.2f"
+		cost := vars.Cost
+		switch {
+		case format == "usd":
+			// Escape hatch: the unconverted USD value, e.g. {cost:usd}.
+			return formatUSD(cost, vars.DefaultCostFormat)
+		case format != "" && !strings.Contains(format, "%"):
+			// A format without a verb selects a model filter, e.g. {cost:opus}.
+			// Without per-model costs (cost hidden or not priced) it is empty.
+			if vars.ModelCosts == nil {
+				return ""
+			}
+			cost = modelCost(vars.ModelCosts, format)
+			format = vars.DefaultCostFormat
 		}
-		return "$" + formatFloat(vars.Cost, format)
+		return formatCost(cost, format, vars)
 	case "tokens":
 		return FormatTokenCount(vars.Tokens, format)
 	case "input_tokens":
@@ -686,6 +1015,36 @@ func resolveVar(name, format string, vars templateVars) string {
 		return vars.GitRepo
 	case "turns":
 		return fmt.Sprintf("%d", vars.Turns)
+	case "model_mix":
+		return formatModelMix(vars, format)
+	case "subagents":
+		return fmt.Sprintf("%d", vars.Subagents)
+	case "subagent_cost":
+		if format == "usd" {
+			return formatUSD(vars.SubagentCost, vars.DefaultCostFormat)
+		}
+		return formatCost(vars.SubagentCost, format, vars)
+	case "subagent_turns":
+		return fmt.Sprintf("%d", vars.SubagentTurns)
+	case "context_pct":
+		return formatContextPct(vars.ContextTokens, vars.ContextWindow)
+	case "context_tokens":
+		return FormatTokenCount(vars.ContextTokens, format)
+	case "session_count":
+		return fmt.Sprintf("%d", vars.SessionCount)
+	case "total_cost":
+		if format == "usd" {
+			return formatUSD(vars.TotalCost, vars.DefaultCostFormat)
+		}
+		return formatCost(vars.TotalCost, format, vars)
+	case "projects":
+		return strings.Join(vars.Projects, ", ")
+	case "lines_added":
+		return fmt.Sprintf("%d", vars.LinesAdded)
+	case "lines_removed":
+		return fmt.Sprintf("%d", vars.LinesRemoved)
+	case "budget_used_pct", "budget_remaining", "budget_spent", "budget_period":
+		return formatBudget(name, format, vars)
 	default:
 		return "{" + name + "}"
 	}
@@ -697,6 +1056,123 @@ func formatFloat(val float64, format string) string {
 	return fmt.Sprintf(format, val)
 }
 
+// formatCost renders a USD cost in the display currency. A symbol ending in a
+// letter (e.g. "CHF") is separated from the amount by a space. Without an
+// exchange rate the cost is shown in USD.
+func formatCost(usd float64, format string, vars templateVars) string {
+	if vars.CurrencyRate <= 0 {
+		return formatUSD(usd, format)
+	}
+	if format == "" {
+		format = "%.2f"
+	}
+	amount := formatFloat(usd*vars.CurrencyRate, format)
+	symbol := vars.CurrencySymbol
+	switch {
+	case symbol == "":
+		return amount
+	case vars.CurrencySymbolAfter:
+		return amount + " " + symbol
+	}
+	if r, _ := utf8.DecodeLastRuneInString(symbol); unicode.IsLetter(r) {
+		return symbol + " " + amount
+	}
+	return symbol + amount
+}
+
+// formatUSD renders a cost in USD with a "$" prefix.
+func formatUSD(usd float64, format string) string {
+	if format == "" {
+		format = "%.2f"
+	}
+	return "$" + formatFloat(usd, format)
+}
+
+// modelCost sums the cost of every model whose ID contains filter
+// (case-insensitive), so "opus" matches all Opus versions.
+func modelCost(costs map[string]float64, filter string) float64 {
+	filter = strings.ToLower(filter)
+	var total float64
+	for m, c := range costs {
+		if strings.Contains(strings.ToLower(m), filter) {
+			total += c
+		}
+	}
+	return total
+}
+
+// formatModelMix renders each model's share of the session as a percentage,
+// largest first (e.g. "Opus 4.6 72% · Sonnet 4.5 28%"). Shares are by cost when
+// any cost is known, otherwise by assistant turns. Models whose display names
+// coincide under the given model format are merged.
+func formatModelMix(vars templateVars, format string) string {
+	weights := make(map[string]float64)
+	var total float64
+	for m, c := range vars.ModelCosts {
+		weights[config.FormatModelName(m, format)] += c
+		total += c
+	}
+	if total == 0 {
+		clear(weights)
+		for m, n := range vars.ModelTurns {
+			weights[config.FormatModelName(m, format)] += float64(n)
+			total += float64(n)
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(weights))
+	for name, w := range weights {
+		if w > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if weights[names[i]] != weights[names[j]] {
+			return weights[names[i]] > weights[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %.0f%%", name, weights[name]/total*100)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatBudget renders a budget variable. The format selects the budget
+// period ("daily", "weekly", "monthly"); without one, the budget closest to
+// its limit is used. Renders empty when no budget is configured.
+func formatBudget(name, period string, vars templateVars) string {
+	b, ok := budget.Select(vars.Budgets, period)
+	if !ok {
+		return ""
+	}
+	switch name {
+	case "budget_used_pct":
+		return fmt.Sprintf("%.0f%%", b.UsedPct())
+	case "budget_remaining":
+		return formatCost(b.Remaining(), vars.DefaultCostFormat, vars)
+	case "budget_spent":
+		return formatCost(b.Spent, vars.DefaultCostFormat, vars)
+	default: // "budget_period"
+		return b.Period
+	}
+}
+
+// formatContextPct renders how full the context window is as a whole
+// percentage, capped at 100%. Renders empty when the window size is unknown.
+func formatContextPct(tokens, window int64) string {
+	if window <= 0 {
+		return ""
+	}
+	pct := min(float64(tokens)/float64(window)*100, 100)
+	return fmt.Sprintf("%.0f%%", pct)
+}
+
 // formatPath formats a file path according to the given format.
 // Supported formats: "basename" (file name only), "dir" (directory only),
 // "ext" (file extension), empty/default (full path).
@@ -8,8 +8,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"tools.zach/dev/agentcord/internal/budget"
 )
 
 // defaultTiers returns the standard model tier list used across state tests.
@@ -724,6 +727,32 @@ func TestStateMigration(t *testing.T) {
 	}
 }
 
+func TestStateMigrationLegacyClient(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "state.json")
+	os.WriteFile(legacy, []byte(`{"sessionId":"abc-123","lastActivity":1707900060,"project":"test"}`), 0o644)
+	perClient := filepath.Join(dir, "state.cursor.json")
+	os.WriteFile(perClient, []byte(`{"$version":1,"sessionId":"def-456","client":"cursor"}`), 0o644)
+
+	got, err := ReadState(legacy)
+	if err != nil {
+		t.Fatalf("ReadState: %v", err)
+	}
+	if got.Version != CurrentVersion || got.Client != "claude-code" || got.Project != "test" {
+		t.Errorf("migrated legacy state = version %d, client %q, project %q; want %d, claude-code, test",
+			got.Version, got.Client, got.Project, CurrentVersion)
+	}
+
+	got, err = ReadState(perClient)
+	if err != nil {
+		t.Fatalf("ReadState: %v", err)
+	}
+	if got.Version != CurrentVersion || got.Client != "cursor" || got.SessionID != "def-456" {
+		t.Errorf("migrated per-client state = version %d, client %q, session %q; want %d, cursor, def-456",
+			got.Version, got.Client, got.SessionID, CurrentVersion)
+	}
+}
+
 func TestStateCorruptedBackup(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "state.json")
@@ -1299,8 +1328,8 @@ func TestDefaultClientConstant(t *testing.T) {
 }
 
 func TestCurrentVersionConstant(t *testing.T) {
-	if CurrentVersion != 1 {
-		t.Errorf("CurrentVersion = %d, want %d", CurrentVersion, 1)
+	if CurrentVersion != 2 {
+		t.Errorf("CurrentVersion = %d, want %d", CurrentVersion, 2)
 	}
 }
 
@@ -1413,3 +1442,427 @@ func TestReadStateEmptyClient(t *testing.T) {
 		t.Errorf("Client = %q, want empty string when not set", got.Client)
 	}
 }
+
+// ///////////////////////////////////////////////
+// Per-Model Template Tests
+// ///////////////////////////////////////////////
+
+func TestTemplateModelCost(t *testing.T) {
+	vars := templateVars{
+		Cost:              3.5,
+		DefaultCostFormat: "%.2f",
+		ModelCosts: map[string]float64{
+			"claude-opus-4-6":            2.25,
+			"claude-opus-4-5-20251101":   0.75,
+			"claude-sonnet-4-5-20250929": 0.5,
+		},
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{cost}", "$3.50"},
+		{"{cost:%.1f}", "$3.5"},
+		{"{cost:opus}", "$3.00"},
+		{"{cost:sonnet}", "$0.50"},
+		{"{cost:haiku}", "$0.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildActivityModelCostHidden(t *testing.T) {
+	s := &State{LastActivity: time.Now().Unix()}
+	jsonl := &JSONLData{
+		ModelCosts: map[string]float64{"claude-opus-4-6": 3, "claude-haiku-4-5": 1},
+		ModelUsage: map[string]ModelUsage{
+			"claude-opus-4-6":  {TurnCount: 1},
+			"claude-haiku-4-5": {TurnCount: 3},
+		},
+	}
+	cfg := ActivityConfig{
+		DetailsNoBranchFormat: "{cost:opus}",
+		StateNoCostFormat:     "{model_mix}",
+		CostFormat:            "%.2f",
+		ModelFormat:           "short",
+	}
+
+	a := BuildActivityWithData(s, cfg, 0, 0, "", jsonl)
+	if a.Details != "" {
+		t.Errorf("{cost:opus} = %q with show_cost off, want empty", a.Details)
+	}
+	// The mix falls back to turns instead of revealing the cost split.
+	if a.State != "Haiku 4.5 75% · Opus 4.6 25%" {
+		t.Errorf("{model_mix} = %q, want the split by turns", a.State)
+	}
+
+	cfg.ShowCost = true
+	if a := BuildActivityWithData(s, cfg, 4, 0, "", jsonl); a.Details != "$3.00" {
+		t.Errorf("{cost:opus} = %q with show_cost on, want $3.00", a.Details)
+	}
+}
+
+func TestTemplateModelMix(t *testing.T) {
+	tests := []struct {
+		name string
+		vars templateVars
+		tmpl string
+		want string
+	}{
+		{
+			name: "by cost",
+			vars: templateVars{
+				DefaultModelFormat: "short",
+				ModelCosts:         map[string]float64{"claude-opus-4-6": 3, "claude-sonnet-4-5": 1},
+			},
+			tmpl: "{model_mix}",
+			want: "Opus 4.6 75% · Sonnet 4.5 25%",
+		},
+		{
+			name: "by turns when unpriced",
+			vars: templateVars{
+				DefaultModelFormat: "short",
+				ModelCosts:         map[string]float64{"claude-opus-4-6": 0},
+				ModelTurns:         map[string]int64{"claude-opus-4-6": 1, "claude-haiku-4-5": 3},
+			},
+			tmpl: "{model_mix}",
+			want: "Haiku 4.5 75% · Opus 4.6 25%",
+		},
+		{
+			name: "raw format",
+			vars: templateVars{
+				ModelCosts: map[string]float64{"claude-opus-4-6": 1},
+			},
+			tmpl: "{model_mix:raw}",
+			want: "claude-opus-4-6 100%",
+		},
+		{
+			name: "no data",
+			vars: templateVars{},
+			tmpl: "{model_mix}",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, tt.vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateSubagents(t *testing.T) {
+	vars := templateVars{
+		DefaultCostFormat: "%.2f",
+		Subagents:         2,
+		SubagentCost:      1.25,
+		SubagentTurns:     7,
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{subagents} agents", "2 agents"},
+		{"{subagent_cost}", "$1.25"},
+		{"{subagent_cost:%.1f}", "$1.2"},
+		{"{subagent_turns} turns", "7 turns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateContext(t *testing.T) {
+	vars := templateVars{ContextTokens: 150_000, ContextWindow: 200_000}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{context_pct}", "75%"},
+		{"{context_tokens}", "150K"},
+		{"{context_tokens:full}", "150,000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+
+	if got := applyTemplate("{context_pct}", templateVars{ContextTokens: 1000}); got != "" {
+		t.Errorf("unknown window = %q, want empty", got)
+	}
+	if got := applyTemplate("{context_pct}", templateVars{ContextTokens: 300_000, ContextWindow: 200_000}); got != "100%" {
+		t.Errorf("over the window = %q, want 100%%", got)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Aggregate Tests
+// ///////////////////////////////////////////////
+
+func TestAggregateAdd(t *testing.T) {
+	var agg Aggregate
+	agg.Add("web", 1.5, 100)
+	agg.Add("api", 2, 50)
+	agg.Add("web", 0.5, 10)
+	agg.Add("", 0, 0)
+
+	if agg.Sessions != 4 || agg.Cost != 4 || agg.Tokens != 160 {
+		t.Errorf("totals = %d sessions, $%v, %d tokens; want 4, $4, 160", agg.Sessions, agg.Cost, agg.Tokens)
+	}
+	if strings.Join(agg.Projects, ",") != "web,api" {
+		t.Errorf("Projects = %v, want [web api]", agg.Projects)
+	}
+}
+
+func TestBuildActivityWithData_Aggregate(t *testing.T) {
+	s := &State{
+		SessionStart: time.Now().Unix() - 60,
+		LastActivity: time.Now().Unix(),
+		Project:      "web",
+	}
+	cfg := ActivityConfig{
+		DetailsNoBranchFormat: "{projects}",
+		StateFormat:           "{session_count} running · {total_cost}",
+		ShowCost:              true,
+		CostFormat:            "%.2f",
+		Aggregate:             &Aggregate{Sessions: 3, Cost: 4.5, Tokens: 900, Projects: []string{"web", "api"}},
+		PartyMax:              5,
+	}
+
+	a := BuildActivityWithData(s, cfg, 1, 100, "claude-opus-4-6", nil)
+	if a.Details != "web, api" || a.State != "3 running · $4.50" {
+		t.Errorf("card = %q / %q, want %q / %q", a.Details, a.State, "web, api", "3 running · $4.50")
+	}
+	if a.Party != (Party{ID: partyID, Size: 3, Max: 5}) {
+		t.Errorf("Party = %+v, want 3 of 5", a.Party)
+	}
+
+	// The maximum never drops below the session count.
+	cfg.PartyMax = 0
+	if a := BuildActivityWithData(s, cfg, 1, 100, "", nil); a.Party.Max != 3 {
+		t.Errorf("Party.Max = %d, want 3", a.Party.Max)
+	}
+
+	// Outside aggregate mode the variables describe the displayed session.
+	cfg.Aggregate = nil
+	a = BuildActivityWithData(s, cfg, 1, 100, "", nil)
+	if a.Details != "web" || a.State != "1 running · $1.00" {
+		t.Errorf("single card = %q / %q", a.Details, a.State)
+	}
+	if a.Party != (Party{}) {
+		t.Errorf("Party = %+v, want none", a.Party)
+	}
+}
+
+func TestBuildActivityConfiguredParty(t *testing.T) {
+	s := &State{SessionStart: time.Now().Unix() - 60, LastActivity: time.Now().Unix()}
+	cfg := ActivityConfig{Party: Party{ID: "team", Size: 2}}
+
+	if a := BuildActivity(s, cfg); a.Party != (Party{ID: "team", Size: 2, Max: 2}) {
+		t.Errorf("Party = %+v, want team 2 of 2", a.Party)
+	}
+
+	// Aggregate mode shows the session count in the configured party.
+	cfg.Aggregate = &Aggregate{Sessions: 3}
+	if a := BuildActivity(s, cfg); a.Party != (Party{ID: "team", Size: 3, Max: 3}) {
+		t.Errorf("aggregate Party = %+v, want team 3 of 3", a.Party)
+	}
+
+	// Without a size there is no party outside aggregate mode.
+	cfg.Aggregate = nil
+	cfg.Party.Size = 0
+	if a := BuildActivity(s, cfg); a.Party != (Party{}) {
+		t.Errorf("Party = %+v, want none", a.Party)
+	}
+}
+
+func TestBuildActivityPayloadFields(t *testing.T) {
+	start := time.Now().Unix() - 60
+	s := &State{
+		SessionStart: start,
+		LastActivity: time.Now().Unix(),
+		Project:      "agentcord",
+		GitRemoteURL: "https://github.com/zachthedev/agentcord",
+	}
+	cfg := ActivityConfig{
+		DetailsNoBranchFormat: "Working on {project}",
+		StateNoCostFormat:     "Coding",
+		ActivityType:          "watching",
+		StatusDisplay:         "details",
+		DetailsURLFormat:      "https://github.com/{git_owner}/{git_repo}",
+		StateURLFormat:        "https://example.com/state",
+		LargeURLFormat:        "https://example.com/{project}",
+		SmallURLFormat:        "https://example.com/small",
+		EndMinutes:            25,
+		Instance:              true,
+	}
+
+	a := BuildActivity(s, cfg)
+	if a.Type != "watching" || a.StatusDisplay != "details" || !a.Instance {
+		t.Errorf("Type, StatusDisplay, Instance = %q, %q, %v", a.Type, a.StatusDisplay, a.Instance)
+	}
+	if a.DetailsURL != "https://github.com/zachthedev/agentcord" {
+		t.Errorf("DetailsURL = %q", a.DetailsURL)
+	}
+	if a.StateURL != "https://example.com/state" {
+		t.Errorf("StateURL = %q", a.StateURL)
+	}
+	if a.Assets.LargeURL != "https://example.com/agentcord" || a.Assets.SmallURL != "https://example.com/small" {
+		t.Errorf("asset URLs = %q, %q", a.Assets.LargeURL, a.Assets.SmallURL)
+	}
+	if a.Timestamps != (Timestamps{Start: start, End: start + 25*60}) {
+		t.Errorf("Timestamps = %+v, want end 25 minutes after start", a.Timestamps)
+	}
+
+	// Without a start there is nothing to count down from.
+	s.SessionStart = 0
+	if a := BuildActivity(s, cfg); a.Timestamps.End != 0 {
+		t.Errorf("End = %d without a start, want 0", a.Timestamps.End)
+	}
+}
+
+func TestTemplateTotalCostHidden(t *testing.T) {
+	s := &State{LastActivity: time.Now().Unix()}
+	cfg := ActivityConfig{
+		StateNoCostFormat: "{total_cost}",
+		CostFormat:        "%.2f",
+		Aggregate:         &Aggregate{Sessions: 2, Cost: 3},
+	}
+	if a := BuildActivityWithData(s, cfg, 0, 0, "", nil); a.State != "$0.00" {
+		t.Errorf("State = %q, want the total hidden with show_cost off", a.State)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Currency Template Tests
+// ///////////////////////////////////////////////
+
+func TestTemplateCurrency(t *testing.T) {
+	base := templateVars{
+		Cost:              10,
+		DefaultCostFormat: "%.2f",
+		ModelCosts:        map[string]float64{"claude-opus-4-6": 10},
+	}
+	eur := base
+	eur.CurrencySymbol, eur.CurrencyRate = "€", 0.9
+	eurAfter := eur
+	eurAfter.CurrencySymbolAfter = true
+	chf := base
+	chf.CurrencySymbol, chf.CurrencyRate = "CHF", 0.8
+
+	tests := []struct {
+		name string
+		vars templateVars
+		tmpl string
+		want string
+	}{
+		{"no rate shows USD", base, "{cost}", "$10.00"},
+		{"symbol before", eur, "{cost}", "€9.00"},
+		{"symbol after", eurAfter, "{cost}", "9.00 €"},
+		{"letter symbol spaced", chf, "{cost}", "CHF 8.00"},
+		{"explicit format", eur, "{cost:%.1f}", "€9.0"},
+		{"model filter converted", eur, "{cost:opus}", "€9.00"},
+		{"usd escape hatch", eurAfter, "{cost:usd}", "$10.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, tt.vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+// ///////////////////////////////////////////////
+// Budget Template Tests
+// ///////////////////////////////////////////////
+
+func TestTemplateBudget(t *testing.T) {
+	vars := templateVars{
+		DefaultCostFormat: "%.2f",
+		Budgets: []budget.Status{
+			{Period: budget.Daily, Limit: 50, Spent: 20},
+			{Period: budget.Weekly, Limit: 100, Spent: 90},
+		},
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{budget_used_pct}", "90%"},
+		{"{budget_remaining}", "$10.00"},
+		{"{budget_spent}", "$90.00"},
+		{"{budget_period}", "weekly"},
+		{"{budget_used_pct:daily} used today", "40% used today"},
+		{"{budget_remaining:daily}", "$30.00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			if got := applyTemplate(tt.tmpl, vars); got != tt.want {
+				t.Errorf("applyTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+
+	if got := applyTemplate("{budget_used_pct}", templateVars{}); got != "" {
+		t.Errorf("without budgets = %q, want empty", got)
+	}
+}
+
+func TestBuildActivityBudgetWarning(t *testing.T) {
+	s := &State{
+		Version:      1,
+		SessionStart: time.Now().Unix() - 60,
+		LastActivity: time.Now().Unix(),
+		Project:      "my-project",
+		CWD:          "/tmp/my-project",
+	}
+	cfg := ActivityConfig{
+		DetailsNoBranchFormat: "Working on {project}",
+		StateFormat:           "{cost}",
+		ShowCost:              true,
+		CostFormat:            "%.2f",
+		ModelFormat:           "short",
+		ShowModelIcon:         true,
+		ModelTiers:            defaultTiers(),
+		BudgetWarnPercent:     80,
+		BudgetWarnState:       "{budget_used_pct} of {budget_period} budget",
+		BudgetWarnSmallImage:  "budget_warning",
+		BudgetWarnSmallText:   "{budget_remaining} left",
+		Budgets:               []budget.Status{{Period: budget.Daily, Limit: 10, Spent: 7}},
+	}
+
+	a := BuildActivityWithData(s, cfg, 1, 0, "claude-opus-4-6", nil)
+	if a.State != "$1.00" || a.Assets.SmallImage != "opus" {
+		t.Errorf("under threshold: state=%q small=%q, want normal card", a.State, a.Assets.SmallImage)
+	}
+
+	cfg.Budgets[0].Spent = 8.5
+	a = BuildActivityWithData(s, cfg, 1, 0, "claude-opus-4-6", nil)
+	if a.State != "85% of daily budget" {
+		t.Errorf("State = %q, want warning template", a.State)
+	}
+	if a.Details != "Working on my-project" {
+		t.Errorf("Details = %q, want normal details without warn_details", a.Details)
+	}
+	if a.Assets.SmallImage != "budget_warning" || a.Assets.SmallText != "$1.50 left" {
+		t.Errorf("small image = %q/%q, want budget warning", a.Assets.SmallImage, a.Assets.SmallText)
+	}
+}
@@ -0,0 +1,175 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Statusline Types
+// ///////////////////////////////////////////////
+
+// StatuslineClient is the client identifier of states built from a
+// statusline payload. Only Claude Code has a statusline.
+const StatuslineClient = "claude-code"
+
+// Statusline is the JSON payload Claude Code pipes to its statusline command
+// on every status update, saved to statusline.json by `agentcord statusline`.
+// Only the fields Agentcord displays are decoded.
+type Statusline struct {
+	// SessionID is the session the payload describes.
+	SessionID string `json:"session_id"`
+	// TranscriptPath is the session's conversation log.
+	TranscriptPath string `json:"transcript_path"`
+	// CWD is the session's current working directory.
+	CWD string `json:"cwd"`
+	// Model is the model currently selected in the session.
+	Model StatuslineModel `json:"model"`
+	// Workspace holds the session's directories.
+	Workspace StatuslineWorkspace `json:"workspace"`
+	// Version is the Claude Code version.
+	Version string `json:"version"`
+	// Cost holds Claude Code's own session totals.
+	Cost StatuslineCost `json:"cost"`
+
+	// UpdatedAt is the modification time of the statusline file, i.e. when
+	// Claude Code last reported. It is not part of the payload.
+	UpdatedAt time.Time `json:"-"`
+}
+
+// StatuslineModel identifies the model in a [Statusline] payload.
+type StatuslineModel struct {
+	// ID is the model identifier (e.g. "claude-opus-4-6").
+	ID string `json:"id"`
+	// DisplayName is the name Claude Code shows for the model (e.g. "Opus").
+	DisplayName string `json:"display_name"`
+}
+
+// StatuslineWorkspace holds the directories of a [Statusline] payload.
+type StatuslineWorkspace struct {
+	// CurrentDir is the session's current working directory.
+	CurrentDir string `json:"current_dir"`
+	// ProjectDir is the directory the session was started in.
+	ProjectDir string `json:"project_dir"`
+}
+
+// StatuslineCost holds the session totals of a [Statusline] payload.
+type StatuslineCost struct {
+	// TotalCostUSD is Claude Code's estimate of the session cost in USD,
+	// including subagents.
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	// TotalDurationMS is the wall-clock age of the session in milliseconds.
+	TotalDurationMS int64 `json:"total_duration_ms"`
+	// TotalAPIDurationMS is the time spent waiting on the API in milliseconds.
+	TotalAPIDurationMS int64 `json:"total_api_duration_ms"`
+	// TotalLinesAdded is the number of lines the session added.
+	TotalLinesAdded int64 `json:"total_lines_added"`
+	// TotalLinesRemoved is the number of lines the session removed.
+	TotalLinesRemoved int64 `json:"total_lines_removed"`
+}
+
+// ///////////////////////////////////////////////
+// Statusline I/O
+// ///////////////////////////////////////////////
+
+// ReadStatusline reads and parses the statusline file at path. UpdatedAt is
+// set from the file's modification time.
+func ReadStatusline(path string) (*Statusline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading statusline file: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat statusline file: %w", err)
+	}
+	return ParseStatusline(data, info.ModTime())
+}
+
+// ParseStatusline parses a statusline payload reported at updatedAt.
+func ParseStatusline(data []byte, updatedAt time.Time) (*Statusline, error) {
+	var sl Statusline
+	if err := json.Unmarshal(data, &sl); err != nil {
+		return nil, fmt.Errorf("parsing statusline payload: %w", err)
+	}
+	sl.UpdatedAt = updatedAt
+	return &sl, nil
+}
+
+// ///////////////////////////////////////////////
+// Statusline Merging
+// ///////////////////////////////////////////////
+
+// dir returns the session's working directory, preferring the workspace's.
+func (sl *Statusline) dir() string {
+	if sl.Workspace.CurrentDir != "" {
+		return sl.Workspace.CurrentDir
+	}
+	return sl.CWD
+}
+
+// project returns the project name: the base name of the project directory,
+// or of the working directory when the payload has none.
+func (sl *Statusline) project() string {
+	dir := sl.Workspace.ProjectDir
+	if dir == "" {
+		dir = sl.dir()
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+// sessionStart returns the Unix time the session began, derived from the
+// report time and the session duration. Returns 0 when the duration is unknown.
+func (sl *Statusline) sessionStart() int64 {
+	if sl.Cost.TotalDurationMS <= 0 {
+		return 0
+	}
+	return sl.UpdatedAt.Add(-time.Duration(sl.Cost.TotalDurationMS) * time.Millisecond).Unix()
+}
+
+// State builds a session state from the payload alone, for when no hook
+// state describes the session. Hook-only fields (branch, tool, agent state)
+// are left empty.
+func (sl *Statusline) State() *State {
+	return &State{
+		Version:        CurrentVersion,
+		SessionID:      sl.SessionID,
+		SessionStart:   sl.sessionStart(),
+		LastActivity:   sl.UpdatedAt.Unix(),
+		Project:        sl.project(),
+		CWD:            sl.dir(),
+		TranscriptPath: sl.TranscriptPath,
+		Client:         StatuslineClient,
+		Statusline:     sl,
+	}
+}
+
+// MergeStatusline attaches sl to s when both describe the same session,
+// filling in the fields the hooks left empty. Reports whether sl was merged;
+// a payload for another session leaves s unchanged.
+func (s *State) MergeStatusline(sl *Statusline) bool {
+	if sl.SessionID == "" || s.SessionID != sl.SessionID {
+		return false
+	}
+	if s.TranscriptPath == "" {
+		s.TranscriptPath = sl.TranscriptPath
+	}
+	if s.CWD == "" {
+		s.CWD = sl.dir()
+	}
+	if s.Project == "" {
+		s.Project = sl.project()
+	}
+	if s.SessionStart == 0 {
+		s.SessionStart = sl.sessionStart()
+	}
+	s.LastActivity = max(s.LastActivity, sl.UpdatedAt.Unix())
+	s.Statusline = sl
+	return true
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ///////////////////////////////////////////////
+// Statusline Tests
+// ///////////////////////////////////////////////
+
+// statuslinePayload is a statusline payload as Claude Code sends it.
+const statuslinePayload = `{
+	"hook_event_name": "Status",
+	"session_id": "s1",
+	"transcript_path": "/home/me/.claude/projects/-home-me-web/s1.jsonl",
+	"cwd": "/home/me/web/src",
+	"model": {"id": "claude-opus-4-6", "display_name": "Opus 4.6"},
+	"workspace": {"current_dir": "/home/me/web/src", "project_dir": "/home/me/web"},
+	"version": "2.0.14",
+	"cost": {
+		"total_cost_usd": 1.25,
+		"total_duration_ms": 600000,
+		"total_api_duration_ms": 120000,
+		"total_lines_added": 156,
+		"total_lines_removed": 23
+	}
+}`
+
+// writeStatusline writes statuslinePayload to dir with the given modification time.
+func writeStatusline(t *testing.T, dir string, mod time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, "statusline.json")
+	if err := os.WriteFile(path, []byte(statuslinePayload), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadStatusline(t *testing.T) {
+	mod := time.Unix(1_700_000_000, 0)
+	sl, err := ReadStatusline(writeStatusline(t, t.TempDir(), mod))
+	if err != nil {
+		t.Fatalf("ReadStatusline: %v", err)
+	}
+	if sl.SessionID != "s1" || sl.Model.DisplayName != "Opus 4.6" || sl.Workspace.ProjectDir != "/home/me/web" {
+		t.Errorf("statusline = %+v", sl)
+	}
+	if sl.Cost.TotalCostUSD != 1.25 || sl.Cost.TotalLinesAdded != 156 || sl.Cost.TotalLinesRemoved != 23 {
+		t.Errorf("cost = %+v", sl.Cost)
+	}
+	if !sl.UpdatedAt.Equal(mod) {
+		t.Errorf("UpdatedAt = %v, want %v", sl.UpdatedAt, mod)
+	}
+
+	if _, err := ReadStatusline(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("ReadStatusline(missing) should fail")
+	}
+}
+
+func TestStatuslineState(t *testing.T) {
+	mod := time.Unix(1_700_000_000, 0)
+	sl, _ := ReadStatusline(writeStatusline(t, t.TempDir(), mod))
+
+	s := sl.State()
+	if s.SessionID != "s1" || s.Project != "web" || s.CWD != "/home/me/web/src" || s.Client != StatuslineClient {
+		t.Errorf("state = %+v", s)
+	}
+	if s.LastActivity != mod.Unix() || s.SessionStart != mod.Unix()-600 {
+		t.Errorf("times = %d/%d, want %d/%d", s.SessionStart, s.LastActivity, mod.Unix()-600, mod.Unix())
+	}
+	if s.Statusline != sl {
+		t.Error("state should carry the statusline")
+	}
+}
+
+func TestMergeStatusline(t *testing.T) {
+	mod := time.Unix(1_700_000_000, 0)
+	sl, _ := ReadStatusline(writeStatusline(t, t.TempDir(), mod))
+
+	other := &State{SessionID: "s2", Project: "api"}
+	if other.MergeStatusline(sl) || other.Statusline != nil {
+		t.Error("a payload for another session must not be merged")
+	}
+
+	s := &State{SessionID: "s1", Project: "hooked", Branch: "main", SessionStart: 100, LastActivity: mod.Unix() - 5}
+	if !s.MergeStatusline(sl) {
+		t.Fatal("MergeStatusline = false for the same session")
+	}
+	if s.Project != "hooked" || s.SessionStart != 100 || s.Branch != "main" {
+		t.Errorf("hook fields overwritten: %+v", s)
+	}
+	if s.TranscriptPath != sl.TranscriptPath || s.LastActivity != mod.Unix() || s.Statusline != sl {
+		t.Errorf("empty fields not filled: %+v", s)
+	}
+}
+
+func TestTemplateStatusline(t *testing.T) {
+	sl := &Statusline{Model: StatuslineModel{ID: "claude-opus-4-6", DisplayName: "Opus 4.6 (1M)"}}
+	sl.Cost.TotalLinesAdded, sl.Cost.TotalLinesRemoved = 156, 23
+	s := &State{Project: "web", Statusline: sl}
+	vars := buildTemplateVars(s, ActivityConfig{}, 0, 0, "claude-opus-4-6", nil)
+
+	if got := applyTemplate("+{lines_added} -{lines_removed}", vars); got != "+156 -23" {
+		t.Errorf("lines = %q, want +156 -23", got)
+	}
+	if got := applyTemplate("{model:display}", vars); got != "Opus 4.6 (1M)" {
+		t.Errorf("model:display = %q", got)
+	}
+
+	vars.ModelDisplayName = ""
+	if got := applyTemplate("{model:display}", vars); got != "Opus 4.6" {
+		t.Errorf("model:display without statusline = %q, want short name", got)
+	}
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ///////////////////////////////////////////////
+// Transcript Resolution
+// ///////////////////////////////////////////////
+
+// TranscriptResolver locates the conversation log of the session a state
+// file describes, so tokens and cost are computed for the session that is
+// actually displayed rather than whichever log was written last.
+type TranscriptResolver struct {
+	// Roots are directories holding one transcript directory per project,
+	// laid out as Claude Code's ~/.claude/projects/<encoded-cwd>/<sessionId>.jsonl.
+	Roots []string
+	// Fallback is a flat directory of <sessionId>.jsonl logs searched after
+	// Roots. When the state has no session ID, its most recently modified log
+	// is used instead.
+	Fallback string
+}
+
+// Resolve returns the path of the transcript for s. Candidates are tried in
+// order: the hook-supplied transcript path, <root>/<encoded-cwd>/<id>.jsonl
+// and then <root>/*/<id>.jsonl for each root, and finally the fallback
+// directory.
+func (r TranscriptResolver) Resolve(s *State) (string, error) {
+	if s.TranscriptPath != "" && isFile(s.TranscriptPath) {
+		return s.TranscriptPath, nil
+	}
+
+	id := s.SessionID
+	if id == "" {
+		if r.Fallback == "" {
+			return "", fmt.Errorf("state has no session ID")
+		}
+		return FindLatestJSONL(r.Fallback)
+	}
+	if strings.ContainsAny(id, `/\*?[`) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid session ID %q", id)
+	}
+	name := id + ".jsonl"
+
+	if s.CWD != "" {
+		for _, root := range r.Roots {
+			if p := filepath.Join(root, EncodeProjectDir(s.CWD), name); isFile(p) {
+				return p, nil
+			}
+		}
+	}
+	for _, root := range r.Roots {
+		matches, _ := filepath.Glob(filepath.Join(root, "*", name))
+		for _, p := range matches {
+			if isFile(p) {
+				return p, nil
+			}
+		}
+	}
+	if r.Fallback != "" {
+		if p := filepath.Join(r.Fallback, name); isFile(p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no transcript found for session %s", id)
+}
+
+// SubagentTranscripts returns the subagent transcripts that belong to the
+// session transcript at path. Claude Code writes them to
+// <transcript without .jsonl>/subagents/*.jsonl.
+func SubagentTranscripts(path string) []string {
+	dir := filepath.Join(strings.TrimSuffix(path, ".jsonl"), "subagents")
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	return matches
+}
+
+// EncodeProjectDir returns the transcript directory name Claude Code uses for
+// a working directory: every UTF-16 code unit other than an ASCII letter or
+// digit is replaced with '-', so /home/me/my.app becomes -home-me-my-app.
+func EncodeProjectDir(cwd string) string {
+	var b strings.Builder
+	for _, c := range cwd {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteRune(c)
+		case c > 0xFFFF:
+			// Encoded as a surrogate pair, i.e. two code units.
+			b.WriteString("--")
+		default:
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// isFile reports whether path exists and is a regular file.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
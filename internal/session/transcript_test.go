@@ -0,0 +1,129 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ///////////////////////////////////////////////
+// TranscriptResolver Tests
+// ///////////////////////////////////////////////
+
+// writeTranscript creates an empty transcript at dir/name and returns its path.
+func writeTranscript(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTranscriptResolver_Resolve(t *testing.T) {
+	root := t.TempDir()
+	fallback := t.TempDir()
+	r := TranscriptResolver{Roots: []string{root}, Fallback: fallback}
+
+	byCWD := writeTranscript(t, filepath.Join(root, "-home-me-web"), "s1.jsonl")
+	// A moved project: the encoded directory no longer matches the CWD.
+	moved := writeTranscript(t, filepath.Join(root, "-old-path"), "s2.jsonl")
+	legacy := writeTranscript(t, fallback, "s3.jsonl")
+	hook := writeTranscript(t, t.TempDir(), "custom.jsonl")
+
+	tests := []struct {
+		name  string
+		state State
+		want  string
+	}{
+		{"hook transcript path", State{SessionID: "s1", CWD: "/home/me/web", TranscriptPath: hook}, hook},
+		{"missing hook path falls through", State{SessionID: "s1", CWD: "/home/me/web", TranscriptPath: "/nope.jsonl"}, byCWD},
+		{"encoded cwd", State{SessionID: "s1", CWD: "/home/me/web"}, byCWD},
+		{"any project dir", State{SessionID: "s2", CWD: "/home/me/web"}, moved},
+		{"fallback dir", State{SessionID: "s3"}, legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Resolve(&tt.state)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscriptResolver_NotFound(t *testing.T) {
+	root := t.TempDir()
+	writeTranscript(t, filepath.Join(root, "-p"), "other.jsonl")
+	r := TranscriptResolver{Roots: []string{root}}
+
+	for _, id := range []string{"missing", "../-p/other", "*"} {
+		if got, err := r.Resolve(&State{SessionID: id}); err == nil {
+			t.Errorf("Resolve(%q) = %q, want error", id, got)
+		}
+	}
+	if _, err := r.Resolve(&State{}); err == nil {
+		t.Error("Resolve without session ID or fallback should fail")
+	}
+}
+
+func TestTranscriptResolver_NoSessionUsesLatestFallback(t *testing.T) {
+	fallback := t.TempDir()
+	path := writeTranscript(t, fallback, "only.jsonl")
+	got, err := TranscriptResolver{Fallback: fallback}.Resolve(&State{})
+	if err != nil || got != path {
+		t.Errorf("Resolve = %q, %v; want %q", got, err, path)
+	}
+}
+
+func TestEncodeProjectDir(t *testing.T) {
+	tests := []struct {
+		cwd  string
+		want string
+	}{
+		{"/home/me/my.app", "-home-me-my-app"},
+		{`C:\Users\me\repo_1`, "C--Users-me-repo-1"},
+		{"/tmp/café", "-tmp-caf-"},
+		{"/tmp/🚀", "-tmp---"},
+	}
+	for _, tt := range tests {
+		if got := EncodeProjectDir(tt.cwd); got != tt.want {
+			t.Errorf("EncodeProjectDir(%q) = %q, want %q", tt.cwd, got, tt.want)
+		}
+	}
+}
+
+// ///////////////////////////////////////////////
+// Claude Code Transcript Format Tests
+// ///////////////////////////////////////////////
+
+func TestParseJSONL_NestedMessageUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.jsonl")
+	os.WriteFile(path, []byte(`{"type":"user","cwd":"/home/me/web","message":{"role":"user","content":"fix the bug"}}
+{"type":"assistant","cwd":"/home/me/web","message":{"model":"claude-opus-4-6","content":[{"type":"tool_use"}],"usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":500}}}
+{"type":"assistant","message":{"model":"<synthetic>","content":[{"type":"text"}],"usage":{"input_tokens":0,"output_tokens":0}}}
+`), 0o644)
+
+	data, err := ParseJSONL(path)
+	if err != nil {
+		t.Fatalf("ParseJSONL: %v", err)
+	}
+	if data.Model != "claude-opus-4-6" || len(data.UniqueModels) != 1 {
+		t.Errorf("Model = %q (unique %v), want opus only", data.Model, data.UniqueModels)
+	}
+	if data.InputTokens != 100 || data.OutputTokens != 20 || data.CacheReadTokens != 500 {
+		t.Errorf("tokens = %d/%d/%d, want 100/20/500", data.InputTokens, data.OutputTokens, data.CacheReadTokens)
+	}
+	if data.TurnCount != 1 {
+		t.Errorf("TurnCount = %d, want 1 (synthetic messages are not turns)", data.TurnCount)
+	}
+	if data.ToolUseCount != 1 || data.CWD != "/home/me/web" {
+		t.Errorf("ToolUseCount = %d, CWD = %q; want 1, /home/me/web", data.ToolUseCount, data.CWD)
+	}
+}
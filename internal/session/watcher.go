@@ -5,12 +5,14 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"tools.zach/dev/agentcord/internal/paths"
 )
 
 // ///////////////////////////////////////////////
@@ -34,6 +36,8 @@ type Watcher struct {
 	polling atomic.Bool
 	// pollInterval is the duration between stat calls in polling mode.
 	pollInterval time.Duration
+	// names are extra file names a directory watcher reports besides state files.
+	names []string
 }
 
 // NewWatcher creates a new Watcher for the given state file path.
@@ -70,14 +74,16 @@ func NewWatcher(stateFilePath string) (*Watcher, error) {
 }
 
 // NewDirWatcher creates a Watcher that monitors a directory for state file changes.
-// It fires events when any file matching "state.*.json" or the legacy "state.json"
-// is written or created inside dir.
-func NewDirWatcher(dir string) (*Watcher, error) {
+// It fires events when any per-session or legacy per-client state file
+// ("state.*.json"), the legacy "state.json", or any of the extra file names
+// in names is written or created inside dir.
+func NewDirWatcher(dir string, names ...string) (*Watcher, error) {
 	w := &Watcher{
 		path:         dir,
 		events:       make(chan struct{}, 1),
 		done:         make(chan struct{}),
 		pollInterval: 2 * time.Second,
+		names:        names,
 	}
 
 	fsw, err := fsnotify.NewWatcher()
@@ -102,17 +108,19 @@ func NewDirWatcher(dir string) (*Watcher, error) {
 	return w, nil
 }
 
-// isStateFile reports whether name is a state file (legacy or per-client).
+// isStateFile reports whether name is a state file: per-session, legacy
+// per-client, or the legacy state.json.
 func isStateFile(name string) bool {
-	base := filepath.Base(name)
-	if base == "state.json" {
-		return true
-	}
-	return strings.HasPrefix(base, "state.") && strings.HasSuffix(base, ".json")
+	return paths.IsStateFile(name)
+}
+
+// watches reports whether a directory watcher reports changes to name.
+func (w *Watcher) watches(name string) bool {
+	return isStateFile(name) || slices.Contains(w.names, filepath.Base(name))
 }
 
 // watchDir loops over fsnotify events on a directory, forwarding write/create
-// notifications for state files to the events channel.
+// notifications for watched files to the events channel.
 func (w *Watcher) watchDir() {
 	for {
 		select {
@@ -122,7 +130,7 @@ func (w *Watcher) watchDir() {
 			if !ok {
 				return
 			}
-			if (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) && isStateFile(event.Name) {
+			if (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) && w.watches(event.Name) {
 				w.notify()
 			}
 		case err, ok := <-w.fsw.Errors:
@@ -139,8 +147,8 @@ func (w *Watcher) watchDir() {
 	}
 }
 
-// pollDir periodically scans the directory for state file changes and sends a
-// notification when any state file's modification time advances.
+// pollDir periodically scans the directory for watched file changes and sends
+// a notification when any watched file's modification time advances.
 func (w *Watcher) pollDir() {
 	lastMod := w.latestStateMod()
 
@@ -161,8 +169,8 @@ func (w *Watcher) pollDir() {
 	}
 }
 
-// latestStateMod returns the most recent modification time among state files
-// in the watched directory.
+// latestStateMod returns the most recent modification time among watched
+// files in the directory.
 func (w *Watcher) latestStateMod() time.Time {
 	var latest time.Time
 	entries, err := os.ReadDir(w.path)
@@ -170,7 +178,7 @@ func (w *Watcher) latestStateMod() time.Time {
 		return latest
 	}
 	for _, e := range entries {
-		if e.IsDir() || !isStateFile(e.Name()) {
+		if e.IsDir() || !w.watches(e.Name()) {
 			continue
 		}
 		info, err := e.Info()
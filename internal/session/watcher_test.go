@@ -342,3 +342,36 @@ func TestPollingFlag(t *testing.T) {
 		t.Errorf("Polling() returned unexpected value: %v", got)
 	}
 }
+
+// ///////////////////////////////////////////////
+// Directory Watcher Tests
+// ///////////////////////////////////////////////
+
+func TestDirWatcherExtraNames(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow watcher test in short mode")
+	}
+
+	dir := t.TempDir()
+	w, err := NewDirWatcher(dir, "statusline.json")
+	if err != nil {
+		t.Fatalf("NewDirWatcher: %v", err)
+	}
+	defer w.Close()
+
+	if !w.watches(filepath.Join(dir, "state.claude-code.json")) || !w.watches(filepath.Join(dir, "state.claude-code.s1.json")) || w.watches("other.json") {
+		t.Error("watches should match state files and the extra names only")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// The payload is written atomically: a temporary file renamed into place.
+	tmp := filepath.Join(dir, "statusline.json.tmp.1")
+	os.WriteFile(tmp, []byte(`{}`), 0o644)
+	os.Rename(tmp, filepath.Join(dir, "statusline.json"))
+
+	select {
+	case <-w.Events():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for statusline change event")
+	}
+}
@@ -1,7 +1,9 @@
 // Package tiers provides model tier configuration fetched from a remote source.
 //
 // Tiers determine which Discord Rich Presence asset is shown for a given model.
-// Data is fetched with double fallback: remote GitHub -> local cache.
+// Data is fetched with triple fallback: remote GitHub -> local cache -> the copy
+// of data/tiers.json embedded at build time. In offline mode the remote is
+// skipped.
 //
 // Tiers are organized per-client: each client tool (claude-code, cursor, etc.)
 // has its own set of tier names and styling. Each tier carries visual config
@@ -16,23 +18,23 @@
 //     Use {} to inherit all defaults, or override specific fields.
 //  2. Run `make gen-assets` to generate nova.png
 //  3. Upload nova.png to Discord Developer Portal -> Rich Presence -> Art Assets
-//  4. Push to main — all running daemons pick up the change on next restart
+//  4. Push to main — running daemons pick up the change on their next refresh
 //  5. No binary release needed
 package tiers
 
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
+	rootpkg "tools.zach/dev/agentcord"
 	"tools.zach/dev/agentcord/internal/atomicfile"
+	"tools.zach/dev/agentcord/internal/httpfetch"
+	"tools.zach/dev/agentcord/internal/netclient"
 	"tools.zach/dev/agentcord/internal/paths"
 	"tools.zach/dev/agentcord/internal/remote"
 )
@@ -151,14 +153,17 @@ func mergeTierConfig(dst *TierConfig, src TierConfig) {
 // Public API
 // ///////////////////////////////////////////////
 
-// Fetch loads tier data: remote -> cache.
-// Returns nil with an error when both sources fail.
+// Fetch loads tier data: remote -> cache -> embedded. The remote is skipped in
+// offline mode. Returns nil with an error only when all three sources fail.
 func Fetch(dataDir string) (*TierData, error) {
-	// Try remote (skip if URL not derivable)
-	if getRemoteURL() == "" {
+	// Try remote (skip if offline or URL not derivable)
+	if netclient.Offline() {
+		slog.Debug("skipping remote tier fetch: offline mode")
+	} else if getRemoteURL() == "" {
 		slog.Debug("skipping remote tier fetch: no remote URL configured")
-	} else if data, err := fetchRemote(); err == nil {
+	} else if data, meta, err := fetchRemote(getRemoteURL(), httpfetch.Meta{}); err == nil {
 		cacheWrite(dataDir, data)
+		metaWrite(dataDir, meta)
 		return data, nil
 	}
 	// Try cache
@@ -166,7 +171,58 @@ func Fetch(dataDir string) (*TierData, error) {
 		slog.Debug("using cached tier data")
 		return data, nil
 	}
-	return nil, fmt.Errorf("no tier data available: remote and cache both failed")
+	// Try embedded
+	data, err := Embedded()
+	if err != nil {
+		return nil, fmt.Errorf("no tier data available: remote, cache and embedded all failed: %w", err)
+	}
+	slog.Debug("using embedded tier data")
+	return data, nil
+}
+
+// ReadCache loads tier data from the local cache only, without network access.
+func ReadCache(dataDir string) (*TierData, error) {
+	return cacheRead(dataDir)
+}
+
+// Embedded returns the tier data embedded at build time.
+func Embedded() (*TierData, error) {
+	var data TierData
+	if err := json.Unmarshal(rootpkg.DefaultTiersJSON, &data); err != nil {
+		return nil, fmt.Errorf("parsing embedded tier data: %w", err)
+	}
+	return &data, nil
+}
+
+// Refresh revalidates tier data against the remote source using the ETag and
+// Last-Modified values recorded by the previous fetch. Returns nil data and a
+// nil error when the remote is unchanged or no remote URL is configured. On
+// success the cache and its metadata are updated. On error the caller should
+// keep its current data. Always returns nil, nil in offline mode.
+func Refresh(dataDir string) (*TierData, error) {
+	if netclient.Offline() {
+		return nil, nil
+	}
+	url := getRemoteURL()
+	if url == "" {
+		return nil, nil
+	}
+	return refreshFrom(url, dataDir)
+}
+
+// refreshFrom implements [Refresh] against an explicit URL.
+func refreshFrom(url, dataDir string) (*TierData, error) {
+	prev, _ := httpfetch.ReadMeta(filepath.Join(dataDir, paths.TiersCacheMetaFile))
+	data, meta, err := fetchRemote(url, prev)
+	if err != nil {
+		return nil, err
+	}
+	metaWrite(dataDir, meta)
+	if data == nil {
+		return nil, nil
+	}
+	cacheWrite(dataDir, data)
+	return data, nil
 }
 
 // modelPrefixes lists known model family prefixes to strip before tier matching.
@@ -200,31 +256,32 @@ func ExtractTier(model, client string, data *TierData) string {
 // Internal helpers
 // ///////////////////////////////////////////////
 
-// fetchRemote downloads tier data from the remote GitHub URL. The response
-// body is limited to 1 MiB to guard against unexpectedly large payloads.
-func fetchRemote() (*TierData, error) {
-	url := getRemoteURL()
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(url)
+// fetchRemote downloads tier data from url with the shared
+// [netclient.Client], revalidating against prev. The response body is limited
+// to 1 MiB to guard against unexpectedly large payloads. Returns nil data with the refreshed metadata when the server
+// reports the cached copy is current.
+func fetchRemote(url string, prev httpfetch.Meta) (*TierData, httpfetch.Meta, error) {
+	res, err := httpfetch.Get(netclient.Client(), url, prev, 1<<20)
 	if err != nil {
-		return nil, fmt.Errorf("GET %s: %w", url, err)
+		return nil, httpfetch.Meta{}, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	if res.NotModified {
+		return nil, res.Meta, nil
 	}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+	var data TierData
+	if err := json.Unmarshal(res.Body, &data); err != nil {
+		return nil, httpfetch.Meta{}, fmt.Errorf("parsing response: %w", err)
 	}
+	return &data, res.Meta, nil
+}
 
-	var data TierData
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+// metaWrite persists fetch metadata next to the tier cache so the next
+// [Refresh] can send a conditional request.
+func metaWrite(dataDir string, meta httpfetch.Meta) {
+	if err := httpfetch.WriteMeta(filepath.Join(dataDir, paths.TiersCacheMetaFile), meta); err != nil {
+		slog.Debug("failed to write tier fetch metadata", "error", err)
 	}
-	return &data, nil
 }
 
 // cacheWrite persists tier data to the local cache file using [atomicfile.Write]
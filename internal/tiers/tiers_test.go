@@ -1,8 +1,12 @@
 package tiers
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"sort"
 	"testing"
+
+	"tools.zach/dev/agentcord/internal/netclient"
 )
 
 // ///////////////////////////////////////////////
@@ -263,3 +267,95 @@ func TestExtractTierEmptyDefault(t *testing.T) {
 		t.Errorf("ExtractTier with empty default = %q, want empty string", got)
 	}
 }
+
+// ///////////////////////////////////////////////
+// Refresh Tests
+// ///////////////////////////////////////////////
+
+func TestRefreshFrom_Conditional(t *testing.T) {
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"t1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"t1"`)
+		w.Write([]byte(`{"default_icon":"default","clients":{"claude-code":{"tiers":{"opus":{}}}}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	data, err := refreshFrom(server.URL, dir)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if data == nil || data.DefaultIcon != "default" {
+		t.Fatalf("first refresh = %+v, want tier data", data)
+	}
+	cached, err := cacheRead(dir)
+	if err != nil || len(cached.Clients) != 1 {
+		t.Errorf("cache after refresh = (%+v, %v), want one client", cached, err)
+	}
+
+	data, err = refreshFrom(server.URL, dir)
+	if err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if data != nil {
+		t.Errorf("second refresh = %+v, want nil for unchanged remote", data)
+	}
+	if conditional != 1 {
+		t.Errorf("conditional requests = %d, want 1", conditional)
+	}
+}
+
+func TestRefreshFrom_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	if data, err := refreshFrom(server.URL, t.TempDir()); err == nil || data != nil {
+		t.Errorf("refreshFrom = (%+v, %v), want (nil, error)", data, err)
+	}
+}
+
+// ///////////////////////////////////////////////
+// Offline and Embedded Tests
+// ///////////////////////////////////////////////
+
+func TestEmbedded(t *testing.T) {
+	data, err := Embedded()
+	if err != nil {
+		t.Fatalf("Embedded: %v", err)
+	}
+	if data.DefaultIcon == "" || len(data.TierNamesForClient("claude-code")) == 0 {
+		t.Errorf("Embedded = %+v, want default icon and claude-code tiers", data)
+	}
+}
+
+func TestFetch_Offline(t *testing.T) {
+	if err := netclient.Configure(netclient.Settings{Offline: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer netclient.Configure(netclient.Settings{})
+
+	dir := t.TempDir()
+	data, err := Fetch(dir)
+	if err != nil {
+		t.Fatalf("Fetch without cache: %v", err)
+	}
+	if len(data.Clients) == 0 {
+		t.Error("Fetch without cache did not return embedded tiers")
+	}
+
+	cacheWrite(dir, &TierData{DefaultIcon: "cached"})
+	if data, err = Fetch(dir); err != nil || data.DefaultIcon != "cached" {
+		t.Errorf("Fetch with cache = (%+v, %v), want cached data", data, err)
+	}
+
+	if data, err := Refresh(dir); data != nil || err != nil {
+		t.Errorf("Refresh offline = (%+v, %v), want (nil, nil)", data, err)
+	}
+}
@@ -9,8 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"time"
 
+	"tools.zach/dev/agentcord/internal/netclient"
 	"tools.zach/dev/agentcord/internal/paths"
 	"tools.zach/dev/agentcord/internal/remote"
 )
@@ -30,8 +30,12 @@ func getManifestURL() string {
 // ///////////////////////////////////////////////
 
 // Check fetches the remote release manifest and logs if a newer version is available.
-// Non-blocking, non-fatal — failures are silently ignored.
+// Non-blocking, non-fatal — failures are silently ignored. Skipped in offline mode.
 func Check(current string) {
+	if netclient.Offline() {
+		slog.Debug("skipping version check: offline mode")
+		return
+	}
 	if getManifestURL() == "" {
 		slog.Debug("skipping version check: no remote URL configured")
 		return
@@ -57,8 +61,7 @@ func Check(current string) {
 // string stored under the "." key, which represents the latest stable release.
 func fetchLatest() (string, error) {
 	url := getManifestURL()
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(url)
+	resp, err := netclient.Client().Get(url)
 	if err != nil {
 		return "", fmt.Errorf("GET %s: %w", url, err)
 	}
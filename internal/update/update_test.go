@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+
+	"tools.zach/dev/agentcord/internal/netclient"
 )
 
 // ///////////////////////////////////////////////
@@ -137,6 +139,28 @@ func TestCheck_EmptyManifestURL(t *testing.T) {
 	Check("1.0.0")
 }
 
+func TestCheck_Offline(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	old := manifestURL
+	manifestURL = server.URL
+	defer func() { manifestURL = old }()
+
+	if err := netclient.Configure(netclient.Settings{Offline: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer netclient.Configure(netclient.Settings{})
+
+	Check("1.0.0")
+	if called {
+		t.Error("manifest fetched in offline mode")
+	}
+}
+
 // ///////////////////////////////////////////////
 // fetchLatest Tests
 // ///////////////////////////////////////////////
@@ -23,8 +23,6 @@ case "$CLIENT" in
     *)           SESSION_ID_FIELD="${AGENTCORD_SESSION_ID_FIELD:-.session_id}" ;;
 esac
 
-# Per-client state file path
-STATE_PATH="$DATA_DIR/state.${CLIENT}.json"
 
 require_jq() {
     command -v jq >/dev/null 2>&1 || { echo "jq is required for agentcord hooks" >&2; exit 1; }
@@ -34,6 +32,16 @@ read_hook_input() {
     INPUT=$(cat)
     SESSION_ID=$(echo "$INPUT" | jq -r "$SESSION_ID_FIELD // empty")
     [ -z "$SESSION_ID" ] && exit 0
+    set_state_path
+}
+
+# Per-session state file path, so parallel sessions of one client don't
+# overwrite each other's state. Requires SESSION_ID. SESSION_KEY is the ID
+# with every character outside [A-Za-z0-9_-] replaced by "_", so it is safe
+# to use in file names (must match paths.SanitizeSessionID).
+set_state_path() {
+    SESSION_KEY="${SESSION_ID//[^A-Za-z0-9_-]/_}"
+    STATE_PATH="$DATA_DIR/state.${CLIENT}.${SESSION_KEY}.json"
 }
 
 write_state() {
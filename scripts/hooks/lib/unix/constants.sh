@@ -7,4 +7,4 @@ PID_FILE="daemon.pid"
 SESSIONS_DIR="sessions"
 SESSION_EXT=".session"
 BINARY_NAME="agentcord"
-STATE_VERSION=1
+STATE_VERSION=2
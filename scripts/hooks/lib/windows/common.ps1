@@ -22,9 +22,6 @@ $SessionIdField = switch ($Client) {
     default       { if ($env:AGENTCORD_SESSION_ID_FIELD) { $env:AGENTCORD_SESSION_ID_FIELD } else { '.session_id' } }
 }
 
-# Per-client state file path
-$StatePath    = Join-Path $DataDir "state.$Client.json"
-
 function Assert-JqInstalled {
     if (-not (Get-Command jq -ErrorAction SilentlyContinue)) {
         Write-Error 'jq is required for agentcord hooks'
@@ -36,6 +33,16 @@ function Read-HookInput {
     $script:HookInput = [Console]::In.ReadToEnd()
     $script:SessionId = $HookInput | jq -r "$SessionIdField // empty"
     if (-not $SessionId -or $SessionId -eq 'null') { exit 0 }
+    Set-StatePath
+}
+
+# Per-session state file path, so parallel sessions of one client don't
+# overwrite each other's state. Requires $SessionId. $SessionKey is the ID
+# with every character outside [A-Za-z0-9_-] replaced by '_', so it is safe
+# to use in file names (must match paths.SanitizeSessionID).
+function Set-StatePath {
+    $script:SessionKey = $SessionId -replace '[^A-Za-z0-9_-]', '_'
+    $script:StatePath = Join-Path $DataDir "state.$Client.$SessionKey.json"
 }
 
 function Write-StateFile {
@@ -6,4 +6,4 @@ $script:PidFile       = 'daemon.pid'
 $script:SessionsDir   = 'sessions'
 $script:SessionExt    = '.session'
 $script:BinaryName    = 'agentcord'
-$script:StateVersion  = 1
+$script:StateVersion  = 2
@@ -8,6 +8,8 @@ setup() {
     export AGENTCORD_CLIENT="code"
     # Source common to get functions and constants
     source "$AGENTCORD_COMMON"
+    SESSION_ID="test-session"
+    set_state_path
 }
 
 teardown() {
@@ -49,6 +51,26 @@ teardown() {
 # write_state
 # ---------------------------------------------------------------------------
 
+@test "read_hook_input sets a per-session state path" {
+    local result
+    result=$(echo '{"session_id":"abc-123"}' | bash -c '
+        source "$AGENTCORD_COMMON"
+        read_hook_input
+        echo "$STATE_PATH"
+    ')
+    [ "$result" = "$AGENTCORD_DATA_DIR/state.code.abc-123.json" ]
+}
+
+@test "read_hook_input sanitizes the session ID in the state path" {
+    local result
+    result=$(echo '{"session_id":"../a.b/c"}' | bash -c '
+        source "$AGENTCORD_COMMON"
+        read_hook_input
+        echo "$SESSION_ID $STATE_PATH"
+    ')
+    [ "$result" = "../a.b/c $AGENTCORD_DATA_DIR/state.code.___a_b_c.json" ]
+}
+
 @test "write_state does atomic write" {
     mkdir -p "$AGENTCORD_DATA_DIR"
     echo '{"active":true}' | write_state
@@ -110,7 +132,7 @@ teardown() {
     for i in $(seq 1 10); do
         (
             echo "{\"writer\":$i,\"ok\":true}" | \
-                bash -c "source \"$AGENTCORD_COMMON\"; write_state"
+                bash -c "source \"$AGENTCORD_COMMON\"; SESSION_ID=test-session; set_state_path; write_state"
         ) &
         pids+=($!)
     done
@@ -40,6 +40,8 @@ Describe 'Write-StateFile' {
     BeforeAll {
         $env:AGENTCORD_DATA_DIR = (New-Item -ItemType Directory -Path (Join-Path ([System.IO.Path]::GetTempPath()) "agentcord-test-$([guid]::NewGuid())")).FullName
         . $env:AGENTCORD_COMMON
+        $script:SessionId = 'test-session'
+        Set-StatePath
     }
 
     AfterAll {
@@ -48,11 +50,20 @@ Describe 'Write-StateFile' {
 
     It 'should produce a state.json with correct content' {
         '{"active":true}' | Write-StateFile
+        $StatePath | Should -Be (Join-Path $env:AGENTCORD_DATA_DIR 'state.code.test-session.json')
         $StatePath | Should -Exist
         $content = Get-Content $StatePath -Raw
         $content.Trim() | Should -Be '{"active":true}'
     }
 
+    It 'should sanitize the session ID in the state path' {
+        $script:SessionId = '../a.b/c'
+        Set-StatePath
+        $StatePath | Should -Be (Join-Path $env:AGENTCORD_DATA_DIR 'state.code.___a_b_c.json')
+        $script:SessionId = 'test-session'
+        Set-StatePath
+    }
+
     It 'should leave no temp files after write' {
         '{"clean":true}' | Write-StateFile
         $tmpFiles = Get-ChildItem $env:AGENTCORD_DATA_DIR -Filter '*.tmp.*' -ErrorAction SilentlyContinue
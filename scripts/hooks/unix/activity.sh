@@ -18,7 +18,7 @@ mkdir -p "$SESSIONS_PATH"
 find "$SESSIONS_PATH" -name "*${SESSION_EXT}" -mmin +1440 -delete 2>/dev/null || true
 
 # Write session marker file (always — must not be skipped by debounce)
-touch "$SESSIONS_PATH/${SESSION_ID}${SESSION_EXT}"
+touch "$SESSIONS_PATH/${SESSION_KEY}${SESSION_EXT}"
 
 # Debounce: skip state write if less than 5 seconds since last write
 if [ -f "$STATE_PATH" ]; then
@@ -31,12 +31,8 @@ fi
 
 # Read existing state to preserve sessionStart
 SESSION_START=""
-OLD_SESSION_ID=""
 if [ -f "$STATE_PATH" ]; then
-    OLD_SESSION_ID=$(jq -r '.sessionId // empty' "$STATE_PATH")
-    if [ "$OLD_SESSION_ID" = "$SESSION_ID" ]; then
-        SESSION_START=$(jq -r '.sessionStart // empty' "$STATE_PATH")
-    fi
+    SESSION_START=$(jq -r '.sessionStart // empty' "$STATE_PATH")
 fi
 
 # New session or no prior state — set fresh start time
@@ -59,6 +55,7 @@ GIT_REMOTE=$(git remote get-url origin 2>/dev/null || true)
 TOOL_NAME=$(echo "$INPUT" | jq -r '.tool_name // empty')
 HOOK_EVENT=$(echo "$INPUT" | jq -r '.hook_event_name // empty')
 PERMISSION_MODE=$(echo "$INPUT" | jq -r '.permission_mode // empty')
+TRANSCRIPT_PATH=$(echo "$INPUT" | jq -r '.transcript_path // empty')
 
 # Extract tool target based on tool name
 TOOL_TARGET=""
@@ -99,6 +96,7 @@ jq -n \
     --arg branch "$BRANCH" \
     --arg cwd "$CWD" \
     --arg remote "$GIT_REMOTE" \
+    --arg transcriptPath "$TRANSCRIPT_PATH" \
     --arg toolName "$TOOL_NAME" \
     --arg toolTarget "$TOOL_TARGET" \
     --arg activeFile "$ACTIVE_FILE" \
@@ -114,6 +112,7 @@ jq -n \
         "branch": $branch,
         "cwd": $cwd,
         "gitRemoteUrl": $remote,
+        "transcriptPath": $transcriptPath,
         "client": $client,
         "stopped": false,
         "toolName": $toolName,
@@ -1,6 +1,6 @@
 #!/usr/bin/env bash
 # sessionend.sh — SessionEnd hook.
-# Cleans up session marker and kills daemon when no sessions remain.
+# Cleans up session marker and state, and kills daemon when no sessions remain.
 # Invoked by Claude Code hook system; must NOT use set -e.
 
 [ -z "$AGENTCORD_COMMON" ] && { echo "AGENTCORD_COMMON not set — run via dispatch.ts" >&2; exit 1; }
@@ -13,7 +13,7 @@ read_hook_input
 # ///////////////////////////////////////////////
 
 # Remove this session's marker file
-rm -f "$SESSIONS_PATH/${SESSION_ID}${SESSION_EXT}"
+rm -f "$SESSIONS_PATH/${SESSION_KEY}${SESSION_EXT}"
 
 # Count remaining sessions
 REMAINING=0
@@ -21,6 +21,11 @@ if [ -d "$SESSIONS_PATH" ]; then
     REMAINING=$(find "$SESSIONS_PATH" -maxdepth 1 -name "*${SESSION_EXT}" | wc -l | tr -d '[:space:]')
 fi
 
+# Other sessions remain — drop this session's state so the daemon shows theirs
+if [ "$REMAINING" -gt 0 ]; then
+    rm -f "$STATE_PATH"
+fi
+
 # ///////////////////////////////////////////////
 # Last Session — Stop Daemon
 # ///////////////////////////////////////////////
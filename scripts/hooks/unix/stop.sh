@@ -17,12 +17,8 @@ _STOP_HOOK_ACTIVE=true
 # ///////////////////////////////////////////////
 
 if [ -f "$STATE_PATH" ]; then
-    # Only update if this session owns the current state
-    CURRENT_SID=$(jq -r '.sessionId // empty' "$STATE_PATH")
-    if [ "$CURRENT_SID" = "$SESSION_ID" ]; then
-        LAST_ACTIVITY=$(date +%s)
-        jq --argjson activity "$LAST_ACTIVITY" \
-            '.lastActivity = $activity | .stopped = true' \
-            "$STATE_PATH" | write_state
-    fi
+    LAST_ACTIVITY=$(date +%s)
+    jq --argjson activity "$LAST_ACTIVITY" \
+        '.lastActivity = $activity | .stopped = true' \
+        "$STATE_PATH" | write_state
 fi
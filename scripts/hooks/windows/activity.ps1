@@ -25,15 +25,12 @@ if (-not (Test-Path $SessionsPath)) {
 Get-ChildItem -Path $SessionsPath -Filter "*$SessionExt" -ErrorAction SilentlyContinue | Where-Object { $_.LastWriteTime -lt (Get-Date).AddHours(-24) } | Remove-Item -Force -ErrorAction SilentlyContinue
 
 # Write session marker file
-'' | Set-Content (Join-Path $SessionsPath "$SessionId$SessionExt")
+'' | Set-Content (Join-Path $SessionsPath "$SessionKey$SessionExt")
 
 # Read existing state to preserve sessionStart
 $SessionStart = $null
 if (Test-Path $StatePath) {
-    $oldSid = jq -r '.sessionId // empty' $StatePath
-    if ($oldSid -eq $SessionId) {
-        $SessionStart = jq -r '.sessionStart // empty' $StatePath
-    }
+    $SessionStart = jq -r '.sessionStart // empty' $StatePath
 }
 
 # New session or no prior state — set fresh start time
@@ -63,6 +60,7 @@ try {
 $ToolName = $HookInput | jq -r '.tool_name // empty'
 $HookEvent = $HookInput | jq -r '.hook_event_name // empty'
 $PermissionMode = $HookInput | jq -r '.permission_mode // empty'
+$TranscriptPath = $HookInput | jq -r '.transcript_path // empty'
 
 $ToolTarget = ''
 $ActiveFile = ''
@@ -105,13 +103,14 @@ jq -n `
     --arg branch $Branch `
     --arg cwd $Cwd `
     --arg remote $GitRemote `
+    --arg transcriptPath $TranscriptPath `
     --arg toolName $ToolName `
     --arg toolTarget $ToolTarget `
     --arg activeFile $ActiveFile `
     --arg agentState $AgentState `
     --arg permissionMode $PermissionMode `
     --arg hookEvent $HookEvent `
-    '{\"$version\": $version, \"sessionId\": $sid, \"sessionStart\": $start, \"lastActivity\": $activity, \"project\": $project, \"branch\": $branch, \"cwd\": $cwd, \"gitRemoteUrl\": $remote, \"client\": $client, \"stopped\": false, \"toolName\": $toolName, \"toolTarget\": $toolTarget, \"activeFile\": $activeFile, \"agentState\": $agentState, \"permissionMode\": $permissionMode, \"hookEvent\": $hookEvent}' | Write-StateFile
+    '{\"$version\": $version, \"sessionId\": $sid, \"sessionStart\": $start, \"lastActivity\": $activity, \"project\": $project, \"branch\": $branch, \"cwd\": $cwd, \"gitRemoteUrl\": $remote, \"transcriptPath\": $transcriptPath, \"client\": $client, \"stopped\": false, \"toolName\": $toolName, \"toolTarget\": $toolTarget, \"activeFile\": $activeFile, \"agentState\": $agentState, \"permissionMode\": $permissionMode, \"hookEvent\": $hookEvent}' | Write-StateFile
 
 # ///////////////////////////////////////////////
 # Daemon Health Check
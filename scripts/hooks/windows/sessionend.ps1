@@ -1,5 +1,5 @@
 # sessionend.ps1 — SessionEnd hook.
-# Cleans up session marker and kills daemon when no sessions remain.
+# Cleans up session marker and state, and kills daemon when no sessions remain.
 # PowerShell port for Windows — invoked by Claude Code hook system.
 
 if (-not $env:AGENTCORD_COMMON) { Write-Error 'AGENTCORD_COMMON not set — run via dispatch.ts'; exit 1 }
@@ -12,12 +12,15 @@ Read-HookInput
 # ///////////////////////////////////////////////
 
 # Remove this session's marker file
-$marker = Join-Path $SessionsPath "$SessionId$SessionExt"
+$marker = Join-Path $SessionsPath "$SessionKey$SessionExt"
 if (Test-Path $marker) { Remove-Item $marker -Force }
 
 # Count remaining sessions
 $remaining = @(Get-ChildItem -Path $SessionsPath -Filter "*$SessionExt" -File -ErrorAction SilentlyContinue).Count
 
+# Other sessions remain — drop this session's state so the daemon shows theirs
+if ($remaining -gt 0 -and (Test-Path $StatePath)) { Remove-Item $StatePath -Force }
+
 # ///////////////////////////////////////////////
 # Last Session — Stop Daemon
 # ///////////////////////////////////////////////
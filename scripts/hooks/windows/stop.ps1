@@ -12,9 +12,6 @@ Read-HookInput
 # ///////////////////////////////////////////////
 
 if (Test-Path $StatePath) {
-    $currentSid = jq -r '.sessionId // empty' $StatePath
-    if ($currentSid -eq $SessionId) {
-        $now = [long][DateTimeOffset]::UtcNow.ToUnixTimeSeconds()
-        jq --argjson activity $now '.lastActivity=$activity|.stopped=true' $StatePath | Write-StateFile
-    }
+    $now = [long][DateTimeOffset]::UtcNow.ToUnixTimeSeconds()
+    jq --argjson activity $now '.lastActivity=$activity|.stopped=true' $StatePath | Write-StateFile
 }
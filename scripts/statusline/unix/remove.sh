@@ -1,6 +1,6 @@
 #!/usr/bin/env bash
 # remove.sh — Restore original Claude Code statusline configuration.
-# Reverses setup-statusline.sh: restores the backed-up statusline or removes the key.
+# Reverses setup.sh: restores the backed-up statusLine setting or removes the key.
 set -euo pipefail
 
 if ! command -v jq >/dev/null 2>&1; then
@@ -12,9 +12,11 @@ fi
 # Paths
 # ///////////////////////////////////////////////
 
-DATA_DIR="${AGENTCORD_DATA_DIR:-$HOME/.agentcord}"
+. "$(dirname "$0")/../../hooks/lib/unix/constants.sh"
+
+DATA_DIR="${AGENTCORD_DATA_DIR:-$HOME/$DATA_DIR_REL}"
 CLAUDE_CONFIG="$HOME/.claude/settings.json"
-BACKUP="$DATA_DIR/statusline-original.sh"
+BACKUP="$DATA_DIR/statusline-original.json"
 
 # ///////////////////////////////////////////////
 # Restore
@@ -28,15 +30,15 @@ fi
 TMP="$CLAUDE_CONFIG.tmp.$$"
 
 if [ -f "$BACKUP" ]; then
-    jq --arg orig "$BACKUP" '.statusline = $orig' "$CLAUDE_CONFIG" > "$TMP"
+    jq --slurpfile orig "$BACKUP" 'del(.statusline) | .statusLine = $orig[0]' "$CLAUDE_CONFIG" > "$TMP"
     mv "$TMP" "$CLAUDE_CONFIG"
     rm -f "$BACKUP"
     echo "Restored original statusline command."
 else
-    jq 'del(.statusline)' "$CLAUDE_CONFIG" > "$TMP"
+    jq 'del(.statusline) | del(.statusLine)' "$CLAUDE_CONFIG" > "$TMP"
     mv "$TMP" "$CLAUDE_CONFIG"
     echo "Removed Agentcord statusline configuration."
 fi
 
-rm -f "$DATA_DIR/statusline.json"
+rm -f "$DATA_DIR/statusline.json" "$DATA_DIR/statusline-cache.json" "$DATA_DIR/statusline-prices.json"
 echo "Restart Claude Code for changes to take effect."
@@ -1,6 +1,9 @@
 #!/usr/bin/env bash
-# setup.sh — Configure Claude Code to pipe statusline data to Agentcord.
-# Backs up any existing statusline command, then sets the wrapper as the handler.
+# setup.sh — Configure Claude Code to run `agentcord statusline` as its statusline.
+# Backs up any existing statusLine setting, then points it at the Agentcord binary,
+# which prints the terminal statusline and saves the payload for the daemon. The
+# backed-up command keeps running: Agentcord passes it the same payload and appends
+# its output to the line.
 # Requires jq to be installed. Idempotent — safe to run multiple times.
 set -euo pipefail
 
@@ -19,10 +22,13 @@ fi
 # Paths
 # ///////////////////////////////////////////////
 
-DATA_DIR="${AGENTCORD_DATA_DIR:-$HOME/.agentcord}"
+. "$(dirname "$0")/../../hooks/lib/unix/constants.sh"
+
+DATA_DIR="${AGENTCORD_DATA_DIR:-$HOME/$DATA_DIR_REL}"
 CLAUDE_CONFIG="$HOME/.claude/settings.json"
-SCRIPT_DIR="$(cd "$(dirname "$0")" && pwd)"
-WRAPPER="$SCRIPT_DIR/wrapper.sh"
+BINARY="$DATA_DIR/$BINARY_NAME"
+COMMAND="\"$BINARY\" statusline --data-dir \"$DATA_DIR\""
+BACKUP="$DATA_DIR/statusline-original.json"
 
 mkdir -p "$DATA_DIR"
 
@@ -31,11 +37,11 @@ mkdir -p "$DATA_DIR"
 # ///////////////////////////////////////////////
 
 if [ -f "$CLAUDE_CONFIG" ]; then
-    EXISTING=$(jq -r '.statusline // empty' "$CLAUDE_CONFIG" 2>/dev/null || true)
-    if [ -n "$EXISTING" ] && [ "$EXISTING" != "$WRAPPER" ]; then
-        cp "$EXISTING" "$DATA_DIR/statusline-original.sh" 2>/dev/null || true
-        chmod +x "$DATA_DIR/statusline-original.sh" 2>/dev/null || true
-        echo "Backed up existing statusline to $DATA_DIR/statusline-original.sh"
+    EXISTING=$(jq -c '.statusLine // empty' "$CLAUDE_CONFIG" 2>/dev/null || true)
+    CURRENT=$(jq -r '.statusLine.command // empty' "$CLAUDE_CONFIG" 2>/dev/null || true)
+    if [ -n "$EXISTING" ] && [ "$CURRENT" != "$COMMAND" ]; then
+        printf '%s\n' "$EXISTING" > "$BACKUP"
+        echo "Backed up existing statusline to $BACKUP"
     fi
 fi
 
@@ -48,8 +54,8 @@ if [ ! -f "$CLAUDE_CONFIG" ]; then
 fi
 
 TMP="$CLAUDE_CONFIG.tmp.$$"
-jq --arg wrapper "$WRAPPER" '.statusline = $wrapper' "$CLAUDE_CONFIG" > "$TMP"
+jq --arg cmd "$COMMAND" 'del(.statusline) | .statusLine = {type: "command", command: $cmd}' "$CLAUDE_CONFIG" > "$TMP"
 mv "$TMP" "$CLAUDE_CONFIG"
 
-echo "Claude Code statusline configured to use Agentcord wrapper."
+echo "Claude Code statusline configured to run: $COMMAND"
 echo "Restart Claude Code for changes to take effect."
@@ -1,11 +1,13 @@
 # remove.ps1 — Restore original Claude Code statusline configuration.
-# Reverses setup.ps1: restores the backed-up statusline or removes the key.
+# Reverses setup.ps1: restores the backed-up statusLine setting or removes the key.
 
 $ErrorActionPreference = 'Stop'
 
-$DataDir = if ($env:AGENTCORD_DATA_DIR) { $env:AGENTCORD_DATA_DIR } else { Join-Path $HOME '.agentcord' }
+. (Join-Path $PSScriptRoot '..\..\hooks\lib\windows\constants.ps1')
+
+$DataDir = if ($env:AGENTCORD_DATA_DIR) { $env:AGENTCORD_DATA_DIR } else { Join-Path $HOME $DataDirRel }
 $ClaudeConfig = Join-Path (Join-Path $HOME '.claude') 'settings.json'
-$Backup = Join-Path $DataDir 'statusline-original.ps1'
+$Backup = Join-Path $DataDir 'statusline-original.json'
 
 if (-not (Test-Path $ClaudeConfig)) {
     Write-Host 'No Claude Code config found, nothing to restore.'
@@ -13,13 +15,15 @@ if (-not (Test-Path $ClaudeConfig)) {
 }
 
 $cfg = Get-Content $ClaudeConfig -Raw | ConvertFrom-Json
+$cfg.PSObject.Properties.Remove('statusline')
 
 if (Test-Path $Backup) {
-    $cfg.statusline = $Backup
+    $original = Get-Content $Backup -Raw | ConvertFrom-Json
+    $cfg | Add-Member -NotePropertyName 'statusLine' -NotePropertyValue $original -Force
     Remove-Item $Backup -Force
     Write-Host 'Restored original statusline command.'
 } else {
-    $cfg.PSObject.Properties.Remove('statusline')
+    $cfg.PSObject.Properties.Remove('statusLine')
     Write-Host 'Removed Agentcord statusline configuration.'
 }
 
@@ -27,5 +31,9 @@ $cfg | ConvertTo-Json -Depth 10 | Set-Content $ClaudeConfig
 
 $StatuslineJson = Join-Path $DataDir 'statusline.json'
 if (Test-Path $StatuslineJson) { Remove-Item $StatuslineJson -Force }
+$StatuslineCache = Join-Path $DataDir 'statusline-cache.json'
+if (Test-Path $StatuslineCache) { Remove-Item $StatuslineCache -Force }
+$StatuslinePrices = Join-Path $DataDir 'statusline-prices.json'
+if (Test-Path $StatuslinePrices) { Remove-Item $StatuslinePrices -Force }
 
 Write-Host 'Restart Claude Code for changes to take effect.'
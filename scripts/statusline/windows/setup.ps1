@@ -1,5 +1,8 @@
-# setup.ps1 — Configure Claude Code to pipe statusline data to Agentcord.
-# Backs up any existing statusline command, then sets the wrapper as the handler.
+# setup.ps1 — Configure Claude Code to run `agentcord statusline` as its statusline.
+# Backs up any existing statusLine setting, then points it at the Agentcord binary,
+# which prints the terminal statusline and saves the payload for the daemon. The
+# backed-up command keeps running: Agentcord passes it the same payload and appends
+# its output to the line.
 # Requires jq to be installed. Idempotent — safe to run multiple times.
 
 $ErrorActionPreference = 'Stop'
@@ -17,10 +20,13 @@ if (-not (Get-Command jq -ErrorAction SilentlyContinue)) {
 # Paths
 # ///////////////////////////////////////////////
 
-$DataDir = if ($env:AGENTCORD_DATA_DIR) { $env:AGENTCORD_DATA_DIR } else { Join-Path $HOME '.agentcord' }
+. (Join-Path $PSScriptRoot '..\..\hooks\lib\windows\constants.ps1')
+
+$DataDir = if ($env:AGENTCORD_DATA_DIR) { $env:AGENTCORD_DATA_DIR } else { Join-Path $HOME $DataDirRel }
 $ClaudeConfig = Join-Path (Join-Path $HOME '.claude') 'settings.json'
-$ScriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
-$Wrapper = Join-Path $ScriptDir 'wrapper.ps1'
+$Binary = Join-Path $DataDir "$BinaryName.exe"
+$Command = "`"$Binary`" statusline --data-dir `"$DataDir`""
+$Backup = Join-Path $DataDir 'statusline-original.json'
 
 if (-not (Test-Path $DataDir)) { New-Item -ItemType Directory -Path $DataDir -Force | Out-Null }
 
@@ -29,10 +35,11 @@ if (-not (Test-Path $DataDir)) { New-Item -ItemType Directory -Path $DataDir -Fo
 # ///////////////////////////////////////////////
 
 if (Test-Path $ClaudeConfig) {
-    $existing = & jq -r '.statusline // empty' $ClaudeConfig 2>$null
-    if ($existing -and $existing -ne $Wrapper) {
-        Copy-Item $existing (Join-Path $DataDir 'statusline-original.ps1') -Force -ErrorAction SilentlyContinue
-        Write-Host "Backed up existing statusline to $(Join-Path $DataDir 'statusline-original.ps1')"
+    $existing = & jq -c '.statusLine // empty' $ClaudeConfig 2>$null
+    $current = & jq -r '.statusLine.command // empty' $ClaudeConfig 2>$null
+    if ($existing -and $current -ne $Command) {
+        $existing | Set-Content $Backup -Encoding utf8
+        Write-Host "Backed up existing statusline to $Backup"
     }
 }
 
@@ -45,8 +52,8 @@ if (-not (Test-Path $ClaudeConfig)) {
 }
 
 $tmp = "$ClaudeConfig.tmp.$PID"
-& jq --arg wrapper $Wrapper '.statusline = $wrapper' $ClaudeConfig | Set-Content $tmp -Encoding utf8
+& jq --arg cmd $Command 'del(.statusline) | .statusLine = {type: "command", command: $cmd}' $ClaudeConfig | Set-Content $tmp -Encoding utf8
 Move-Item -Path $tmp -Destination $ClaudeConfig -Force
 
-Write-Host 'Claude Code statusline configured to use Agentcord wrapper.'
+Write-Host "Claude Code statusline configured to run: $Command"
 Write-Host 'Restart Claude Code for changes to take effect.'